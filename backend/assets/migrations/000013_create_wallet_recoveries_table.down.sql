@@ -0,0 +1,8 @@
+DELETE FROM permissions WHERE name LIKE 'permissions:recoveries-%';
+
+ALTER TABLE stored_rewards DROP CONSTRAINT IF EXISTS stored_rewards_user_address_fkey;
+
+ALTER TABLE stored_rewards ADD CONSTRAINT stored_rewards_user_address_fkey
+    FOREIGN KEY (user_address) REFERENCES users(friendly_address) ON DELETE CASCADE;
+
+DROP TABLE IF EXISTS wallet_recoveries;
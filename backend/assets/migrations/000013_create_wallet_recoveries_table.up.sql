@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS wallet_recoveries (
+    id BIGSERIAL NOT NULL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_raw_address TEXT NOT NULL,
+    old_friendly_address TEXT NOT NULL,
+    new_raw_address TEXT NOT NULL,
+    new_friendly_address TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    confirmed_by TEXT,
+    confirmed_by_user_id BIGINT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    version BIGINT NOT NULL DEFAULT 1
+);
+
+CREATE INDEX wallet_recoveries_user_id_idx ON wallet_recoveries (user_id);
+
+CREATE INDEX wallet_recoveries_status_idx ON wallet_recoveries (status);
+
+-- stored rewards must follow the user when the address is re-keyed
+ALTER TABLE stored_rewards DROP CONSTRAINT IF EXISTS stored_rewards_user_address_fkey;
+
+ALTER TABLE stored_rewards ADD CONSTRAINT stored_rewards_user_address_fkey
+    FOREIGN KEY (user_address) REFERENCES users(friendly_address) ON DELETE CASCADE ON UPDATE CASCADE;
+
+INSERT INTO permissions (name, route, method)
+VALUES
+('permissions:recoveries-read', '/v1/admin/recoveries', 'GET'),
+('permissions:recoveries-edit', '/v1/admin/recoveries/:id', 'PUT');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE roles.name = 'admin' AND permissions.name LIKE 'permissions:recoveries-%';
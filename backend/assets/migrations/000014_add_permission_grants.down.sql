@@ -0,0 +1,14 @@
+DELETE FROM permissions WHERE name IN (
+    'permissions:existing-collection-create',
+    'permissions:merch-read',
+    'permissions:media-upload-create'
+);
+
+DROP TABLE IF EXISTS permission_grants;
+
+ALTER TABLE permissions DROP CONSTRAINT IF EXISTS permissions_name_unique;
+
+DELETE FROM users_roles a USING users_roles b
+WHERE a.user_id = b.user_id AND a.role_id > b.role_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_roles_unique ON users_roles (user_id);
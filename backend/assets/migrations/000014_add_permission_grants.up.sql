@@ -0,0 +1,34 @@
+-- users can have several roles
+DROP INDEX IF EXISTS idx_users_roles_unique;
+
+ALTER TABLE permissions ADD CONSTRAINT permissions_name_unique UNIQUE (name);
+
+-- permissions granted to a user for a single resource, e.g. mint into one collection
+CREATE TABLE IF NOT EXISTS permission_grants (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    permission_id BIGINT NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    resource_type TEXT NOT NULL,
+    resource_id BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    UNIQUE (user_id, permission_id, resource_type, resource_id)
+);
+
+CREATE INDEX permission_grants_user_id_idx ON permission_grants (user_id);
+
+INSERT INTO permissions (name, route, method)
+VALUES
+('permissions:existing-collection-create', '/v1/admin/existing-collection', 'POST'),
+('permissions:merch-read', '/v1/admin/merch', 'GET'),
+('permissions:media-upload-create', '/v1/admin/media/upload', 'POST')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE roles.name = 'admin' AND permissions.name IN (
+    'permissions:existing-collection-create',
+    'permissions:merch-read',
+    'permissions:media-upload-create'
+)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id BIGINT,
+    actor_address TEXT,
+    method TEXT NOT NULL,
+    route TEXT NOT NULL,
+    target_type TEXT,
+    target_id TEXT,
+    status INTEGER NOT NULL,
+    before JSONB,
+    after JSONB,
+    diff JSONB,
+    request_id TEXT NOT NULL,
+    ip TEXT,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX audit_logs_actor_id_idx ON audit_logs (actor_id);
+
+CREATE INDEX audit_logs_target_idx ON audit_logs (target_type, target_id);
+
+CREATE INDEX audit_logs_created_at_idx ON audit_logs (created_at);
+
+-- audit log is append-only
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_no_update_delete
+BEFORE UPDATE OR DELETE ON audit_logs
+FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+INSERT INTO permissions (name, route, method)
+VALUES
+('permissions:audit-log-read', '/v1/admin/audit-log', 'GET')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE roles.name = 'admin' AND permissions.name = 'permissions:audit-log-read'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE name LIKE 'permissions:service-accounts-%';
+
+DROP TABLE IF EXISTS api_keys;
+
+DROP TABLE IF EXISTS service_accounts_roles;
+
+DROP TABLE IF EXISTS service_accounts;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    version BIGINT NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS service_accounts_roles (
+    service_account_id BIGINT NOT NULL REFERENCES service_accounts ON DELETE CASCADE,
+    role_id BIGINT NOT NULL REFERENCES roles ON DELETE CASCADE,
+    PRIMARY KEY (service_account_id, role_id)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    service_account_id BIGINT NOT NULL REFERENCES service_accounts ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    hash BYTEA NOT NULL UNIQUE,
+    expiry BIGINT,
+    last_used_at BIGINT,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX api_keys_service_account_id_idx ON api_keys (service_account_id);
+
+INSERT INTO permissions (name, route, method)
+VALUES
+('permissions:service-accounts-read', '/v1/admin/service-accounts', 'GET'),
+('permissions:service-accounts-create', '/v1/admin/service-accounts', 'POST'),
+('permissions:service-accounts-edit', '/v1/admin/service-accounts/:id', 'PATCH'),
+('permissions:service-accounts-delete', '/v1/admin/service-accounts/:id', 'DELETE')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE roles.name = 'admin' AND permissions.name LIKE 'permissions:service-accounts-%'
+ON CONFLICT DO NOTHING;
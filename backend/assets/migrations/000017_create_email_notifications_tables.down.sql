@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notification_preferences;
+
+DROP TABLE IF EXISTS user_emails;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS user_emails (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    verified_at BIGINT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    version BIGINT NOT NULL DEFAULT 1
+);
+
+-- an address can be verified by one profile only
+CREATE UNIQUE INDEX user_emails_verified_email_idx ON user_emails (lower(email)) WHERE verified;
+
+-- missing rows mean the default of the event
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    email BOOLEAN NOT NULL,
+    updated_at BIGINT NOT NULL,
+    PRIMARY KEY (user_id, event)
+);
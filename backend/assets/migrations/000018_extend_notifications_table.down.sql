@@ -0,0 +1,22 @@
+DELETE FROM permissions WHERE name LIKE 'permissions:notifications-%';
+
+DROP INDEX IF EXISTS notifications_created_at_idx;
+
+DROP INDEX IF EXISTS notifications_unread_idx;
+
+DROP INDEX IF EXISTS notifications_user_id_idx;
+
+CREATE INDEX notifications_user_id_idx ON notifications (user_id);
+
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_user_id_fkey;
+
+ALTER TABLE notifications ADD CONSTRAINT notifications_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id);
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS read_at;
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS data;
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS title;
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS type;
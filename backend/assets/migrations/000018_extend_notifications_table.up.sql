@@ -0,0 +1,32 @@
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'admin_message';
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS data JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS read_at BIGINT;
+
+-- notifications are removed with the user
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_user_id_fkey;
+
+ALTER TABLE notifications ADD CONSTRAINT notifications_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS notifications_user_id_idx;
+
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, id DESC);
+
+CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+CREATE INDEX notifications_created_at_idx ON notifications (created_at);
+
+INSERT INTO permissions (name, route, method)
+VALUES
+('permissions:notifications-create', '/v1/admin/notifications', 'POST')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE roles.name = 'admin' AND permissions.name LIKE 'permissions:notifications-%'
+ON CONFLICT DO NOTHING;
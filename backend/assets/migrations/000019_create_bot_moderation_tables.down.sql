@@ -0,0 +1,5 @@
+DELETE FROM roles WHERE name = 'moderator';
+
+DELETE FROM permissions WHERE name LIKE 'permissions:bot-%';
+
+DROP TABLE IF EXISTS tg_muted_users;
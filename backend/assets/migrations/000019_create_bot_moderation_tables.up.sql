@@ -0,0 +1,26 @@
+-- users whose group messages are not scored
+CREATE TABLE IF NOT EXISTS tg_muted_users (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    muted_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at BIGINT NOT NULL
+);
+
+-- bot commands are checked like admin routes, route is the command
+INSERT INTO permissions (name, route, method)
+VALUES
+('permissions:bot-award', '/award', 'BOT'),
+('permissions:bot-revoke', '/revoke', 'BOT'),
+('permissions:bot-mute', '/mute_points', 'BOT'),
+('permissions:bot-stats', '/stats', 'BOT')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description)
+VALUES
+('moderator', 'Awards and moderates members of Telegram chats')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE roles.name IN ('admin', 'moderator') AND permissions.name LIKE 'permissions:bot-%'
+ON CONFLICT DO NOTHING;
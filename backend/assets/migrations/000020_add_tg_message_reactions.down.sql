@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS tg_messages_chat_message_idx;
+
+DROP TABLE IF EXISTS tg_message_reactions;
+
+ALTER TABLE tg_messages DROP COLUMN IF EXISTS bonus;
@@ -0,0 +1,13 @@
+-- weight added to the message by reactions of other members
+ALTER TABLE tg_messages ADD COLUMN IF NOT EXISTS bonus INTEGER NOT NULL DEFAULT 0;
+
+-- a member reacts to a message once
+CREATE TABLE IF NOT EXISTS tg_message_reactions (
+    chat_id BIGINT NOT NULL,
+    message_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (chat_id, message_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS tg_messages_chat_message_idx ON tg_messages (chat_id, message_id);
@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE name LIKE 'permissions:tg-chats-%';
+
+DROP INDEX IF EXISTS tg_messages_chat_created_at_idx;
+
+ALTER TABLE tg_messages DROP COLUMN IF EXISTS weight;
+
+DROP TABLE IF EXISTS tg_chats;
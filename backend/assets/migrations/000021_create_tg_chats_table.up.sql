@@ -0,0 +1,32 @@
+-- community chats served by the bot, id is the telegram chat id
+CREATE TABLE IF NOT EXISTS tg_chats (
+    id BIGINT PRIMARY KEY,
+    title TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    -- scoring weight of messages in the chat
+    weight INTEGER NOT NULL DEFAULT 1,
+    language TEXT NOT NULL DEFAULT 'en',
+    welcome_text TEXT,
+    invite_url TEXT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+ALTER TABLE tg_messages ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS tg_messages_chat_created_at_idx ON tg_messages (chat_id, created_at);
+
+INSERT INTO permissions (name, route, method)
+VALUES
+('permissions:tg-chats-read', '/v1/admin/tg-chats', 'GET'),
+('permissions:tg-chats-create', '/v1/admin/tg-chats', 'POST'),
+('permissions:tg-chats-edit', '/v1/admin/tg-chats/:id', 'PATCH'),
+('permissions:tg-chats-delete', '/v1/admin/tg-chats/:id', 'DELETE')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE roles.name = 'admin' AND permissions.name LIKE 'permissions:tg-chats-%'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name LIKE 'permissions:tg-failed-updates-%';
+
+DROP TABLE IF EXISTS tg_failed_updates;
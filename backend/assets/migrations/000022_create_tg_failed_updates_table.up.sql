@@ -0,0 +1,29 @@
+-- telegram updates the bot failed to process, kept for inspection and replay
+CREATE TABLE IF NOT EXISTS tg_failed_updates (
+    id BIGSERIAL PRIMARY KEY,
+    update_id BIGINT NOT NULL,
+    chat_id BIGINT,
+    -- command, message or callback
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    error TEXT NOT NULL,
+    panicked BOOLEAN NOT NULL DEFAULT FALSE,
+    -- processing attempts including replays
+    attempts INTEGER NOT NULL DEFAULT 1,
+    created_at BIGINT NOT NULL,
+    replayed_at BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS tg_failed_updates_created_at_idx ON tg_failed_updates (created_at);
+
+INSERT INTO permissions (name, route, method)
+VALUES
+('permissions:tg-failed-updates-read', '/v1/admin/tg-failed-updates', 'GET'),
+('permissions:tg-failed-updates-replay', '/v1/admin/tg-failed-updates/:id/replay', 'POST')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE roles.name = 'admin' AND permissions.name LIKE 'permissions:tg-failed-updates-%'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- profile settings of users, rows are created on the first change
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- language of emails and bot messages, telegram language is used when empty
+    language TEXT,
+    updated_at BIGINT NOT NULL
+);
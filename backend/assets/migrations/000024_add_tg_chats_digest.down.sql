@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS rewards_created_at_idx;
+
+ALTER TABLE tg_chats DROP COLUMN IF EXISTS digest;
@@ -0,0 +1,4 @@
+-- the weekly leaderboard digest is posted to chats with digest enabled
+ALTER TABLE tg_chats ADD COLUMN IF NOT EXISTS digest BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS rewards_created_at_idx ON rewards (created_at);
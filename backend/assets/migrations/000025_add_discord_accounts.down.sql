@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS discord_messages;
+
+DROP INDEX IF EXISTS linked_accounts_discord_user_id_idx;
+
+ALTER TABLE linked_accounts DROP COLUMN IF EXISTS discord_user_id;
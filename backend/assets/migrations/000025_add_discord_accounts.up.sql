@@ -0,0 +1,20 @@
+-- Discord accounts linked with OAuth, the bot finds users by the Discord user id
+ALTER TABLE linked_accounts ADD COLUMN IF NOT EXISTS discord_user_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS linked_accounts_discord_user_id_idx ON linked_accounts (discord_user_id);
+
+-- messages scored in Discord guilds, user_id is the Discord user id like in tg_messages
+CREATE TABLE IF NOT EXISTS discord_messages (
+    id BIGSERIAL NOT NULL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    message_id BIGINT NOT NULL,
+    channel_id BIGINT NOT NULL,
+    guild_id BIGINT NOT NULL,
+    weight INTEGER NOT NULL DEFAULT 1,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    version BIGINT NOT NULL DEFAULT 1,
+    UNIQUE(message_id)
+);
+
+CREATE INDEX IF NOT EXISTS discord_messages_user_id_idx ON discord_messages (user_id);
@@ -0,0 +1,5 @@
+DELETE FROM permissions WHERE name LIKE 'permissions:kudos-%';
+
+ALTER TABLE activities DROP COLUMN IF EXISTS metric;
+
+DROP TABLE IF EXISTS kudos;
@@ -0,0 +1,37 @@
+-- kudos users give each other from the bot or the api, weight is added to the receiver's rating
+CREATE TABLE IF NOT EXISTS kudos (
+    id BIGSERIAL PRIMARY KEY,
+    giver_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    receiver_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- telegram, discord or api
+    source TEXT NOT NULL,
+    -- chat and message thanked for, empty for kudos from the api and Discord commands
+    chat_id BIGINT,
+    message_id BIGINT,
+    weight BIGINT NOT NULL,
+    -- given back within KUDOS_RECIPROCAL_DAYS, such kudos have no weight
+    reciprocal BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at BIGINT NOT NULL,
+    CHECK (giver_id <> receiver_id)
+);
+
+CREATE INDEX IF NOT EXISTS kudos_giver_id_created_at_idx ON kudos (giver_id, created_at);
+CREATE INDEX IF NOT EXISTS kudos_receiver_id_idx ON kudos (receiver_id);
+
+-- a message is thanked once by the same user
+CREATE UNIQUE INDEX IF NOT EXISTS kudos_giver_id_message_idx ON kudos (giver_id, chat_id, message_id) WHERE message_id IS NOT NULL;
+
+-- what token_threshold of the activity is compared with: rating or received kudos
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS metric TEXT NOT NULL DEFAULT 'rating' CHECK (metric IN ('rating', 'kudos'));
+
+INSERT INTO permissions (name, route, method)
+VALUES
+('permissions:kudos-read', '/v1/admin/kudos', 'GET'),
+('permissions:kudos-delete', '/v1/admin/kudos/:id', 'DELETE')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE roles.name = 'admin' AND permissions.name LIKE 'permissions:kudos-%'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- sbts of the old wallet a recovery has sent a revoke for, a retry of the recovery task skips them
+CREATE TABLE IF NOT EXISTS wallet_recovery_revokes (
+    recovery_id BIGINT NOT NULL REFERENCES wallet_recoveries(id) ON DELETE CASCADE,
+    sbt_token_id BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (recovery_id, sbt_token_id)
+);
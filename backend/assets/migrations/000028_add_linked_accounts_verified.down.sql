@@ -0,0 +1 @@
+ALTER TABLE linked_accounts DROP COLUMN IF EXISTS verified;
@@ -0,0 +1,5 @@
+-- accounts linked with a flow proving both the profile and the account, GitHub accounts linked
+-- by username before link tokens are not trusted for wallet recovery until they are linked again
+ALTER TABLE linked_accounts ADD COLUMN IF NOT EXISTS verified BOOLEAN NOT NULL DEFAULT true;
+
+UPDATE linked_accounts SET verified = false WHERE provider = 'github';
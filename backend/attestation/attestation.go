@@ -0,0 +1,148 @@
+// Package attestation signs and verifies token-gating attestations issued by
+// the TON Developers Platform.
+//
+// An attestation is a compact string "<payload>.<signature>", both parts are
+// base64url without padding. The payload is JSON encoded Attestation and the
+// signature is ed25519 over the payload part. Partners verify it offline with
+// the key published at GET /v1/attestation/key:
+//
+//	key, err := attestation.ParsePublicKey(publishedKey)
+//	a, err := attestation.Verify(key, token, time.Now())
+//	if err == nil && a.Satisfied && a.Address == wallet && a.Audience == "my-site" {
+//		// grant access
+//	}
+package attestation
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Algorithm is the only signature algorithm used for attestations
+const Algorithm = "ed25519"
+
+// Issuer is set in every attestation issued by the platform
+const Issuer = "ton-developer-program"
+
+var (
+	ErrMalformed        = errors.New("attestation: malformed token")
+	ErrInvalidSignature = errors.New("attestation: invalid signature")
+	ErrExpired          = errors.New("attestation: expired")
+	ErrInvalidKey       = errors.New("attestation: invalid public key")
+)
+
+// Policy lists the conditions the wallet must meet, all of them are required
+type Policy struct {
+	// holds at least one SBT of every prototype
+	PrototypeIDs []int64 `json:"prototype_ids,omitempty"`
+	// holds at least one SBT of every collection
+	CollectionIDs []int64  `json:"collection_ids,omitempty"`
+	MinRating     *float64 `json:"min_rating,omitempty"`
+	// position in the rating, 1 is the top
+	MaxRank *int64 `json:"max_rank,omitempty"`
+}
+
+// Checks holds the result of every condition of the policy
+type Checks struct {
+	PrototypeIDs  map[int64]bool `json:"prototype_ids,omitempty"`
+	CollectionIDs map[int64]bool `json:"collection_ids,omitempty"`
+	MinRating     *bool          `json:"min_rating,omitempty"`
+	MaxRank       *bool          `json:"max_rank,omitempty"`
+}
+
+type Attestation struct {
+	Issuer string `json:"iss"`
+	KeyID  string `json:"kid"`
+	// partner identifier from the request, partners should reject attestations issued for others
+	Audience string `json:"aud,omitempty"`
+	// friendly wallet address
+	Address string `json:"address"`
+	// true when the wallet ownership was proven with TonConnect proof
+	ProofVerified bool   `json:"proof_verified"`
+	Policy        Policy `json:"policy"`
+	Checks        Checks `json:"checks"`
+	Satisfied     bool   `json:"satisfied"`
+	IssuedAt      int64  `json:"iat"`
+	ExpiresAt     int64  `json:"exp"`
+}
+
+// KeyID returns short identifier of the public key
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// EncodePublicKey returns the key in the format accepted by ParsePublicKey
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ParsePublicKey parses base64 encoded ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+
+	return ed25519.PublicKey(b), nil
+}
+
+// Sign encodes the attestation and signs it with the platform key
+func Sign(key ed25519.PrivateKey, a *Attestation) (string, error) {
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	signature := ed25519.Sign(key, []byte(encoded))
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks the signature and expiry of the token and returns the attestation.
+// Callers must still check Satisfied, Address and Audience.
+func Verify(key ed25519.PublicKey, token string, now time.Time) (*Attestation, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+
+	encoded, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrMalformed
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if !ed25519.Verify(key, []byte(encoded), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	var a Attestation
+
+	err = json.Unmarshal(payload, &a)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if now.Unix() >= a.ExpiresAt {
+		return nil, ErrExpired
+	}
+
+	return &a, nil
+}
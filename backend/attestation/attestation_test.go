@@ -0,0 +1,174 @@
+package attestation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pub, priv
+}
+
+func newAttestation(pub ed25519.PublicKey, now time.Time) *Attestation {
+	minRating := 10.0
+	satisfied := true
+
+	return &Attestation{
+		Issuer:        Issuer,
+		KeyID:         KeyID(pub),
+		Audience:      "partner",
+		Address:       "EQCD39VS5jcptHL8vMjEXrzGaRcCVYto7HUn4bpAOg8xqB2N",
+		ProofVerified: true,
+		Policy:        Policy{PrototypeIDs: []int64{1, 2}, MinRating: &minRating},
+		Checks:        Checks{PrototypeIDs: map[int64]bool{1: true, 2: true}, MinRating: &satisfied},
+		Satisfied:     true,
+		IssuedAt:      now.Unix(),
+		ExpiresAt:     now.Add(10 * time.Minute).Unix(),
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	pub, priv := newKey(t)
+	now := time.Now()
+
+	token, err := Sign(priv, newAttestation(pub, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := Verify(pub, token, now)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if a.Issuer != Issuer || a.KeyID != KeyID(pub) || a.Audience != "partner" || !a.Satisfied {
+		t.Errorf("Verify() = %+v, fields don't match the signed attestation", a)
+	}
+
+	if !a.Checks.PrototypeIDs[1] || !a.Checks.PrototypeIDs[2] || a.Checks.MinRating == nil || !*a.Checks.MinRating {
+		t.Errorf("Verify() checks = %+v, want all satisfied", a.Checks)
+	}
+}
+
+func TestVerifyExpired(t *testing.T) {
+	pub, priv := newKey(t)
+	now := time.Now()
+
+	token, err := Sign(priv, newAttestation(pub, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want error
+	}{
+		{"before expiry", now.Add(10*time.Minute - time.Second), nil},
+		{"at expiry", now.Add(10 * time.Minute), ErrExpired},
+		{"after expiry", now.Add(time.Hour), ErrExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(pub, token, tt.at)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyWrongKey(t *testing.T) {
+	pub, priv := newKey(t)
+	otherPub, _ := newKey(t)
+	now := time.Now()
+
+	token, err := Sign(priv, newAttestation(pub, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Verify(otherPub, token, now)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	_, err = Verify(pub[:16], token, now)
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrInvalidKey)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	pub, priv := newKey(t)
+	now := time.Now()
+
+	token, err := Sign(priv, newAttestation(pub, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, signature, _ := strings.Cut(token, ".")
+
+	forged := newAttestation(pub, now)
+	forged.Address = "EQBvW8Z5huBkMJYdnfAEM5JqTNkuWX3diqYENkWsIL0XggGG"
+
+	forgedToken, err := Sign(priv, forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forgedPayload, _, _ := strings.Cut(forgedToken, ".")
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"no signature", payload, ErrMalformed},
+		{"signature not base64", payload + ".!!!", ErrMalformed},
+		{"payload of other attestation", forgedPayload + "." + signature, ErrInvalidSignature},
+		{"empty", "", ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(pub, tt.token, now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _ := newKey(t)
+
+	parsed, err := ParsePublicKey(EncodePublicKey(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !parsed.Equal(pub) {
+		t.Errorf("ParsePublicKey() = %x, want %x", parsed, pub)
+	}
+
+	for _, s := range []string{"", "not base64", EncodePublicKey(pub[:31])} {
+		_, err := ParsePublicKey(s)
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("ParsePublicKey(%q) error = %v, want %v", s, err, ErrInvalidKey)
+		}
+	}
+}
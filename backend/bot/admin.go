@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/i18n"
+	"github.com/ton-developer-program/internal/tasks"
+)
+
+// permissions of the bot commands, granted to admin and moderator roles
+const (
+	permissionBotAward  = "permissions:bot-award"
+	permissionBotRevoke = "permissions:bot-revoke"
+	permissionBotMute   = "permissions:bot-mute"
+	permissionBotStats  = "permissions:bot-stats"
+)
+
+type adminCommand struct {
+	permission string
+	handler    func(moderator *database.User, msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error
+}
+
+// adminCommands are available only to users with the permission, the Telegram account
+// of the sender must be linked to the platform account
+func (app *application) adminCommands() map[string]adminCommand {
+	return map[string]adminCommand{
+		"award":         {permissionBotAward, app.awardHandler},
+		"revoke":        {permissionBotRevoke, app.revokeHandler},
+		"mute_points":   {permissionBotMute, app.mutePointsHandler},
+		"unmute_points": {permissionBotMute, app.unmutePointsHandler},
+		"stats":         {permissionBotStats, app.statsHandler},
+	}
+}
+
+// adminHandler checks permission of the sender and runs the command
+func (app *application) adminHandler(command adminCommand, msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+	ctx := telegramContext(updateMsg)
+
+	moderator, err := app.sqlModels.Users.GetByTelegramUserId(ctx, updateMsg.From.ID)
+	if err != nil {
+		return err
+	}
+
+	allowed := false
+
+	if moderator != nil {
+		permissions, err := app.sqlModels.Users.GetAllPermissions(ctx, moderator.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range permissions {
+			if p.Name == command.permission && p.ResourceType == nil {
+				allowed = true
+				break
+			}
+		}
+	}
+
+	if !allowed {
+		app.logger.Warningw("telegram user is not allowed to use the command", "telegram_user_id", updateMsg.From.ID, "command", updateMsg.Command())
+
+		if moderator != nil {
+			app.auditBotCommand(moderator, updateMsg, 403, "", "", nil)
+		}
+
+		return app.reply(msgConfig, updateMsg, i18n.T(app.language(updateMsg), "bot.admin.forbidden"))
+	}
+
+	return command.handler(moderator, msgConfig, updateMsg)
+}
+
+// /award [@username] <prototype_id>, without username the author of the replied message is awarded
+func (app *application) awardHandler(moderator *database.User, msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+	ctx := telegramContext(updateMsg)
+
+	lang := app.language(updateMsg)
+
+	user, args, err := app.commandTarget(updateMsg)
+	if err != nil {
+		return err
+	}
+
+	if user == nil || len(args) != 1 {
+		return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.award.usage"))
+	}
+
+	prototypeID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.award.invalid_id"))
+	}
+
+	metadata, err := app.sqlModels.Nfts.GetNFTMetadataByPrototypeID(ctx, prototypeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.award.not_found"))
+		}
+		return err
+	}
+
+	id, err := app.sqlModels.Rewards.InsertStoredReward(user.FriendlyAddress, app.config.App.AdminCollectionAddress, metadata.Base64)
+	if err != nil {
+		return err
+	}
+
+	app.auditBotCommand(moderator, updateMsg, 200, "stored_reward", strconv.FormatInt(id, 10), database.JSONB{
+		"user_id":      user.ID,
+		"prototype_id": prototypeID,
+		"name":         metadata.Name,
+	})
+
+	app.notifyAwarded(ctx, user, id, metadata)
+
+	return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.award.success",
+		html.EscapeString(user.Username), html.EscapeString(metadata.Name)))
+}
+
+// notifyAwarded adds the achievement to the notification center and sends it to the user with accept buttons
+func (app *application) notifyAwarded(ctx context.Context, user *database.User, achievementID int64, metadata *database.NFTMetadata) {
+	err := app.sqlModels.Notifications.Insert(ctx, &database.Notification{
+		UserID: user.ID,
+		Type:   database.NotificationAchievementPending,
+		Title:  fmt.Sprintf("%s is waiting for your approval", metadata.Name),
+		Data: database.JSONB{
+			"achievement_id": achievementID,
+			"name":           metadata.Name,
+			"image":          metadata.Image,
+		},
+	})
+	if err != nil {
+		app.logger.Warningw("error inserting notification", "user_id", user.ID, "error", err)
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"user_id":        user.ID,
+		"achievement_id": achievementID,
+	})
+	if err != nil {
+		app.logger.Warningw("error marshalling telegram payload", "error", err)
+		return
+	}
+
+	task := tasks.NewTask(ctx, database.TYPE_TG_ACHIEVEMENT_PENDING, payload)
+
+	info, err := app.asynqClient.Enqueue(task, asynq.MaxRetry(5), asynq.Retention(24*time.Hour), asynq.Queue(database.QUEUE_BOT))
+	if err != nil {
+		app.logger.Warningw("error enqueueing telegram message", "user_id", user.ID, "error", err)
+		return
+	}
+
+	app.logger.Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+}
+
+// /revoke <achievement_id> declines the achievement before it is minted, minted SBTs are soulbound
+func (app *application) revokeHandler(moderator *database.User, msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+	lang := app.language(updateMsg)
+
+	args := strings.Fields(updateMsg.CommandArguments())
+
+	if len(args) != 1 {
+		return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.revoke.usage"))
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.revoke.invalid_id"))
+	}
+
+	achievement, err := app.sqlModels.Rewards.GetStoredRewardByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.achievement.not_found"))
+		}
+		return err
+	}
+
+	if achievement.Processed {
+		return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.revoke.processed"))
+	}
+
+	err = app.sqlModels.Rewards.DeclineStoredReward(achievement.ID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.revoke.processed"))
+		}
+		return err
+	}
+
+	app.auditBotCommand(moderator, updateMsg, 200, "stored_reward", strconv.FormatInt(achievement.ID, 10), database.JSONB{
+		"user_address": achievement.UserAddress,
+	})
+
+	return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.revoke.success", achievement.ID))
+}
+
+// /mute_points [@username] stops scoring messages of the user
+func (app *application) mutePointsHandler(moderator *database.User, msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+	lang := app.language(updateMsg)
+
+	user, _, err := app.commandTarget(updateMsg)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.mute.usage"))
+	}
+
+	err = app.sqlModels.Moderation.Mute(telegramContext(updateMsg), user.ID, moderator.ID)
+	if err != nil {
+		return err
+	}
+
+	app.auditBotCommand(moderator, updateMsg, 200, "user", strconv.FormatInt(user.ID, 10), nil)
+
+	return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.mute.success", html.EscapeString(user.Username)))
+}
+
+// /unmute_points [@username] scores messages of the user again
+func (app *application) unmutePointsHandler(moderator *database.User, msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+	lang := app.language(updateMsg)
+
+	user, _, err := app.commandTarget(updateMsg)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.unmute.usage"))
+	}
+
+	unmuted, err := app.sqlModels.Moderation.Unmute(telegramContext(updateMsg), user.ID)
+	if err != nil {
+		return err
+	}
+
+	if !unmuted {
+		return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.unmute.not_muted", html.EscapeString(user.Username)))
+	}
+
+	app.auditBotCommand(moderator, updateMsg, 200, "user", strconv.FormatInt(user.ID, 10), nil)
+
+	return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.unmute.success", html.EscapeString(user.Username)))
+}
+
+func (app *application) statsHandler(moderator *database.User, msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+	stats, err := app.sqlModels.Moderation.GetStats(telegramContext(updateMsg))
+	if err != nil {
+		return err
+	}
+
+	app.auditBotCommand(moderator, updateMsg, 200, "", "", nil)
+
+	return app.reply(msgConfig, updateMsg, i18n.T(app.language(updateMsg), "bot.stats",
+		stats.Users, stats.TelegramUsers, stats.MessagesToday, stats.PendingAchievements, stats.MutedUsers))
+}
+
+// commandTarget returns the user from the @username argument or the author of the replied message
+// and the rest of the arguments
+func (app *application) commandTarget(updateMsg *tgbotapi.Message) (*database.User, []string, error) {
+	ctx := telegramContext(updateMsg)
+
+	args := strings.Fields(updateMsg.CommandArguments())
+
+	if len(args) > 0 && strings.HasPrefix(args[0], "@") {
+		user, err := app.sqlModels.Users.GetByTelegramUsername(ctx, args[0][1:])
+		return user, args[1:], err
+	}
+
+	if updateMsg.ReplyToMessage != nil && updateMsg.ReplyToMessage.From != nil {
+		user, err := app.sqlModels.Users.GetByTelegramUserId(ctx, updateMsg.ReplyToMessage.From.ID)
+		return user, args, err
+	}
+
+	return nil, args, nil
+}
+
+// auditBotCommand writes the command to the audit log next to admin API requests,
+// failed records are logged and never fail the command
+func (app *application) auditBotCommand(moderator *database.User, updateMsg *tgbotapi.Message, status int, targetType, targetID string, details database.JSONB) {
+	log := &database.AuditLog{
+		ActorID:      &moderator.ID,
+		ActorAddress: &moderator.FriendlyAddress,
+		Method:       "BOT",
+		Route:        "/" + updateMsg.Command(),
+		Status:       status,
+		RequestID:    telegramRequestID(updateMsg),
+		CreatedAt:    time.Now().Unix(),
+	}
+
+	if targetType != "" {
+		log.TargetType = &targetType
+		log.TargetID = &targetID
+	}
+
+	if details != nil {
+		log.After = &details
+	}
+
+	err := app.sqlModels.Audit.Insert(telegramContext(updateMsg), log)
+	if err != nil {
+		app.logger.Warningw("error writing audit log", "command", updateMsg.Command(), "error", err)
+	}
+
+	app.logger.Infow("bot command", "command", updateMsg.Command(), "actor_id", moderator.ID, "status", status, "target_type", targetType, "target_id", targetID)
+}
+
+func (app *application) reply(msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message, text string) error {
+	msgConfig.Text = text
+	msgConfig.ParseMode = "HTML"
+	msgConfig.ReplyToMessageID = updateMsg.MessageID
+
+	_, err := app.send(msgConfig)
+
+	return err
+}
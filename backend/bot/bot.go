@@ -1,65 +1,150 @@
 package main
 
 import (
-	"log"
+	"fmt"
 	"os"
 	"os/signal"
+	"runtime/debug"
 	"syscall"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/ton-developer-program/internal/metrics"
 )
 
 func (app *application) startBot() error {
 
-	app.logger.Info("Authorized on account %s", app.bot.Self.UserName)
+	app.logger.Infow("authorized", "account", app.bot.Self.UserName)
+
+	// create a channel to handle OS signals
+	quit := make(chan os.Signal, 1)
+	// notify the quit channel for multiple signals
+	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
+
+	d := app.newDispatcher(app.config.Bot.Workers)
+
+	// Discord runs next to Telegram and stops with it
+	stopDiscord := app.startDiscord()
+	defer stopDiscord()
+
+	switch app.config.Bot.Mode {
+	case "webhook":
+		return app.serveWebhook(d, quit)
+	case "polling":
+		return app.poll(d, quit)
+	default:
+		return fmt.Errorf("unknown bot mode %q, must be polling or webhook", app.config.Bot.Mode)
+	}
+}
+
+// poll gets updates with long polling, only one instance of the bot can poll
+func (app *application) poll(d *dispatcher, quit <-chan os.Signal) error {
+	// getUpdates doesn't work while a webhook is set
+	_, err := app.bot.RemoveWebhook()
+	if err != nil {
+		return err
+	}
 
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
 	updates, err := app.bot.GetUpdatesChan(u)
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
 
-	// var allowedChats = 
-	// 	-592623628 
+	app.logger.Info("receiving updates with long polling")
 
-	// create a channel to handle OS signals
-	quit := make(chan os.Signal, 1)
-	// notify the quit channel for multiple signals
-	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
+	for {
+		select {
+		case update := <-updates:
+			d.dispatch(update)
 
-	// run the main loop in a goroutine so that it can be stopped gracefully
-	go func() {
-		for update := range updates {
-			if update.Message == nil {
-				continue
-			}
+		case <-quit:
+			app.logger.Info("Stopping bot...")
+			app.bot.StopReceivingUpdates()
+			// wait for updates already received
+			d.drain()
+			app.logger.Info("Bot stopped")
 
-			// check if chat id is negative number
-			if update.Message.Chat.ID < 0 && app.config.App.AlloweGroupChatID != update.Message.Chat.ID {
-                    continue
-			}
-			
+			return nil
+		}
+	}
+}
 
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, "")
+// handleUpdate processes the update, a failing or panicking handler is logged and recorded
+// to tg_failed_updates, it never stops the bot
+func (app *application) handleUpdate(update tgbotapi.Update) {
+	kind := app.updateKind(update)
 
-				err := app.routes(msg, update.Message)
-				if err != nil {
-					log.Panic(err)
-				}
-			}
+	processed, err := app.processUpdate(update)
+	if err != nil {
+		metrics.BotUpdates.WithLabelValues(kind, "failure").Inc()
+		app.recordFailedUpdate(update, kind, err)
+		return
+	}
+
+	if !processed {
+		metrics.BotUpdates.WithLabelValues(kind, "skipped").Inc()
+		return
+	}
 
+	metrics.BotUpdates.WithLabelValues(kind, "success").Inc()
+}
+
+// processUpdate routes the update to the handlers, false is returned for updates the bot ignores
+func (app *application) processUpdate(update tgbotapi.Update) (processed bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &panicError{value: r, stack: debug.Stack()}
+		}
 	}()
 
-	// listen to OS signals
-	<-quit
+	if update.CallbackQuery != nil {
+		return true, app.callbackHandler(update.CallbackQuery)
+	}
 
-	app.logger.Info("Stopping bot...")
-	// close the updates channel
-	app.bot.StopReceivingUpdates()
-	// wait for the main loop to finish
-	app.logger.Info("Bot stopped")
+	if update.Message == nil {
+		return false, nil
+	}
+
+	// group chats must be registered and enabled
+	if update.Message.Chat.ID < 0 {
+		chat, err := app.communityChat(update.Message.Chat.ID)
+		if err != nil {
+			return false, err
+		}
+
+		if chat == nil || !chat.Enabled {
+			return false, nil
+		}
+	}
+
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID, "")
+
+	return true, app.routes(msg, update.Message)
+}
+
+// updateKind is the command, "unknown", "message" or "callback", used as metrics label.
+// Commands are user input, only the ones the bot routes get their own label.
+func (app *application) updateKind(update tgbotapi.Update) string {
+	switch {
+	case update.CallbackQuery != nil:
+		return "callback"
+	case update.Message == nil:
+		return "none"
+	case update.Message.Command() != "":
+		command := update.Message.Command()
+
+		if _, ok := app.commands()[command]; ok {
+			return command
+		}
+
+		if _, ok := app.adminCommands()[command]; ok {
+			return command
+		}
+
+		return "unknown"
+	}
 
-	return nil
+	return "message"
 }
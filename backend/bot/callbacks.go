@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/i18n"
+	"github.com/ton-developer-program/internal/leveledlog"
+	"github.com/ton-developer-program/internal/tasks"
+)
+
+// callback data of the achievement buttons is "achievement:<action>:<id>"
+const (
+	callbackAchievement = "achievement"
+	actionAccept        = "accept"
+	actionDecline       = "decline"
+)
+
+func achievementKeyboard(lang string, id int64) tgbotapi.InlineKeyboardMarkup {
+	data := func(action string) string {
+		return fmt.Sprintf("%s:%s:%d", callbackAchievement, action, id)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "bot.achievement.accept"), data(actionAccept)),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "bot.achievement.decline"), data(actionDecline)),
+		),
+	)
+}
+
+// callbackHandler handles presses of inline keyboard buttons
+func (app *application) callbackHandler(query *tgbotapi.CallbackQuery) error {
+	ctx := leveledlog.ContextWithRequestID(context.Background(), "tg-callback-"+query.ID)
+
+	lang := app.telegramUserLanguage(ctx, query.From)
+
+	parts := strings.Split(query.Data, ":")
+
+	if len(parts) != 3 || parts[0] != callbackAchievement {
+		return app.answerCallback(query, i18n.T(lang, "bot.callback.unknown"))
+	}
+
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return app.answerCallback(query, i18n.T(lang, "bot.callback.unknown"))
+	}
+
+	return app.achievementCallbackHandler(ctx, lang, query, parts[1], id)
+}
+
+// achievementCallbackHandler accepts or declines the achievement, the Telegram user must be linked
+// to the account the achievement belongs to
+func (app *application) achievementCallbackHandler(ctx context.Context, lang string, query *tgbotapi.CallbackQuery, action string, id int64) error {
+	if action != actionAccept && action != actionDecline {
+		return app.answerCallback(query, i18n.T(lang, "bot.callback.unknown"))
+	}
+
+	user, err := app.sqlModels.Users.GetByTelegramUserId(ctx, query.From.ID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return app.answerCallback(query, i18n.T(lang, "bot.achievement.unlinked"))
+	}
+
+	achievement, err := app.sqlModels.Rewards.GetStoredRewardByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return app.answerCallback(query, i18n.T(lang, "bot.achievement.not_found"))
+		}
+		return err
+	}
+
+	if achievement.UserAddress != user.FriendlyAddress {
+		return app.answerCallback(query, i18n.T(lang, "bot.achievement.not_yours"))
+	}
+
+	if achievement.Processed || achievement.ApprovedByUser {
+		app.closeAchievementMessage(query, i18n.T(lang, "bot.achievement.processed"))
+		return app.answerCallback(query, i18n.T(lang, "bot.achievement.processed.short"))
+	}
+
+	if action == actionDecline {
+		err = app.sqlModels.Rewards.DeclineStoredReward(achievement.ID)
+		if err != nil {
+			// processed after it was loaded, e.g. declined twice with a double tap
+			if errors.Is(err, database.ErrRecordNotFound) {
+				app.closeAchievementMessage(query, i18n.T(lang, "bot.achievement.processed"))
+				return app.answerCallback(query, i18n.T(lang, "bot.achievement.processed.short"))
+			}
+			return err
+		}
+
+		app.logger.Infow("achievement declined", "user_id", user.ID, "achievement_id", achievement.ID)
+
+		app.closeAchievementMessage(query, i18n.T(lang, "bot.achievement.declined"))
+		return app.answerCallback(query, i18n.T(lang, "bot.achievement.declined.short"))
+	}
+
+	err = app.sqlModels.Rewards.UpdateStoredRewardApprovedByUser(achievement.ID, true)
+	if err != nil {
+		return err
+	}
+
+	// same task as PUT /v1/incoming-achievements/:id, mints every approved achievement
+	task := tasks.NewTask(ctx, database.TYPE_MINT_STORED_REWARDS, nil)
+
+	info, err := app.asynqClient.Enqueue(task, asynq.TaskID("MINT_STORED_REWARDS"), asynq.ProcessIn(10*time.Second), asynq.MaxRetry(5), asynq.Retention(30*time.Second), asynq.Queue(database.PRIORITY_URGENT))
+	switch {
+	case errors.Is(err, asynq.ErrTaskIDConflict):
+		// minting is already scheduled and picks this achievement up
+	case err != nil:
+		return err
+	default:
+		app.logger.Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+	}
+
+	app.logger.Infow("achievement accepted", "user_id", user.ID, "achievement_id", achievement.ID)
+
+	app.closeAchievementMessage(query, i18n.T(lang, "bot.achievement.accepted"))
+	return app.answerCallback(query, i18n.T(lang, "bot.achievement.accepted.short"))
+}
+
+// closeAchievementMessage replaces buttons of the message with the result
+func (app *application) closeAchievementMessage(query *tgbotapi.CallbackQuery, result string) {
+	if query.Message == nil {
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, query.Message.Text+"\n\n"+result)
+
+	if _, err := app.send(edit); err != nil {
+		app.logger.Warningw("error editing achievement message", "error", err)
+	}
+}
+
+func (app *application) answerCallback(query *tgbotapi.CallbackQuery, text string) error {
+	return app.retryTelegram(func() error {
+		_, err := app.bot.AnswerCallbackQuery(tgbotapi.NewCallback(query.ID, text))
+		return err
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/ton-developer-program/internal/database"
+)
+
+// chats are reloaded from the database this often, changes in the admin app are picked up without restart
+const chatsRefreshInterval = time.Minute
+
+// chatRegistry caches community chats from tg_chats
+type chatRegistry struct {
+	mu       sync.Mutex
+	chats    map[int64]*database.Chat
+	loadedAt time.Time
+}
+
+// communityChat returns the registered chat or nil, the registry is reloaded when it is stale
+func (app *application) communityChat(id int64) (*database.Chat, error) {
+	chats, err := app.communityChats()
+	if err != nil {
+		return nil, err
+	}
+
+	return chats[id], nil
+}
+
+func (app *application) communityChats() (map[int64]*database.Chat, error) {
+	app.chats.mu.Lock()
+	defer app.chats.mu.Unlock()
+
+	if app.chats.chats != nil && time.Since(app.chats.loadedAt) < chatsRefreshInterval {
+		return app.chats.chats, nil
+	}
+
+	// the registry is shared by all updates, its reload doesn't belong to the update that triggered it
+	all, err := app.sqlModels.Chats.GetAll(context.Background())
+	if err != nil {
+		// keep serving stale chats when the database is unavailable
+		if app.chats.chats != nil {
+			app.logger.Warningw("error reloading chats", "error", err)
+			return app.chats.chats, nil
+		}
+		return nil, err
+	}
+
+	chats := make(map[int64]*database.Chat, len(all))
+	for _, chat := range all {
+		chats[chat.ID] = chat
+	}
+
+	app.chats.chats = chats
+	app.chats.loadedAt = time.Now()
+
+	return chats, nil
+}
+
+// registerAllowedChat adds the chat with its Telegram title to the registry if it is not there yet
+func (app *application) registerAllowedChat(ctx context.Context, id int64) error {
+	title := ""
+
+	chat, err := app.bot.GetChat(tgbotapi.ChatConfig{ChatID: id})
+	if err != nil {
+		app.logger.Warningw("error getting chat title", "chat_id", id, "error", err)
+	} else {
+		title = chat.Title
+	}
+
+	return app.sqlModels.Chats.EnsureExists(ctx, id, title)
+}
+
+// chatLinks lists enabled chats with invite links for the start message
+func (app *application) chatLinks() string {
+	chats, err := app.communityChats()
+	if err != nil {
+		app.logger.Warningw("error loading chats", "error", err)
+		return ""
+	}
+
+	ids := make([]int64, 0, len(chats))
+	for id := range chats {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return chats[ids[i]].CreatedAt < chats[ids[j]].CreatedAt })
+
+	links := []string{}
+
+	for _, id := range ids {
+		chat := chats[id]
+
+		if chat.Enabled && chat.InviteURL != nil && *chat.InviteURL != "" {
+			links = append(links, fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(*chat.InviteURL), html.EscapeString(chat.Title)))
+		}
+	}
+
+	return strings.Join(links, ", ")
+}
+
+// welcomeHandler greets new members with the welcome text of the chat, {name} is replaced with their names
+func (app *application) welcomeHandler(msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+	chat, err := app.communityChat(updateMsg.Chat.ID)
+	if err != nil {
+		return err
+	}
+
+	if chat == nil || chat.WelcomeText == nil || *chat.WelcomeText == "" {
+		return nil
+	}
+
+	names := []string{}
+
+	for _, member := range *updateMsg.NewChatMembers {
+		if !member.IsBot {
+			names = append(names, html.EscapeString(member.FirstName))
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	msgConfig.Text = strings.ReplaceAll(*chat.WelcomeText, "{name}", strings.Join(names, ", "))
+	msgConfig.ParseMode = "HTML"
+	msgConfig.DisableWebPagePreview = true
+
+	_, err = app.send(msgConfig)
+
+	return err
+}
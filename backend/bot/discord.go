@@ -0,0 +1,462 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/i18n"
+	"github.com/ton-developer-program/internal/metrics"
+	"github.com/ton-developer-program/internal/tasks"
+)
+
+// discordCommands are registered in every guild from DISCORD_GUILDS
+var discordCommands = []*discordgo.ApplicationCommand{
+	{
+		Name:        "rating",
+		Description: "Your position in the TON Developers leaderboard",
+	},
+	{
+		Name:        "whois",
+		Description: "Profile of a member on TON Developers Platform",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "member", Description: "Member to show", Required: true},
+		},
+	},
+	{
+		Name:        "thanks",
+		Description: "Thank a member for help, they get a kudos",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "member", Description: "Member to thank", Required: true},
+		},
+	},
+}
+
+// discordPlatform scores messages in Discord guilds and answers /rating, /whois and /thanks,
+// members link Discord accounts in the settings on the platform
+type discordPlatform struct {
+	app     *application
+	session *discordgo.Session
+	guilds  map[string]bool
+	// events are handled concurrently, the gateway keeps reading while handlers wait
+	wg sync.WaitGroup
+}
+
+func (app *application) newDiscordPlatform() (*discordPlatform, error) {
+	session, err := discordgo.New("Bot " + app.config.Discord.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	session.Identify.Intents = discordgo.IntentsGuilds | discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent
+	// handlers start their own goroutines tracked by wg, the bot doesn't use the state cache
+	session.SyncEvents = true
+	session.StateEnabled = false
+
+	// errors and warnings of the library go to the bot log, the session logs at LogError by default
+	discordgo.Logger = func(level, caller int, format string, a ...any) {
+		app.logger.Warningw(fmt.Sprintf(format, a...), "platform", "discord", "library", "discordgo")
+	}
+
+	d := &discordPlatform{
+		app:     app,
+		session: session,
+		guilds:  map[string]bool{},
+	}
+
+	for _, guild := range app.config.Discord.Guilds {
+		d.guilds[guild] = true
+	}
+
+	session.AddHandler(func(_ *discordgo.Session, ready *discordgo.Ready) {
+		d.handleEvent("READY", func() (string, error) {
+			d.app.logger.Infow("connected to discord", "username", ready.User.Username)
+
+			return "ready", d.registerCommands(ready.Application.ID)
+		})
+	})
+
+	session.AddHandler(func(_ *discordgo.Session, message *discordgo.MessageCreate) {
+		// messages of bots and webhooks and messages outside of community guilds are not scored
+		if message.Author == nil || message.Author.Bot || message.WebhookID != "" || !d.guilds[message.GuildID] {
+			return
+		}
+
+		d.handleEvent("MESSAGE_CREATE", func() (string, error) {
+			return "message", d.app.scoreHandler(d.message(message.Message))
+		})
+	})
+
+	session.AddHandler(func(_ *discordgo.Session, interaction *discordgo.InteractionCreate) {
+		if interaction.Type != discordgo.InteractionApplicationCommand || discordAuthor(interaction.Interaction) == nil {
+			return
+		}
+
+		d.handleEvent("INTERACTION_CREATE", func() (string, error) {
+			msg := d.interaction(interaction.Interaction)
+
+			switch msg.Command {
+			case "rating":
+				return msg.Command, d.app.ratingHandler(msg)
+			case "whois":
+				return msg.Command, d.app.whoisHandler(msg)
+			case "thanks":
+				return msg.Command, d.app.thanksHandler(msg)
+			}
+
+			return "", nil
+		})
+	})
+
+	return d, nil
+}
+
+// startDiscord connects to the Discord gateway when DISCORD_BOT_TOKEN is set, the returned
+// function disconnects and waits for events being handled
+func (app *application) startDiscord() func() {
+	if app.config.Discord.Token == "" {
+		return func() {}
+	}
+
+	app.logger.Info("connecting to the discord gateway")
+
+	d, err := app.newDiscordPlatform()
+	if err == nil {
+		// the session reconnects by itself once it's open
+		err = d.session.Open()
+	}
+	if err != nil {
+		// a failed connection stops only the Discord adapter, Telegram keeps working
+		app.logger.Errorw(err, "platform", "discord")
+		return func() {}
+	}
+
+	return func() {
+		err := d.session.Close()
+		if err != nil {
+			app.logger.Warningw("error closing discord session", "error", err)
+		}
+
+		d.wg.Wait()
+	}
+}
+
+// handleEvent runs process in its own goroutine, process returns the command, "message"
+// or empty kind for events the bot ignores
+func (d *discordPlatform) handleEvent(event string, process func() (string, error)) {
+	d.wg.Add(1)
+
+	go func() {
+		defer d.wg.Done()
+
+		kind, err := d.processEvent(process)
+		if kind == "" {
+			return
+		}
+
+		if err != nil {
+			metrics.BotUpdates.WithLabelValues(kind, "failure").Inc()
+
+			logger := d.app.logger.With("platform", "discord", "event", event, "kind", kind)
+
+			var pErr *panicError
+			if errors.As(err, &pErr) {
+				logger.Error(err, pErr.stack)
+			} else {
+				logger.Errorw(err)
+			}
+
+			return
+		}
+
+		metrics.BotUpdates.WithLabelValues(kind, "success").Inc()
+	}()
+}
+
+func (d *discordPlatform) processEvent(process func() (string, error)) (kind string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &panicError{value: r, stack: debug.Stack()}
+		}
+	}()
+
+	return process()
+}
+
+// registerCommands replaces slash commands in community guilds, guild commands are available at once
+func (d *discordPlatform) registerCommands(applicationID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for guild := range d.guilds {
+		_, err := d.session.ApplicationCommandBulkOverwrite(applicationID, guild, discordCommands, discordgo.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("registering commands in guild %s: %w", guild, err)
+		}
+	}
+
+	return nil
+}
+
+// message converts the Discord message for community handlers
+func (d *discordPlatform) message(message *discordgo.Message) *chatMessage {
+	forward := message.MessageReference != nil && message.MessageReference.Type == discordgo.MessageReferenceTypeForward
+
+	msg := &chatMessage{
+		Platform:  d,
+		ChatID:    snowflake(message.ChannelID),
+		MessageID: snowflake(message.ID),
+		Private:   message.GuildID == "",
+		From:      discordUser(message.Author),
+		Text:      message.Content,
+		Sticker:   len(message.StickerItems) > 0,
+		Forward:   forward,
+		origin:    message,
+		requestID: fmt.Sprintf("discord-%s-%s", message.ChannelID, message.ID),
+	}
+
+	for _, mention := range message.Mentions {
+		msg.Mentions = append(msg.Mentions, discordUser(mention))
+	}
+
+	if reply := message.ReferencedMessage; reply != nil && !msg.Forward {
+		msg.ReplyTo = &chatMessage{
+			Platform:  d,
+			ChatID:    snowflake(reply.ChannelID),
+			MessageID: snowflake(reply.ID),
+			From:      discordUser(reply.Author),
+			origin:    reply,
+		}
+	}
+
+	return msg
+}
+
+// interaction converts the slash command for community handlers
+func (d *discordPlatform) interaction(interaction *discordgo.Interaction) *chatMessage {
+	data := interaction.ApplicationCommandData()
+
+	msg := &chatMessage{
+		Platform: d,
+		ChatID:   snowflake(interaction.ChannelID),
+		Private:  interaction.GuildID == "",
+		From:     discordUser(discordAuthor(interaction)),
+		Command:  data.Name,
+		origin:   interaction,
+		// interactions are not messages, the interaction id stands for the message id
+		requestID: fmt.Sprintf("discord-%s-%s", interaction.ChannelID, interaction.ID),
+	}
+
+	for _, option := range data.Options {
+		if option.Name != "member" || option.Type != discordgo.ApplicationCommandOptionUser || data.Resolved == nil {
+			continue
+		}
+
+		if member, ok := data.Resolved.Users[fmt.Sprint(option.Value)]; ok {
+			msg.Mentions = []chatUser{discordUser(member)}
+		}
+	}
+
+	return msg
+}
+
+// discordAuthor is the member of guild interactions and the user of direct messages
+func discordAuthor(interaction *discordgo.Interaction) *discordgo.User {
+	if interaction.Member != nil && interaction.Member.User != nil {
+		return interaction.Member.User
+	}
+
+	return interaction.User
+}
+
+func discordUser(user *discordgo.User) chatUser {
+	if user == nil {
+		return chatUser{}
+	}
+
+	return chatUser{ID: snowflake(user.ID), Username: user.Username, Bot: user.Bot}
+}
+
+// snowflake converts Discord ids, they are sent as strings
+func snowflake(id string) int64 {
+	n, _ := strconv.ParseInt(id, 10, 64)
+	return n
+}
+
+func (d *discordPlatform) name() string {
+	return database.ProviderDiscord
+}
+
+func (d *discordPlatform) linkedUser(ctx context.Context, member chatUser) (*database.User, error) {
+	return d.app.sqlModels.Users.GetByDiscordUserId(ctx, member.ID)
+}
+
+// userByName is not used, Discord passes members of commands by id
+func (d *discordPlatform) userByName(ctx context.Context, username string) (*database.User, error) {
+	return nil, nil
+}
+
+// language of the guild for guild commands, the language of the member's client otherwise
+func (d *discordPlatform) language(msg *chatMessage) string {
+	interaction, ok := msg.origin.(*discordgo.Interaction)
+	if !ok {
+		return i18n.English
+	}
+
+	if interaction.GuildID != "" && interaction.GuildLocale != nil {
+		return i18n.Match(string(*interaction.GuildLocale), string(interaction.Locale))
+	}
+
+	return i18n.Match(string(interaction.Locale))
+}
+
+// reply answers the slash command, messages are only scored and never answered
+func (d *discordPlatform) reply(msg *chatMessage, r chatReply) error {
+	interaction, ok := msg.origin.(*discordgo.Interaction)
+	if !ok {
+		return errors.New("discord replies are sent only to commands")
+	}
+
+	data := &discordgo.InteractionResponseData{
+		Content: discordMarkdown(r.Text),
+		// usernames in replies don't ping anyone
+		AllowedMentions: &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}},
+		Flags:           discordgo.MessageFlagsSuppressEmbeds,
+	}
+
+	if r.ButtonURL != "" {
+		data.Components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{Style: discordgo.LinkButton, Label: r.ButtonText, URL: r.ButtonURL},
+				},
+			},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return d.session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	}, discordgo.WithContext(ctx))
+}
+
+// chatWeight scores messages of community guilds with weight 1
+func (d *discordPlatform) chatWeight(msg *chatMessage) (int, bool, error) {
+	message, ok := msg.origin.(*discordgo.Message)
+	if !ok || !d.guilds[message.GuildID] {
+		return 0, false, nil
+	}
+
+	return 1, true, nil
+}
+
+func (d *discordPlatform) score(msg *chatMessage, weight int) error {
+	message := msg.origin.(*discordgo.Message)
+
+	payload, err := json.Marshal(&database.DiscordMessage{
+		UserID:    msg.From.ID,
+		MessageID: msg.MessageID,
+		ChannelID: msg.ChatID,
+		GuildID:   snowflake(message.GuildID),
+		Weight:    weight,
+	})
+	if err != nil {
+		return err
+	}
+
+	task := tasks.NewTask(msg.context(), database.TYPE_ADD_DISCORD_MESSAGE, payload)
+
+	info, err := d.app.asynqClient.Enqueue(task, asynq.MaxRetry(5), asynq.ProcessIn(5*time.Second), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_NORMAL))
+	if err != nil {
+		return err
+	}
+
+	d.app.logger.Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+
+	return nil
+}
+
+// react doesn't add reaction bonus, Discord members react with emoji rather than replies
+func (d *discordPlatform) react(msg *chatMessage, policy scoringPolicy) error {
+	return nil
+}
+
+// tags of Telegram HTML used in bot messages
+var htmlTagRe = regexp.MustCompile(`<a href="([^"]*)">|</a>|</?(b|strong|i|em|u|s|code|pre)>`)
+
+var markdownTags = map[string]string{
+	"b":      "**",
+	"strong": "**",
+	"i":      "*",
+	"em":     "*",
+	"u":      "__",
+	"s":      "~~",
+	"code":   "`",
+	"pre":    "```",
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"~", `\~`,
+	"`", "\\`",
+	"|", `\|`,
+	"[", `\[`,
+	"]", `\]`,
+)
+
+// discordMarkdown converts Telegram HTML of replies to Discord markdown, links become
+// masked links and text outside of tags and code is escaped
+func discordMarkdown(text string) string {
+	var b strings.Builder
+
+	var link string
+	code := false
+	last := 0
+
+	writeText := func(segment string) {
+		segment = html.UnescapeString(segment)
+		if !code {
+			segment = markdownEscaper.Replace(segment)
+		}
+		b.WriteString(segment)
+	}
+
+	for _, match := range htmlTagRe.FindAllStringSubmatchIndex(text, -1) {
+		writeText(text[last:match[0]])
+		last = match[1]
+
+		switch {
+		case match[2] >= 0:
+			link = html.UnescapeString(text[match[2]:match[3]])
+			b.WriteString("[")
+		case text[match[0]:match[1]] == "</a>":
+			b.WriteString("](" + link + ")")
+		default:
+			name := text[match[4]:match[5]]
+			if name == "code" || name == "pre" {
+				code = !strings.HasPrefix(text[match[0]:match[1]], "</")
+			}
+			b.WriteString(markdownTags[name])
+		}
+	}
+
+	writeText(text[last:])
+
+	return b.String()
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
+	"github.com/hibiken/asynq"
+	"github.com/jmoiron/sqlx"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/leveledlog"
+	"github.com/ton-developer-program/internal/tasks"
+	"github.com/ton-developer-program/util"
+)
+
+const (
+	testApplicationID = "100"
+	testGuildID       = "200"
+	testChannelID     = "300"
+)
+
+// fakeRequest is a REST request the bot sent to the fake gateway
+type fakeRequest struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// fakeGateway speaks HELLO, IDENTIFY and DISPATCH of the Discord gateway and answers REST requests
+type fakeGateway struct {
+	t        *testing.T
+	server   *httptest.Server
+	conn     chan *websocket.Conn
+	identify chan map[string]any
+	requests chan fakeRequest
+	seq      int
+}
+
+func newFakeGateway(t *testing.T) *fakeGateway {
+	g := &fakeGateway{
+		t:        t,
+		conn:     make(chan *websocket.Conn, 1),
+		identify: make(chan map[string]any, 1),
+		requests: make(chan fakeRequest, 10),
+	}
+
+	g.server = httptest.NewServer(http.HandlerFunc(g.serveHTTP))
+	t.Cleanup(g.server.Close)
+
+	return g
+}
+
+func (g *fakeGateway) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/gateway"):
+		json.NewEncoder(w).Encode(map[string]string{"url": "ws" + strings.TrimPrefix(g.server.URL, "http") + "/ws"})
+
+	// the session adds a slash and the version to the url
+	case strings.HasPrefix(r.URL.Path, "/ws"):
+		g.serveGateway(w, r)
+
+	default:
+		body, _ := io.ReadAll(r.Body)
+		g.requests <- fakeRequest{Method: r.Method, Path: r.URL.Path, Body: body}
+
+		if r.Method == http.MethodPut {
+			w.Write([]byte("[]"))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// serveGateway says HELLO, waits for IDENTIFY and dispatches READY like Discord does
+func (g *fakeGateway) serveGateway(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		g.t.Error(err)
+		return
+	}
+
+	err = conn.WriteJSON(map[string]any{"op": 10, "d": map[string]any{"heartbeat_interval": 45000}})
+	if err != nil {
+		g.t.Error(err)
+		return
+	}
+
+	var identify struct {
+		Op int            `json:"op"`
+		D  map[string]any `json:"d"`
+	}
+
+	err = conn.ReadJSON(&identify)
+	if err != nil || identify.Op != 2 {
+		g.t.Errorf("expected IDENTIFY, got op %d: %v", identify.Op, err)
+		return
+	}
+
+	g.identify <- identify.D
+
+	g.seq++
+	err = conn.WriteJSON(map[string]any{"op": 0, "t": "READY", "s": g.seq, "d": map[string]any{
+		"v":           9,
+		"session_id":  "session",
+		"user":        map[string]any{"id": "1", "username": "tdp", "bot": true},
+		"application": map[string]any{"id": testApplicationID},
+		"guilds":      []any{},
+	}})
+	if err != nil {
+		g.t.Error(err)
+		return
+	}
+
+	g.conn <- conn
+
+	// heartbeats and the close frame of the bot
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (g *fakeGateway) dispatch(conn *websocket.Conn, event string, data any) {
+	g.t.Helper()
+
+	g.seq++
+	err := conn.WriteJSON(map[string]any{"op": 0, "t": event, "s": g.seq, "d": data})
+	if err != nil {
+		g.t.Fatal(err)
+	}
+}
+
+func (g *fakeGateway) request() fakeRequest {
+	g.t.Helper()
+
+	select {
+	case req := <-g.requests:
+		return req
+	case <-time.After(5 * time.Second):
+		g.t.Fatal("no request from the bot")
+		return fakeRequest{}
+	}
+}
+
+// RoundTrip sends requests for discord.com to the fake gateway
+func (g *fakeGateway) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = strings.TrimPrefix(g.server.URL, "http://")
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+var userColumns = []string{"id", "username", "friendly_address", "rating"}
+
+func TestDiscordGateway(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mr := miniredis.RunT(t)
+
+	app := &application{
+		config: util.Config{
+			App:     util.AppConfig{BaseUrl: "https://tdp.test"},
+			Discord: util.DiscordConfig{Token: "token", Guilds: []string{testGuildID}},
+		},
+		sqlModels:            database.NewModels(sqlx.NewDb(db, "postgres")),
+		logger:               leveledlog.NewLogger(io.Discard, leveledlog.LevelAll, false),
+		asynqClient:          asynq.NewClient(asynq.RedisClientOpt{Addr: mr.Addr()}),
+		redisClient:          redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		defaultScoringPolicy: defaultScoringPolicy,
+	}
+	defer app.asynqClient.Close()
+
+	gateway := newFakeGateway(t)
+
+	d, err := app.newDiscordPlatform()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.session.Client = &http.Client{Transport: gateway}
+
+	err = d.session.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		d.session.Close()
+		d.wg.Wait()
+	}()
+
+	identify := <-gateway.identify
+	if identify["token"] != "Bot token" {
+		t.Errorf("identified with token %v", identify["token"])
+	}
+
+	conn := <-gateway.conn
+
+	req := gateway.request()
+	if req.Method != http.MethodPut || req.Path != "/api/v9/applications/"+testApplicationID+"/guilds/"+testGuildID+"/commands" {
+		t.Fatalf("expected commands registration, got %s %s", req.Method, req.Path)
+	}
+
+	// rows are read once, every query gets its own
+	alice := func() *sqlmock.Rows { return sqlmock.NewRows(userColumns).AddRow(1, "alice", "EQalice", 12) }
+	bob := func() *sqlmock.Rows { return sqlmock.NewRows(userColumns).AddRow(2, "bob", "EQbob", 30) }
+
+	t.Run("message scoring", func(t *testing.T) {
+		mock.ExpectQuery(`discord_user_id = \$1`).WithArgs(42).WillReturnRows(alice())
+		mock.ExpectQuery(`FROM tg_muted_users`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		gateway.dispatch(conn, "MESSAGE_CREATE", map[string]any{
+			"id":         "500",
+			"channel_id": testChannelID,
+			"guild_id":   testGuildID,
+			"author":     map[string]any{"id": "42", "username": "alice"},
+			"content":    "the wallet connects with the proof from tonconnect",
+		})
+
+		inspector := asynq.NewInspector(asynq.RedisClientOpt{Addr: mr.Addr()})
+		defer inspector.Close()
+
+		var scheduled []*asynq.TaskInfo
+
+		for deadline := time.Now().Add(5 * time.Second); len(scheduled) == 0 && time.Now().Before(deadline); {
+			time.Sleep(20 * time.Millisecond)
+			scheduled, _ = inspector.ListScheduledTasks(database.PRIORITY_NORMAL)
+		}
+
+		if len(scheduled) != 1 {
+			t.Fatalf("expected a scheduled task, got %d", len(scheduled))
+		}
+
+		task := asynq.NewTask(scheduled[0].Type, scheduled[0].Payload)
+		if task.Type() != database.TYPE_ADD_DISCORD_MESSAGE {
+			t.Errorf("enqueued %s", task.Type())
+		}
+
+		if id := tasks.RequestID(task); id != "discord-300-500" {
+			t.Errorf("request id %q", id)
+		}
+
+		var message database.DiscordMessage
+		if err := tasks.Decode(task, &message); err != nil {
+			t.Fatal(err)
+		}
+
+		if message.UserID != 42 || message.MessageID != 500 || message.ChannelID != 300 || message.GuildID != 200 || message.Weight != 1 {
+			t.Errorf("scored %+v", message)
+		}
+	})
+
+	t.Run("rating", func(t *testing.T) {
+		mock.ExpectQuery(`discord_user_id = \$1`).WithArgs(42).WillReturnRows(alice())
+		expectProfile(mock, "EQalice", 1, 12)
+
+		gateway.dispatch(conn, "INTERACTION_CREATE", interaction("600", map[string]any{"id": "700", "name": "rating", "type": 1}))
+
+		reply := gateway.request()
+		if reply.Path != "/api/v9/interactions/600/token-600/callback" {
+			t.Fatalf("replied to %s", reply.Path)
+		}
+
+		content := replyContent(t, reply)
+		for _, want := range []string{"alice", "Position: 3 out of 10", "Rating: 12"} {
+			if !strings.Contains(content, want) {
+				t.Errorf("reply %q doesn't contain %q", content, want)
+			}
+		}
+	})
+
+	t.Run("whois", func(t *testing.T) {
+		mock.ExpectQuery(`discord_user_id = \$1`).WithArgs(42).WillReturnRows(alice())
+		mock.ExpectQuery(`discord_user_id = \$1`).WithArgs(43).WillReturnRows(bob())
+		expectProfile(mock, "EQbob", 2, 30)
+
+		gateway.dispatch(conn, "INTERACTION_CREATE", interaction("601", map[string]any{
+			"id":       "701",
+			"name":     "whois",
+			"type":     1,
+			"options":  []any{map[string]any{"name": "member", "type": 6, "value": "43"}},
+			"resolved": map[string]any{"users": map[string]any{"43": map[string]any{"id": "43", "username": "bob"}}},
+		}))
+
+		reply := gateway.request()
+		if reply.Path != "/api/v9/interactions/601/token-601/callback" {
+			t.Fatalf("replied to %s", reply.Path)
+		}
+
+		content := replyContent(t, reply)
+		for _, want := range []string{"bob", "Rating: 30", "https://tdp.test/user/bob"} {
+			if !strings.Contains(content, want) {
+				t.Errorf("reply %q doesn't contain %q", content, want)
+			}
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// expectProfile expects queries of profileMessage, the user has no SBTs and the third position of 10
+func expectProfile(mock sqlmock.Sqlmock, address string, userID int64, rating float64) {
+	mock.ExpectQuery(`FROM sbt_tokens`).WithArgs(address).WillReturnRows(sqlmock.NewRows([]string{"friendly_address", "name", "weight"}))
+	mock.ExpectQuery(`SELECT rating FROM users`).WithArgs(userID).WillReturnRows(sqlmock.NewRows([]string{"rating"}).AddRow(rating))
+	mock.ExpectQuery(`WHERE rating > \$1`).WithArgs(rating).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users$`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+}
+
+// interaction is a slash command of alice in the community guild
+func interaction(id string, data map[string]any) map[string]any {
+	return map[string]any{
+		"id":             id,
+		"application_id": testApplicationID,
+		"type":           2,
+		"token":          "token-" + id,
+		"channel_id":     testChannelID,
+		"guild_id":       testGuildID,
+		"locale":         "en-US",
+		"guild_locale":   "en-US",
+		"member":         map[string]any{"user": map[string]any{"id": "42", "username": "alice"}},
+		"data":           data,
+	}
+}
+
+// replyContent returns the content of the interaction response with the link of the button
+func replyContent(t *testing.T, req fakeRequest) string {
+	t.Helper()
+
+	var response struct {
+		Type int `json:"type"`
+		Data struct {
+			Content    string `json:"content"`
+			Components []struct {
+				Components []struct {
+					URL string `json:"url"`
+				} `json:"components"`
+			} `json:"components"`
+		} `json:"data"`
+	}
+
+	err := json.Unmarshal(req.Body, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Type != 4 {
+		t.Errorf("response type %d", response.Type)
+	}
+
+	content := response.Data.Content
+	for _, row := range response.Data.Components {
+		for _, button := range row.Components {
+			content += "\n" + button.URL
+		}
+	}
+
+	return content
+}
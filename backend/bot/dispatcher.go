@@ -0,0 +1,73 @@
+package main
+
+import (
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// updates waiting for a busy worker, webhook requests block when the queue is full
+const dispatcherQueueSize = 64
+
+// dispatcher processes updates concurrently, updates of one chat always go to the same
+// worker so they are handled in the order Telegram sent them
+type dispatcher struct {
+	queues []chan tgbotapi.Update
+	wg     sync.WaitGroup
+}
+
+func (app *application) newDispatcher(workers int) *dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+
+	d := &dispatcher{
+		queues: make([]chan tgbotapi.Update, workers),
+	}
+
+	for i := range d.queues {
+		queue := make(chan tgbotapi.Update, dispatcherQueueSize)
+		d.queues[i] = queue
+
+		d.wg.Add(1)
+
+		go func() {
+			defer d.wg.Done()
+
+			for update := range queue {
+				app.handleUpdate(update)
+			}
+		}()
+	}
+
+	return d
+}
+
+func (d *dispatcher) dispatch(update tgbotapi.Update) {
+	d.queues[uint64(updateChatID(update))%uint64(len(d.queues))] <- update
+}
+
+// drain stops the workers once queued updates are processed, dispatch must not be called after it
+func (d *dispatcher) drain() {
+	for _, queue := range d.queues {
+		close(queue)
+	}
+
+	d.wg.Wait()
+}
+
+// updateChatID returns the chat of the update, updates without a chat share worker 0
+func updateChatID(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	case update.EditedMessage != nil:
+		return update.EditedMessage.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return update.CallbackQuery.Message.Chat.ID
+	case update.CallbackQuery != nil:
+		return int64(update.CallbackQuery.From.ID)
+	}
+
+	return 0
+}
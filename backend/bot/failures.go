@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/tasks"
+)
+
+// Telegram API calls are retried this many times on rate limits and server errors
+const (
+	telegramMaxAttempts = 3
+	// longer retry_after is not waited for, the update is recorded as failed instead
+	telegramMaxRetryAfter = 30 * time.Second
+)
+
+// panicError is a recovered panic of a handler
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// recordFailedUpdate logs the error with the update and stores the update for replay
+func (app *application) recordFailedUpdate(update tgbotapi.Update, kind string, err error) {
+	chatID := updateChatID(update)
+
+	logger := app.logger.With("update_id", update.UpdateID, "chat_id", chatID, "kind", kind)
+
+	failed := &database.FailedUpdate{
+		UpdateID: int64(update.UpdateID),
+		Kind:     kind,
+		Error:    err.Error(),
+	}
+
+	if chatID != 0 {
+		failed.ChatID = &chatID
+	}
+
+	var pErr *panicError
+	if errors.As(err, &pErr) {
+		failed.Panicked = true
+		logger.Error(err, pErr.stack)
+	} else {
+		logger.Errorw(err)
+	}
+
+	data, err := json.Marshal(update)
+	if err == nil {
+		err = json.Unmarshal(data, &failed.Payload)
+	}
+
+	if err == nil {
+		err = app.sqlModels.FailedUpdates.Insert(context.Background(), failed)
+	}
+
+	if err != nil {
+		logger.Warningw("error recording failed update", "error", err)
+		return
+	}
+
+	logger.Infow("recorded failed update", "failed_update_id", failed.ID)
+}
+
+// replayUpdateTask processes a failed update again, enqueued from the admin API
+func (app *application) replayUpdateTask(ctx context.Context, t *asynq.Task) error {
+	var payloadData struct {
+		ID int64 `json:"id"`
+	}
+
+	if err := tasks.Decode(t, &payloadData); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	failed, err := app.sqlModels.FailedUpdates.GetByID(ctx, payloadData.ID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			return fmt.Errorf("failed update %d not found: %w", payloadData.ID, asynq.SkipRetry)
+		}
+		return err
+	}
+
+	if failed.ReplayedAt != nil {
+		return nil
+	}
+
+	var update tgbotapi.Update
+
+	data, err := json.Marshal(failed.Payload)
+	if err == nil {
+		err = json.Unmarshal(data, &update)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid payload of failed update %d: %v: %w", failed.ID, err, asynq.SkipRetry)
+	}
+
+	_, replayErr := app.processUpdate(update)
+
+	err = app.sqlModels.FailedUpdates.RecordReplay(ctx, failed.ID, replayErr)
+	if err != nil {
+		return err
+	}
+
+	// the update is replayed again only on request
+	if replayErr != nil {
+		return fmt.Errorf("replay of failed update %d: %v: %w", failed.ID, replayErr, asynq.SkipRetry)
+	}
+
+	app.logger.Ctx(ctx).Infow("replayed failed update", "failed_update_id", failed.ID)
+
+	return nil
+}
+
+// send sends the message and retries rate limits and transient errors of the Telegram API
+func (app *application) send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var msg tgbotapi.Message
+
+	err := app.retryTelegram(func() error {
+		var err error
+		msg, err = app.bot.Send(c)
+		return err
+	})
+
+	return msg, err
+}
+
+func (app *application) retryTelegram(call func() error) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		err = call()
+		if err == nil || attempt == telegramMaxAttempts {
+			return err
+		}
+
+		delay, ok := telegramRetryDelay(err, attempt)
+		if !ok {
+			return err
+		}
+
+		app.logger.Warningw("retrying telegram request", "attempt", attempt, "delay", delay.String(), "error", err.Error())
+
+		time.Sleep(delay)
+	}
+}
+
+// telegramRetryDelay returns how long to wait before the next attempt, false if the error is not transient
+func telegramRetryDelay(err error, attempt int) (time.Duration, bool) {
+	var tgErr tgbotapi.Error
+
+	if errors.As(err, &tgErr) {
+		if tgErr.RetryAfter > 0 {
+			delay := time.Duration(tgErr.RetryAfter) * time.Second
+			return delay, delay <= telegramMaxRetryAfter
+		}
+
+		for _, transient := range []string{"Internal Server Error", "Bad Gateway", "Gateway Timeout", "Service Unavailable"} {
+			if strings.Contains(tgErr.Message, transient) {
+				return time.Duration(attempt) * time.Second, true
+			}
+		}
+
+		return 0, false
+	}
+
+	// connection errors and html error pages of the proxy in front of the API
+	var netErr net.Error
+	var urlErr *url.Error
+	var syntaxErr *json.SyntaxError
+
+	if errors.As(err, &netErr) || errors.As(err, &urlErr) || errors.As(err, &syntaxErr) {
+		return time.Duration(attempt) * time.Second, true
+	}
+
+	return 0, false
+}
@@ -1,13 +1,13 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
-	"time"
+	"html"
+	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
-	"github.com/hibiken/asynq"
 	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/i18n"
+	"github.com/ton-developer-program/internal/metrics"
 )
 
 // start handler
@@ -18,25 +18,23 @@ func (app *application) startHandler(msgConfig tgbotapi.MessageConfig, updateMsg
 		return nil
 	}
 
-	msgConfig.Text = `
-	👋 Hello, I'm your personal bot for TON Developers Platform!
-
-		I will help you stay up-to-date with your new rewards and all the events for developers in TON. I'm also available in every chat from <a href='https://t.me/c/1913693703/66'>TON Dev Kit</a>.
-
-		Commands:
-
-		/rating - check your current rating
+	// opened with the deep link from the settings
+	if token := updateMsg.CommandArguments(); token != "" && updateMsg.Chat.IsPrivate() {
+		return app.linkHandler(msgConfig, updateMsg, token)
+	}
 
-		/whois - get information about a chat participant via reply
+	lang := app.language(updateMsg)
 
-		/whois [@username] - get information about a participant via Telegram username
+	chats := ""
+	if links := app.chatLinks(); links != "" {
+		chats = i18n.T(lang, "bot.start.chats", links)
+	}
 
-		/help - display the list of commands	
-	`
+	msgConfig.Text = i18n.T(lang, "bot.start", chats)
 
 	msgConfig.ParseMode = "HTML"
 
-	if _, err := app.bot.Send(msgConfig); err != nil {
+	if _, err := app.send(msgConfig); err != nil {
 		return err
 	}
 
@@ -52,19 +50,9 @@ func (app *application) helpHandler(msgConfig tgbotapi.MessageConfig, updateMsg
 		return nil
 	}
 
-	msgConfig.Text = `
-	Commands:
-
-	/rating - check your current rating
+	msgConfig.Text = i18n.T(app.language(updateMsg), "bot.help")
 
-	/whois - get information about a chat participant via reply
-
-	/whois [@username] - get information about a participant via Telegram username
-
-	/help - display the list of commands	
-	`
-
-	if _, err := app.bot.Send(msgConfig); err != nil {
+	if _, err := app.send(msgConfig); err != nil {
 		return err
 	}
 
@@ -72,113 +60,76 @@ func (app *application) helpHandler(msgConfig tgbotapi.MessageConfig, updateMsg
 
 }
 
-func (app *application) ratingHandler(msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+func (app *application) ratingHandler(msg *chatMessage) error {
+	lang := msg.Platform.language(msg)
 
-	user, err := app.sqlModels.Users.GetByTelegramUserId(updateMsg.From.ID)
+	user, err := msg.Platform.linkedUser(msg.context(), msg.From)
 	if err != nil {
 		return err
 	}
 
 	if user == nil {
-		app.welcomeMessage(msgConfig)
-		return nil
+		return app.welcomeMessage(msg, lang)
 	}
 
-	// // get last award of user
-	name, friendlyAddr, weight, err := app.sqlModels.Nfts.GetLastTokenCreated(user.FriendlyAddress)
-	if err != nil {
-		return err
-	}
-
-	// get position of user
-	position, allUsers, err := app.sqlModels.Users.GetUserPosition(user.ID)
-	if err != nil {
-		return err
-	}
-
-	var lastRewardText string
-
-	if name != "" {
-		var url = fmt.Sprintf("https://getgems.io/nft/%s", friendlyAddr)
-
-		lastRewardText = fmt.Sprintf(`<a href="%s">Last reward: %s (+%d)</a>`, url, name, weight)
-	} else {
-		lastRewardText = fmt.Sprintf("Last reward: none")
-	}
-
-	msgConfig.Text = fmt.Sprintf(`
-	🏆 TON Developers Leaderboard 🏆
-
-		▪️ Username: %s
-		▪️ Position: %d out of %d
-		▪️ Rating: %d 💎
-		
-		%s
-		`, user.Username, position, allUsers, int64(user.Rating), lastRewardText)
-
-	// parse mode html
-	msgConfig.ParseMode = "HTML"
-
-	// add button
-	msgConfig.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("🔗 Open profile", "https://tdp.tonbuilders.com/user/"+user.Username),
-		),
-	)
-
-	// remove preview
-	msgConfig.DisableWebPagePreview = true
-	if _, err := app.bot.Send(msgConfig); err != nil {
-		return err
-	}
-
-	return nil
+	return app.profileMessage(msg, lang, "bot.rating", user)
 }
 
 // command sayhi
-func (app *application) whoisHandler(msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+func (app *application) whoisHandler(msg *chatMessage) error {
+	lang := msg.Platform.language(msg)
 
-	incomingUser, err := app.sqlModels.Users.GetByTelegramUserId(updateMsg.From.ID)
+	incomingUser, err := msg.Platform.linkedUser(msg.context(), msg.From)
 	if err != nil {
 		return err
 	}
 
 	if incomingUser == nil {
-		app.welcomeMessage(msgConfig)
-		return nil
+		return app.welcomeMessage(msg, lang)
 	}
 
 	var user *database.User
 
-	if updateMsg.CommandArguments() != "" {
-		user, err = app.sqlModels.Users.GetByTelegramUsername(updateMsg.CommandArguments()[1:])
-		if err != nil {
-			return err
-		}
-
+	switch {
+	case len(msg.Mentions) > 0:
+		user, err = msg.Platform.linkedUser(msg.context(), msg.Mentions[0])
+	case msg.Args != "":
+		user, err = msg.Platform.userByName(msg.context(), strings.TrimPrefix(msg.Args, "@"))
+	}
+	if err != nil {
+		return err
 	}
 
 	// ic ommandArguments is "" and replyToMessage.From.ID is not nil
-	if user == nil && updateMsg.ReplyToMessage != nil && updateMsg.ReplyToMessage.From.ID != 0 {
-		user, err = app.sqlModels.Users.GetByTelegramUserId(updateMsg.ReplyToMessage.From.ID)
+	if user == nil && msg.ReplyTo != nil && msg.ReplyTo.From.ID != 0 {
+		user, err = msg.Platform.linkedUser(msg.context(), msg.ReplyTo.From)
 		if err != nil {
 			return err
 		}
-
 	}
 
 	if user == nil {
+		// the member was given but has no profile, without a member the command is ignored
+		if len(msg.Mentions) > 0 || msg.Args != "" || msg.ReplyTo != nil {
+			return msg.Platform.reply(msg, chatReply{Text: i18n.T(lang, "bot.whois.unknown")})
+		}
+
 		return nil
 	}
 
+	return app.profileMessage(msg, lang, "bot.whois", user)
+}
+
+// profileMessage replies with the position and the last reward of the user
+func (app *application) profileMessage(msg *chatMessage, lang, key string, user *database.User) error {
 	// // get last award of user
-	name, friendlyAddr, weight, err := app.sqlModels.Nfts.GetLastTokenCreated(user.FriendlyAddress)
+	name, friendlyAddr, weight, err := app.sqlModels.Nfts.GetLastTokenCreated(msg.context(), user.FriendlyAddress)
 	if err != nil {
 		return err
 	}
 
 	// get position of user
-	position, allUsers, err := app.sqlModels.Users.GetUserPosition(user.ID)
+	position, allUsers, err := app.sqlModels.Users.GetUserPosition(msg.context(), user.ID)
 	if err != nil {
 		return err
 	}
@@ -186,52 +137,29 @@ func (app *application) whoisHandler(msgConfig tgbotapi.MessageConfig, updateMsg
 	var lastRewardText string
 
 	if name != "" {
-		var url = fmt.Sprintf("https://getgems.io/nft/%s", friendlyAddr)
+		var url = app.tokenURL(friendlyAddr)
 
-		lastRewardText = fmt.Sprintf(`<a href="%s">Last reward: %s (+%d)</a>`, url, name, weight)
+		lastRewardText = i18n.T(lang, "bot.last_reward", url, html.EscapeString(name), weight)
 	} else {
-		lastRewardText = fmt.Sprintf("Last reward: none")
-	}
-
-	msgConfig.Text = fmt.Sprintf(`
-	🏆 TON Developers Platform 🏆
-
-		▪️ Username: %s
-		▪️ Position: %d out of %d
-		▪️ Rating: %d 💎
-		
-		%s
-		`, user.Username, position, allUsers, int64(user.Rating), lastRewardText)
-
-	// parse mode html
-
-	msgConfig.ParseMode = "HTML"
-
-	msgConfig.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("🔗 Open profile", "https://tdp.tonbuilders.com/user/"+user.Username),
-		),
-	)
-
-	msgConfig.DisableWebPagePreview = true
-
-	if _, err := app.bot.Send(msgConfig); err != nil {
-		return err
+		lastRewardText = i18n.T(lang, "bot.last_reward.none")
 	}
 
-	return nil
+	return msg.Platform.reply(msg, chatReply{
+		Text:       i18n.T(lang, key, html.EscapeString(user.Username), position, allUsers, int64(user.Rating), lastRewardText),
+		ButtonText: i18n.T(lang, "bot.open_profile"),
+		ButtonURL:  app.profileURL(user.Username),
+	})
 }
 
 // user's score handler
-func (app *application) scoreHandler(msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+func (app *application) scoreHandler(msg *chatMessage) error {
 
-	//  only if the chat is negative number
-	if updateMsg.Chat.ID > 0 {
+	//  only messages of community chats
+	if msg.Private {
 		return nil
 	}
 
-	// get user by telegram user id
-	user, err := app.sqlModels.Users.GetByTelegramUserId(updateMsg.From.ID)
+	user, err := msg.Platform.linkedUser(msg.context(), msg.From)
 	if err != nil {
 		return err
 	}
@@ -240,73 +168,49 @@ func (app *application) scoreHandler(msgConfig tgbotapi.MessageConfig, updateMsg
 		return nil
 	}
 
-	telegramMessage := &database.TelegramMessage{
-		UserID:    updateMsg.From.ID,
-		MessageID: updateMsg.MessageID,
-		ChatID:    updateMsg.Chat.ID,
-	}
-
-	payload, err := json.Marshal(telegramMessage)
-
-	if err != nil {
-		app.logger.Error(err, nil)
-		return err
-	}
-
-	runGetTelegramMessageQueue := asynq.NewTask(database.TYPE_ADD_TG_MESSAGE, payload)
-
-	info, err := app.asynqClient.Enqueue(runGetTelegramMessageQueue, asynq.ProcessIn(20*time.Second), asynq.MaxRetry(5), asynq.ProcessIn(5*time.Second), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_NORMAL))
+	// moderators stopped scoring messages of the user
+	muted, err := app.sqlModels.Moderation.IsMuted(msg.context(), user.ID)
 	if err != nil {
-		app.logger.Error(fmt.Errorf("error: %s", err), nil)
 		return err
 	}
 
-	prototypesNFT, err := app.sqlModels.Nfts.GetPrototypesByRating(user.ID)
-	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error running get user rating handler: %v", err))
-		return err
+	if muted {
+		return nil
 	}
 
-	if len(prototypesNFT) > 0 {
-		msg := fmt.Sprintf("🎉 Congratulations %s! You have earned new SBT token! 🎉\n\n", user.Username)
-
-		for _, prototype := range prototypesNFT {
-			nft, err := app.sqlModels.Nfts.GetSbtTokenByContentUri("https://tdp.tonbuilders.com/v1/deployed-nft/n/" + prototype.Base64 + "/meta.json")
-			if err != nil {
-				return err
-			}
-
-			rewardText := fmt.Sprintf(`<a href="%s">%s</a>`, nft.ContentUri, *nft.Name)
+	policy := app.scoringPolicy(msg.ChatID)
 
-			msg += fmt.Sprintf(`
-			
-			🏆 Hey Roman! Here is your reward:
+	if reason := policy.check(msg); reason != "" {
+		metrics.BotScoring.WithLabelValues(reason).Inc()
 
-			▪️ SBT: %s
-			▪️ Description: %s
-			▪️ Rating: +%d points
+		if isReaction(msg, reason) {
+			return msg.Platform.react(msg, policy)
+		}
 
-			Keep up the great work! Enjoy!
+		return nil
+	}
 
-			`,
-			rewardText, prototype.Description, nft.Weight)
-		}
+	decision, err := app.allowScore(msg.Platform.name(), msg.ChatID, msg.From.ID, policy)
+	if err != nil {
+		// the message is not scored rather than scored without limits
+		app.logger.Warningw("error checking scoring limits", "error", err)
+		return nil
+	}
 
-		// inline keyboard
-		msgConfig.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonURL("🔗 See In My Profile", "https://tdp.tonbuilders.com/user/"+user.Username),
-			),
-		)
+	metrics.BotScoring.WithLabelValues(decision).Inc()
 
-		msgConfig.Text = msg
+	if decision != scoreScored {
+		return nil
+	}
 
-		if _, err := app.bot.Send(msgConfig); err != nil {
-			return err
-		}
+	weight, scored, err := msg.Platform.chatWeight(msg)
+	if err != nil {
+		return err
 	}
 
-	app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))
+	if !scored {
+		return nil
+	}
 
-	return nil
+	return msg.Platform.score(msg, weight)
 }
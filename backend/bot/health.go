@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/ton-developer-program/internal/health"
+)
+
+// telegramCheck calls getMe, the bot can't receive updates when Bot API is not reachable
+func (app *application) telegramCheck() health.Check {
+	return health.Check{
+		Name:     "telegram",
+		Critical: true,
+		Run: func(ctx context.Context) (map[string]any, error) {
+			type result struct {
+				user tgbotapi.User
+				err  error
+			}
+
+			// getMe does not accept context
+			done := make(chan result, 1)
+
+			go func() {
+				user, err := app.bot.GetMe()
+				done <- result{user, err}
+			}()
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case res := <-done:
+				if res.err != nil {
+					return nil, res.err
+				}
+
+				return map[string]any{"username": res.user.UserName}, nil
+			}
+		},
+	}
+}
@@ -1,34 +1,75 @@
 package main
 
 import (
-	"log"
+	"context"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/ton-developer-program/internal/i18n"
 )
 
-func (app *application) welcomeMessage(msg tgbotapi.MessageConfig) {
-	msg.Text = `
-	🏆 TON Developers Platform 🏆
+// rewardsURL explains what the rating gives, linked in the welcome message
+const rewardsURL = "https://ton-org.notion.site/How-to-get-rewarded-ad8ab607478d4a7ab8658051d4ce5bf7"
 
-	Hey! You don't have an account yet. 🙂
+// welcomeMessage invites members without a profile to join the platform
+func (app *application) welcomeMessage(msg *chatMessage, lang string) error {
+	return msg.Platform.reply(msg, chatReply{
+		Text:       i18n.T(lang, "bot.welcome", rewardsURL),
+		ButtonText: i18n.T(lang, "bot.join"),
+		ButtonURL:  app.config.App.BaseUrl + "/",
+	})
+}
 
-	Join now, become #1 in the TON Community and get <a href="https://ton-org.notion.site/How-to-get-rewarded-ad8ab607478d4a7ab8658051d4ce5bf7">unique rewards</a> in TON merch store with your rating!
-	`
+// language of the reply, group chats use the language of the chat, private chats the language
+// chosen in the profile or the Telegram language of the user
+func (app *application) language(updateMsg *tgbotapi.Message) string {
+	if updateMsg.Chat.ID < 0 {
+		chat, err := app.communityChat(updateMsg.Chat.ID)
+		if err != nil {
+			app.logger.Warningw("error loading chat", "chat_id", updateMsg.Chat.ID, "error", err)
+		}
 
-	msg.ParseMode = "HTML"
+		if chat != nil {
+			return i18n.Match(chat.Language)
+		}
 
-	// Add button to the message
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("💎 Join Developers Platform", "https://tdp.tonbuilders.com/"),
-		),
-	)
+		return i18n.English
+	}
 
+	if updateMsg.From == nil {
+		return i18n.English
+	}
 
-	msg.DisableWebPagePreview = true
+	return app.telegramUserLanguage(telegramContext(updateMsg), updateMsg.From)
+}
 
-	if _, err := app.bot.Send(msg); err != nil {
-		log.Panic(err)
+// telegramUserLanguage is the language chosen in the profile linked to the Telegram account
+// or the Telegram language of the user
+func (app *application) telegramUserLanguage(ctx context.Context, from *tgbotapi.User) string {
+	language, err := app.sqlModels.Users.GetLanguageByTelegramUserId(ctx, from.ID)
+	if err != nil {
+		app.logger.Warningw("error getting language", "telegram_user_id", from.ID, "error", err)
 	}
 
-}
\ No newline at end of file
+	return i18n.Match(language, from.LanguageCode)
+}
+
+// userLanguage is the language chosen in the profile, direct messages sent from tasks have
+// no Telegram language to fall back to
+func (app *application) userLanguage(ctx context.Context, userID int64) string {
+	language, err := app.sqlModels.Users.GetLanguage(ctx, userID)
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error getting language", "user_id", userID, "error", err)
+	}
+
+	return i18n.Match(language)
+}
+
+// profileURL returns link to the profile of the user on the platform
+func (app *application) profileURL(username string) string {
+	return app.config.App.BaseUrl + "/user/" + username
+}
+
+// tokenURL returns link to the SBT on the marketplace
+func (app *application) tokenURL(address string) string {
+	return app.config.App.TokenUrl + address
+}
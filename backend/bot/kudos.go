@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"html"
+
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/i18n"
+	"github.com/ton-developer-program/internal/kudos"
+)
+
+// thanksHandler gives a kudos to the member the command replies to, Discord passes the member
+// as the option of /thanks
+func (app *application) thanksHandler(msg *chatMessage) error {
+	lang := msg.Platform.language(msg)
+
+	var member *chatUser
+
+	switch {
+	case len(msg.Mentions) > 0:
+		member = &msg.Mentions[0]
+	case msg.ReplyTo != nil && msg.ReplyTo.From.ID != 0:
+		member = &msg.ReplyTo.From
+	}
+
+	// kudos are given only in community chats
+	if msg.Private || member == nil || member.Bot {
+		return msg.Platform.reply(msg, chatReply{Text: i18n.T(lang, "bot.thanks.usage")})
+	}
+
+	giver, err := msg.Platform.linkedUser(msg.context(), msg.From)
+	if err != nil {
+		return err
+	}
+
+	if giver == nil {
+		return app.welcomeMessage(msg, lang)
+	}
+
+	receiver, err := msg.Platform.linkedUser(msg.context(), *member)
+	if err != nil {
+		return err
+	}
+
+	if receiver == nil {
+		return msg.Platform.reply(msg, chatReply{Text: i18n.T(lang, "bot.whois.unknown")})
+	}
+
+	given := &database.Kudos{
+		GiverID:    giver.ID,
+		ReceiverID: receiver.ID,
+		Source:     msg.Platform.name(),
+		ChatID:     &msg.ChatID,
+	}
+
+	if msg.ReplyTo != nil {
+		given.MessageID = &msg.ReplyTo.MessageID
+	}
+
+	receiverName := html.EscapeString(receiver.Username)
+
+	err = app.sqlModels.Kudos.Give(msg.context(), given, kudos.Policy(app.config.Kudos))
+	switch {
+	case errors.Is(err, database.ErrKudosSelf):
+		return msg.Platform.reply(msg, chatReply{Text: i18n.T(lang, "bot.thanks.self")})
+	case errors.Is(err, database.ErrKudosAlreadyGiven):
+		return msg.Platform.reply(msg, chatReply{Text: i18n.T(lang, "bot.thanks.already", receiverName)})
+	case errors.Is(err, database.ErrKudosDailyLimit):
+		return msg.Platform.reply(msg, chatReply{Text: i18n.T(lang, "bot.thanks.limit", app.config.Kudos.DailyLimit)})
+	case err != nil:
+		return err
+	}
+
+	giverName := html.EscapeString(giver.Username)
+
+	// reciprocal kudos change neither rating nor kudos count of the receiver
+	text := i18n.T(lang, "bot.thanks.reciprocal", giverName, receiverName)
+
+	if !given.Reciprocal {
+		text = i18n.T(lang, "bot.thanks", giverName, receiverName, given.Weight)
+
+		info, err := kudos.CheckRatingRewards(msg.context(), app.asynqClient, given)
+		if err != nil {
+			return err
+		}
+
+		app.logger.Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+	}
+
+	return msg.Platform.reply(msg, chatReply{
+		Text:       text,
+		ButtonText: i18n.T(lang, "bot.open_profile"),
+		ButtonURL:  app.profileURL(receiver.Username),
+	})
+}
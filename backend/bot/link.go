@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/i18n"
+	"github.com/ton-developer-program/internal/tasks"
+)
+
+// linkHandler links the Telegram account of the sender to the user of the token from
+// the t.me/<bot>?start=<token> deep link created by POST /v1/telegram/link
+func (app *application) linkHandler(msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message, token string) error {
+	ctx := telegramContext(updateMsg)
+
+	lang := app.language(updateMsg)
+
+	user, err := app.sqlModels.Users.GetForToken(ctx, database.ScopeTelegramLink, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.link.invalid"))
+		}
+		return err
+	}
+
+	linked, err := app.sqlModels.Users.GetByTelegramUserId(ctx, updateMsg.From.ID)
+	if err != nil {
+		return err
+	}
+
+	if linked != nil {
+		if linked.ID != user.ID {
+			return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.link.taken"))
+		}
+
+		err = app.sqlModels.Tokens.DeleteAllForUser(ctx, database.ScopeTelegramLink, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.link.already", html.EscapeString(user.Username)))
+	}
+
+	accounts, err := app.sqlModels.Users.GetLinkedAccounts(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	// another Telegram account is linked to the user
+	for _, account := range accounts {
+		if account.Provider == database.ProviderTelegram {
+			return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.link.exists", html.EscapeString(user.Username)))
+		}
+	}
+
+	telegramUserID := int64(updateMsg.From.ID)
+	now := uint64(time.Now().Unix())
+
+	err = app.sqlModels.Users.InsertLinkedAccount(ctx, &database.LinkedAccount{
+		UserID:         user.ID,
+		TelegramUserID: &telegramUserID,
+		Provider:       database.ProviderTelegram,
+		Login:          updateMsg.From.UserName,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Version:        1,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = app.sqlModels.Tokens.DeleteAllForUser(ctx, database.ScopeTelegramLink, user.ID)
+	if err != nil {
+		return err
+	}
+
+	app.logger.Infow("linked telegram account", "user_id", user.ID, "telegram_user_id", telegramUserID)
+
+	err = app.linkedAccountReward(ctx, user)
+	if err != nil {
+		// the account is linked, the reward check is logged and can be repeated by linking another account
+		app.logger.Warningw("error checking linked account reward", "user_id", user.ID, "error", err)
+	}
+
+	msgConfig.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL(i18n.T(lang, "bot.open_profile"), app.profileURL(user.Username)),
+		),
+	)
+
+	return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.link.success", html.EscapeString(user.Username)))
+}
+
+// linkedAccountReward enqueues the reward for linked accounts like checkTelegramAuthorization
+// of the API when the user has two linked accounts and no auth SBT yet
+func (app *application) linkedAccountReward(ctx context.Context, user *database.User) error {
+	hasTwoAccounts, err := app.sqlModels.Users.HasTwoLinkedAccounts(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if !hasTwoAccounts {
+		return nil
+	}
+
+	authNft, err := app.sqlModels.Nfts.GetNFTMetadataByID(ctx, app.config.App.AuthMetadataID)
+	if err != nil {
+		return err
+	}
+
+	hasAuthNft, err := app.sqlModels.Nfts.HasAuthNFT(ctx, user.FriendlyAddress, authNft.Base64)
+	if err != nil {
+		return err
+	}
+
+	if hasAuthNft {
+		return nil
+	}
+
+	payload, err := json.Marshal(user.ID)
+	if err != nil {
+		return err
+	}
+
+	task := tasks.NewTask(ctx, database.TYPE_REWARD_FOR_LINKED_ACCOUNT, payload)
+
+	info, err := app.asynqClient.Enqueue(task, asynq.TaskID(fmt.Sprint("reward_auth", user.ID)), asynq.ProcessIn(5*time.Second), asynq.MaxRetry(5), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_URGENT))
+	switch {
+	case errors.Is(err, asynq.ErrTaskIDConflict):
+		// the reward is already scheduled
+	case err != nil:
+		return err
+	default:
+		app.logger.Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+	}
+
+	return nil
+}
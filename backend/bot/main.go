@@ -1,17 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"runtime/debug"
 	"sync"
 
+	"github.com/go-redis/redis/v8"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/hibiken/asynq"
 	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/health"
 	"github.com/ton-developer-program/internal/leveledlog"
+	"github.com/ton-developer-program/internal/metrics"
 	"github.com/ton-developer-program/internal/version"
 	"github.com/ton-developer-program/util"
 )
@@ -33,6 +38,10 @@ type application struct {
 	wg     sync.WaitGroup
 	asynqClient *asynq.Client
 	bot *tgbotapi.BotAPI
+	redisClient *redis.Client
+	defaultScoringPolicy scoringPolicy
+	scoringPolicies map[int64]scoringPolicy
+	chats chatRegistry
 }
 
 func run(logger *leveledlog.Logger) error {
@@ -41,6 +50,10 @@ func run(logger *leveledlog.Logger) error {
 	if err != nil {
 		return err
 	}
+
+	if config.Log.Format == "json" {
+		logger = leveledlog.NewJSONLogger(os.Stdout, leveledlog.LevelAll)
+	}
 //dd
 	showVersion := flag.Bool("version", false, "display version and exit")
 
@@ -79,6 +92,53 @@ func run(logger *leveledlog.Logger) error {
 		bot:    bot,
 	}
 
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     config.Redis.Addr,
+		Password: config.Redis.Password,
+	})
+	defer redisClient.Close()
+
+	app.redisClient = redisClient
+
+	app.defaultScoringPolicy, app.scoringPolicies, err = parseScoringPolicies(config.Scoring.Policies)
+	if err != nil {
+		return err
+	}
+
+	// the chat from APP_ALLOWED_GROUP_CHAT_ID is registered on the first start
+	if config.App.AlloweGroupChatID != 0 {
+		err = app.registerAllowedChat(context.Background(), config.App.AlloweGroupChatID)
+		if err != nil {
+			return err
+		}
+	}
+
+	checker := health.NewChecker("bot", version.Get(),
+		health.Postgres(db.DB),
+		health.Redis(redisClient),
+		app.telegramCheck(),
+	)
+
+	metrics.BuildInfo.WithLabelValues("bot", version.Get()).Set(1)
+
+	go func() {
+		mux := http.NewServeMux()
+		checker.Register(mux)
+
+		err := metrics.Serve(config.Metrics.Addr, mux)
+		if err != nil {
+			app.logger.Errorw(err, "server", "metrics")
+		}
+	}()
+
+	srv := app.newTaskServer()
+
+	err = srv.Start(app.taskRoutes())
+	if err != nil {
+		return err
+	}
+	defer srv.Shutdown()
+
 	return app.startBot()
 
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/leveledlog"
+)
+
+// platform is a chat platform the bot is connected to. Community handlers (rating, whois, thanks
+// and message scoring) work with chatMessage and reply through the platform of the message.
+type platform interface {
+	// name is the provider of linked accounts
+	name() string
+	// linkedUser returns the user the member linked the account to, nil when the member has no profile
+	linkedUser(ctx context.Context, member chatUser) (*database.User, error)
+	// userByName returns the user of the member with the username, nil when not found
+	userByName(ctx context.Context, username string) (*database.User, error)
+	// language of replies to the message
+	language(msg *chatMessage) string
+	// reply sends the reply to the chat of the message
+	reply(msg *chatMessage, r chatReply) error
+	// chatWeight returns the weight of messages scored in the chat, false when messages
+	// of the chat are not scored
+	chatWeight(msg *chatMessage) (int, bool, error)
+	// score records the message that passed the scoring policy
+	score(msg *chatMessage, weight int) error
+	// react adds the reaction bonus to the message the member replied to
+	react(msg *chatMessage, policy scoringPolicy) error
+}
+
+type chatUser struct {
+	ID       int64
+	Username string
+	Bot      bool
+}
+
+// chatMessage is a message or command of a member
+type chatMessage struct {
+	Platform  platform
+	ChatID    int64
+	MessageID int64
+	// sent to the bot, not to a community chat
+	Private bool
+	From    chatUser
+	// text or caption of the message
+	Text string
+	// command without the slash and the text after it
+	Command string
+	Args    string
+	Sticker bool
+	Forward bool
+	// members passed to the command, Discord passes the member of /whois and /thanks this way
+	Mentions []chatUser
+	ReplyTo  *chatMessage
+	// update of the platform the message was made from
+	origin any
+	// request id of tasks enqueued for the message
+	requestID string
+}
+
+// context carries the request id of the message to enqueued tasks
+func (m *chatMessage) context() context.Context {
+	return leveledlog.ContextWithRequestID(context.Background(), m.requestID)
+}
+
+// chatReply is formatted with Telegram HTML, platforms without HTML convert it
+type chatReply struct {
+	Text string
+	// link button under the reply
+	ButtonText string
+	ButtonURL  string
+}
@@ -4,42 +4,41 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
+type commandHandler func(msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error
+
+// commands anyone can use, messages with other commands are scored like plain messages
+func (app *application) commands() map[string]commandHandler {
+	return map[string]commandHandler{
+		"start": app.startHandler,
+		"rating": func(_ tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+			return app.ratingHandler(app.telegramMessage(updateMsg))
+		},
+		"whois": func(_ tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+			return app.whoisHandler(app.telegramMessage(updateMsg))
+		},
+		"thanks": func(_ tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+			return app.thanksHandler(app.telegramMessage(updateMsg))
+		},
+		"top":  app.topHandler,
+		"help": app.helpHandler,
+	}
+}
+
 func (app *application) routes(msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
 
 	command := updateMsg.Command()
-	
-	switch command {
-	case "start":
-		err := app.startHandler(msgConfig, updateMsg)
-		if err != nil {
-			return err
-		}
-
-	case "rating":
-		err := app.ratingHandler(msgConfig, updateMsg)
-		if err != nil {
-			return err
-		}
-
-	// Process the command and parameter as needed
-	case "whois":
-		err := app.whoisHandler(msgConfig, updateMsg)
-		if err != nil {
-			return err
-		}
-
-	case "help":
-		err := app.helpHandler(msgConfig, updateMsg)
-		if err != nil {
-			return err
-		}
-
-	default:
-		err := app.scoreHandler(msgConfig, updateMsg)
-		if err != nil {
-			return err
-		}
+
+	if updateMsg.NewChatMembers != nil {
+		return app.welcomeHandler(msgConfig, updateMsg)
+	}
+
+	if adminCommand, ok := app.adminCommands()[command]; ok {
+		return app.adminHandler(adminCommand, msgConfig, updateMsg)
+	}
+
+	if handler, ok := app.commands()[command]; ok {
+		return handler(msgConfig, updateMsg)
 	}
 
-	return nil
+	return app.scoreHandler(app.telegramMessage(updateMsg))
 }
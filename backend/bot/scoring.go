@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ton-developer-program/internal/database"
+)
+
+// scoringPolicy decides which group messages are scored
+type scoringPolicy struct {
+	// messages with shorter text or caption are not scored, in runes
+	MinLength int
+	// time between scored messages of the user in the chat
+	Cooldown time.Duration
+	// scored messages of the user in the chat per day (UTC)
+	DailyCap int
+	// weight added to a scored message for every member reacting to it, up to MaxBonus
+	ReactionBonus int
+	MaxBonus      int
+}
+
+// defaultScoringPolicy is used for chats without override in SCORING_POLICIES
+var defaultScoringPolicy = scoringPolicy{
+	MinLength:     20,
+	Cooldown:      time.Minute,
+	DailyCap:      50,
+	ReactionBonus: 1,
+	MaxBonus:      5,
+}
+
+// reasons why a message is not scored, used as metrics label
+const (
+	scoreScored   = "scored"
+	scoreCommand  = "command"
+	scoreSticker  = "sticker"
+	scoreForward  = "forward"
+	scoreTooShort = "too_short"
+	scoreCooldown = "cooldown"
+	scoreDailyCap = "daily_cap"
+	scoreReaction = "reaction"
+)
+
+// parseScoringPolicies applies overrides in format
+// chat=min_length:cooldown_sec:daily_cap:reaction_bonus:max_bonus, chat is chat id or "default"
+func parseScoringPolicies(overrides string) (scoringPolicy, map[int64]scoringPolicy, error) {
+	defaultPolicy := defaultScoringPolicy
+	policies := map[int64]scoringPolicy{}
+
+	for _, item := range strings.Split(overrides, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		chat, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return defaultPolicy, nil, fmt.Errorf("invalid scoring policy %q", item)
+		}
+
+		fields := strings.Split(value, ":")
+		if len(fields) != 5 {
+			return defaultPolicy, nil, fmt.Errorf("invalid scoring policy %q", item)
+		}
+
+		numbers := make([]int, len(fields))
+
+		for i, field := range fields {
+			number, err := strconv.Atoi(field)
+			if err != nil || number < 0 {
+				return defaultPolicy, nil, fmt.Errorf("invalid number %q in scoring policy %q", field, item)
+			}
+
+			numbers[i] = number
+		}
+
+		policy := scoringPolicy{
+			MinLength:     numbers[0],
+			Cooldown:      time.Duration(numbers[1]) * time.Second,
+			DailyCap:      numbers[2],
+			ReactionBonus: numbers[3],
+			MaxBonus:      numbers[4],
+		}
+
+		if chat == "default" {
+			defaultPolicy = policy
+			continue
+		}
+
+		chatID, err := strconv.ParseInt(chat, 10, 64)
+		if err != nil {
+			return defaultPolicy, nil, fmt.Errorf("invalid chat id in scoring policy %q", item)
+		}
+
+		policies[chatID] = policy
+	}
+
+	return defaultPolicy, policies, nil
+}
+
+func (app *application) scoringPolicy(chatID int64) scoringPolicy {
+	if policy, ok := app.scoringPolicies[chatID]; ok {
+		return policy
+	}
+
+	return app.defaultScoringPolicy
+}
+
+// check returns why the message is not scored, empty reason means the message has enough content
+func (p scoringPolicy) check(msg *chatMessage) string {
+	switch {
+	case msg.Command != "":
+		return scoreCommand
+	case msg.Sticker:
+		return scoreSticker
+	case msg.Forward:
+		return scoreForward
+	}
+
+	if utf8.RuneCountInString(strings.TrimSpace(msg.Text)) < p.MinLength {
+		return scoreTooShort
+	}
+
+	return ""
+}
+
+// isReaction reports whether the message only reacts to a message of another member, like "+1" or a sticker
+func isReaction(msg *chatMessage, reason string) bool {
+	if reason != scoreTooShort && reason != scoreSticker {
+		return false
+	}
+
+	reply := msg.ReplyTo
+
+	return reply != nil && reply.From.ID != 0 && !reply.From.Bot && reply.From.ID != msg.From.ID
+}
+
+// scoreLimitScript checks cooldown and daily cap of the user in the chat and counts the message
+// only when both pass
+var scoreLimitScript = redis.NewScript(`
+local cooldown = tonumber(ARGV[1])
+local cap = tonumber(ARGV[2])
+
+if cooldown > 0 and redis.call("EXISTS", KEYS[1]) == 1 then
+	return "cooldown"
+end
+
+local count = tonumber(redis.call("GET", KEYS[2]) or "0")
+if count >= cap then
+	return "daily_cap"
+end
+
+redis.call("INCR", KEYS[2])
+redis.call("EXPIRE", KEYS[2], 90000)
+
+if cooldown > 0 then
+	redis.call("SET", KEYS[1], 1, "EX", cooldown)
+end
+
+return "scored"
+`)
+
+// allowScore takes a slot for the message, returns scoreScored or the reason the message is not scored.
+// Keys of platforms other than Telegram are prefixed with the platform name.
+func (app *application) allowScore(platformName string, chatID, userID int64, policy scoringPolicy) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	day := time.Now().UTC().Format("20060102")
+
+	prefix := "scoring"
+	if platformName != database.ProviderTelegram {
+		prefix += ":" + platformName
+	}
+
+	keys := []string{
+		fmt.Sprintf("%s:cooldown:%d:%d", prefix, chatID, userID),
+		fmt.Sprintf("%s:daily:%d:%d:%s", prefix, chatID, userID, day),
+	}
+
+	return scoreLimitScript.Run(ctx, app.redisClient, keys, int(policy.Cooldown.Seconds()), policy.DailyCap).Text()
+}
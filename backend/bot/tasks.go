@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/i18n"
+	"github.com/ton-developer-program/internal/leveledlog"
+	"github.com/ton-developer-program/internal/metrics"
+	"github.com/ton-developer-program/internal/tasks"
+)
+
+// newTaskServer creates asynq server for tasks the worker and the api send to the bot
+func (app *application) newTaskServer() *asynq.Server {
+	return asynq.NewServer(
+		asynq.RedisClientOpt{
+			Addr:     app.config.Redis.Addr,
+			Password: app.config.Redis.Password,
+		},
+		asynq.Config{
+			// telegram allows about 30 messages per second
+			Concurrency: 4,
+			Queues: map[string]int{
+				database.QUEUE_BOT: 1,
+			},
+			RetryDelayFunc: func(n int, e error, t *asynq.Task) time.Duration {
+				return time.Duration(n+1) * 10 * time.Second
+			},
+		},
+	)
+}
+
+func (app *application) taskRoutes() *asynq.ServeMux {
+	mux := asynq.NewServeMux()
+
+	mux.Use(app.taskContext)
+	mux.Use(app.instrumentTask)
+
+	mux.HandleFunc(database.TYPE_TG_REWARD_MINTED, app.rewardMintedTask)
+	mux.HandleFunc(database.TYPE_TG_ACHIEVEMENT_PENDING, app.achievementPendingTask)
+	mux.HandleFunc(database.TYPE_TG_REPLAY_UPDATE, app.replayUpdateTask)
+	mux.HandleFunc(database.TYPE_TG_CHAT_DIGEST, app.chatDigestTask)
+
+	return mux
+}
+
+// taskContext adds request id from the task payload and task id to the logger context
+func (app *application) taskContext(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		if requestID := tasks.RequestID(t); requestID != "" {
+			ctx = leveledlog.ContextWithRequestID(ctx, requestID)
+		}
+
+		taskID, _ := asynq.GetTaskID(ctx)
+		ctx = leveledlog.ContextWithFields(ctx, "task_type", t.Type(), "task_id", taskID)
+
+		return next.ProcessTask(ctx, t)
+	})
+}
+
+// instrumentTask records outcome and duration of every task
+func (app *application) instrumentTask(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		start := time.Now()
+
+		err := next.ProcessTask(ctx, t)
+
+		outcome := "success"
+		if err != nil {
+			outcome = "failure"
+		}
+
+		metrics.Tasks.WithLabelValues(t.Type(), outcome).Inc()
+		metrics.TaskDuration.WithLabelValues(t.Type()).Observe(time.Since(start).Seconds())
+
+		return err
+	})
+}
+
+// rewardMintedTask sends a direct message to the Telegram account linked to the user
+// once the SBT is minted and added to the profile
+func (app *application) rewardMintedTask(ctx context.Context, t *asynq.Task) error {
+	var payloadData struct {
+		UserID     int64  `json:"user_id"`
+		NftAddress string `json:"nft_address"`
+	}
+
+	if err := tasks.Decode(t, &payloadData); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	user, err := app.sqlModels.Users.GetById(ctx, payloadData.UserID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return nil
+	}
+
+	chatID, err := app.telegramChatID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if chatID == 0 {
+		app.logger.Ctx(ctx).Infow("user has no linked telegram account", "user_id", user.ID)
+		return nil
+	}
+
+	nft, err := app.sqlModels.Nfts.GetTokenByAddress(ctx, payloadData.NftAddress)
+	if err != nil {
+		return err
+	}
+
+	if nft == nil {
+		return fmt.Errorf("token %s not found: %w", payloadData.NftAddress, asynq.SkipRetry)
+	}
+
+	lang := app.userLanguage(ctx, user.ID)
+
+	name := "SBT"
+	if nft.Name != nil && *nft.Name != "" {
+		name = *nft.Name
+	}
+
+	description := ""
+	if nft.Description != nil && *nft.Description != "" {
+		description = i18n.T(lang, "bot.reward.description", html.EscapeString(*nft.Description))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "bot.reward.minted",
+		html.EscapeString(user.Username), app.tokenURL(nft.FriendlyAddress), html.EscapeString(name), description, nft.Weight))
+
+	msg.ParseMode = "HTML"
+	msg.DisableWebPagePreview = true
+
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL(i18n.T(lang, "bot.reward.profile"), app.profileURL(user.Username)),
+		),
+	)
+
+	if _, err := app.bot.Send(msg); err != nil {
+		return telegramSendError(err)
+	}
+
+	app.logger.Ctx(ctx).Infow("sent reward message", "user_id", user.ID)
+
+	return nil
+}
+
+// achievementPendingTask sends the achievement waiting for approval to the Telegram account
+// linked to the user with buttons to accept or decline it
+func (app *application) achievementPendingTask(ctx context.Context, t *asynq.Task) error {
+	var payloadData struct {
+		UserID        int64 `json:"user_id"`
+		AchievementID int64 `json:"achievement_id"`
+	}
+
+	if err := tasks.Decode(t, &payloadData); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	chatID, err := app.telegramChatID(ctx, payloadData.UserID)
+	if err != nil {
+		return err
+	}
+
+	if chatID == 0 {
+		app.logger.Ctx(ctx).Infow("user has no linked telegram account", "user_id", payloadData.UserID)
+		return nil
+	}
+
+	achievement, err := app.sqlModels.Rewards.GetStoredRewardByID(payloadData.AchievementID)
+	if err != nil {
+		return err
+	}
+
+	// already accepted or declined in the web app
+	if achievement.Processed || achievement.ApprovedByUser {
+		return nil
+	}
+
+	metadata, err := app.sqlModels.Nfts.GetNFTMetadataByBase64(ctx, achievement.Base64Metadata)
+	if err != nil {
+		return err
+	}
+
+	lang := app.userLanguage(ctx, payloadData.UserID)
+
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "bot.achievement.pending",
+		html.EscapeString(metadata.Name), html.EscapeString(metadata.Description)))
+
+	msg.ParseMode = "HTML"
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = achievementKeyboard(lang, achievement.ID)
+
+	if _, err := app.bot.Send(msg); err != nil {
+		return telegramSendError(err)
+	}
+
+	app.logger.Ctx(ctx).Infow("sent achievement", "achievement_id", achievement.ID, "user_id", payloadData.UserID)
+
+	return nil
+}
+
+// telegramChatID returns id of the private chat with the Telegram account linked to the user, 0 if there is none
+func (app *application) telegramChatID(ctx context.Context, userID int64) (int64, error) {
+	accounts, err := app.sqlModels.Users.GetLinkedAccounts(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, account := range accounts {
+		if account.Provider == database.ProviderTelegram && account.TelegramUserID != nil {
+			return *account.TelegramUserID, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// telegramSendError doesn't retry messages to users who never started the bot or blocked it
+func telegramSendError(err error) error {
+	var tgErr tgbotapi.Error
+
+	if errors.As(err, &tgErr) && tgErr.RetryAfter > 0 {
+		return err
+	}
+
+	if strings.HasPrefix(err.Error(), "Forbidden") || strings.Contains(err.Error(), "chat not found") {
+		return fmt.Errorf("%v: %w", err, asynq.SkipRetry)
+	}
+
+	return err
+}
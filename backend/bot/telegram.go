@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/leveledlog"
+	"github.com/ton-developer-program/internal/metrics"
+	"github.com/ton-developer-program/internal/tasks"
+)
+
+// telegramPlatform runs community handlers for Telegram updates
+type telegramPlatform struct {
+	app *application
+}
+
+// telegramMessage converts the Telegram message for community handlers
+func (app *application) telegramMessage(updateMsg *tgbotapi.Message) *chatMessage {
+	p := telegramPlatform{app: app}
+
+	msg := &chatMessage{
+		Platform:  p,
+		ChatID:    updateMsg.Chat.ID,
+		MessageID: int64(updateMsg.MessageID),
+		Private:   updateMsg.Chat.ID > 0,
+		From:      telegramUser(updateMsg.From),
+		Text:      updateMsg.Text,
+		Command:   updateMsg.Command(),
+		Args:      updateMsg.CommandArguments(),
+		Sticker:   updateMsg.Sticker != nil,
+		Forward:   updateMsg.ForwardFrom != nil || updateMsg.ForwardFromChat != nil,
+		origin:    updateMsg,
+		requestID: telegramRequestID(updateMsg),
+	}
+
+	if msg.Text == "" {
+		msg.Text = updateMsg.Caption
+	}
+
+	if reply := updateMsg.ReplyToMessage; reply != nil {
+		msg.ReplyTo = &chatMessage{
+			Platform:  p,
+			ChatID:    reply.Chat.ID,
+			MessageID: int64(reply.MessageID),
+			From:      telegramUser(reply.From),
+			origin:    reply,
+		}
+	}
+
+	return msg
+}
+
+// telegramRequestID identifies the update in the audit log and in tasks enqueued for it
+func telegramRequestID(updateMsg *tgbotapi.Message) string {
+	return fmt.Sprintf("tg-%d-%d", updateMsg.Chat.ID, updateMsg.MessageID)
+}
+
+// telegramContext carries the request id of the update to enqueued tasks
+func telegramContext(updateMsg *tgbotapi.Message) context.Context {
+	return leveledlog.ContextWithRequestID(context.Background(), telegramRequestID(updateMsg))
+}
+
+func telegramUser(user *tgbotapi.User) chatUser {
+	if user == nil {
+		return chatUser{}
+	}
+
+	return chatUser{ID: int64(user.ID), Username: user.UserName, Bot: user.IsBot}
+}
+
+func (p telegramPlatform) name() string {
+	return database.ProviderTelegram
+}
+
+func (p telegramPlatform) linkedUser(ctx context.Context, member chatUser) (*database.User, error) {
+	return p.app.sqlModels.Users.GetByTelegramUserId(ctx, int(member.ID))
+}
+
+func (p telegramPlatform) userByName(ctx context.Context, username string) (*database.User, error) {
+	return p.app.sqlModels.Users.GetByTelegramUsername(ctx, username)
+}
+
+func (p telegramPlatform) language(msg *chatMessage) string {
+	return p.app.language(msg.origin.(*tgbotapi.Message))
+}
+
+func (p telegramPlatform) reply(msg *chatMessage, r chatReply) error {
+	msgConfig := tgbotapi.NewMessage(msg.ChatID, r.Text)
+
+	msgConfig.ParseMode = "HTML"
+	msgConfig.DisableWebPagePreview = true
+
+	if r.ButtonURL != "" {
+		msgConfig.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonURL(r.ButtonText, r.ButtonURL),
+			),
+		)
+	}
+
+	_, err := p.app.send(msgConfig)
+
+	return err
+}
+
+func (p telegramPlatform) chatWeight(msg *chatMessage) (int, bool, error) {
+	chat, err := p.app.communityChat(msg.ChatID)
+	if err != nil || chat == nil {
+		return 0, false, err
+	}
+
+	return chat.Weight, true, nil
+}
+
+func (p telegramPlatform) score(msg *chatMessage, weight int) error {
+	telegramMessage := &database.TelegramMessage{
+		UserID:    int(msg.From.ID),
+		MessageID: int(msg.MessageID),
+		ChatID:    msg.ChatID,
+		Weight:    weight,
+	}
+
+	payload, err := json.Marshal(telegramMessage)
+
+	if err != nil {
+		return err
+	}
+
+	runGetTelegramMessageQueue := tasks.NewTask(msg.context(), database.TYPE_ADD_TG_MESSAGE, payload)
+
+	info, err := p.app.asynqClient.Enqueue(runGetTelegramMessageQueue, asynq.ProcessIn(20*time.Second), asynq.MaxRetry(5), asynq.ProcessIn(5*time.Second), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_NORMAL))
+	if err != nil {
+		return err
+	}
+
+	p.app.logger.Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+
+	return nil
+}
+
+// react adds reaction bonus to the replied message if it was scored
+func (p telegramPlatform) react(msg *chatMessage, policy scoringPolicy) error {
+	if policy.ReactionBonus == 0 {
+		return nil
+	}
+
+	added, err := p.app.sqlModels.Rewards.AddReactionBonus(msg.context(), msg.ChatID, int(msg.ReplyTo.MessageID), int(msg.From.ID), policy.ReactionBonus, policy.MaxBonus)
+	if err != nil {
+		return err
+	}
+
+	if added {
+		metrics.BotScoring.WithLabelValues(scoreReaction).Inc()
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/i18n"
+)
+
+const (
+	// users shown by /top
+	topLimit = 10
+	// items in every section of the digest
+	digestLimit = 5
+)
+
+// periods of /top, the leaderboard by rating is shown without period
+var topPeriods = map[string]time.Duration{
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// /top [week|month] shows the leaderboard by rating or by points of the period with rank changes
+func (app *application) topHandler(msgConfig tgbotapi.MessageConfig, updateMsg *tgbotapi.Message) error {
+	ctx := telegramContext(updateMsg)
+
+	lang := app.language(updateMsg)
+
+	period := strings.ToLower(strings.TrimSpace(updateMsg.CommandArguments()))
+
+	var (
+		entries []*database.LeaderboardEntry
+		err     error
+		key     string
+	)
+
+	now := time.Now()
+
+	switch duration, ok := topPeriods[period]; {
+	case period == "" || period == "all":
+		key = "bot.top.all"
+		entries, err = app.sqlModels.Leaderboard.GetRatingTop(ctx, now.Add(-topPeriods["week"]).Unix(), topLimit)
+	case ok:
+		key = "bot.top." + period
+		entries, err = app.sqlModels.Leaderboard.GetPeriodTop(ctx, now.Add(-duration).Unix(), now.Unix(), topLimit)
+	default:
+		return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.top.usage"))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return app.reply(msgConfig, updateMsg, i18n.T(lang, "bot.top.empty"))
+	}
+
+	rows := make([]string, len(entries))
+
+	for i, entry := range entries {
+		rows[i] = i18n.T(lang, "bot.top.row", entry.Rank, app.userLink(entry.Username), entry.Points, rankChange(entry))
+	}
+
+	msgConfig.DisableWebPagePreview = true
+
+	return app.reply(msgConfig, updateMsg, i18n.T(lang, key, strings.Join(rows, "\n")))
+}
+
+// rankChange renders the move since the previous period
+func rankChange(entry *database.LeaderboardEntry) string {
+	switch {
+	case entry.PreviousRank == nil:
+		return "🆕"
+	case *entry.PreviousRank > entry.Rank:
+		return fmt.Sprintf("▲%d", *entry.PreviousRank-entry.Rank)
+	case *entry.PreviousRank < entry.Rank:
+		return fmt.Sprintf("▼%d", entry.Rank-*entry.PreviousRank)
+	}
+
+	return ""
+}
+
+func (app *application) userLink(username string) string {
+	return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(app.profileURL(username)), html.EscapeString(username))
+}
+
+// chatDigestTask posts movers, issued SBTs and newcomers of the last week to chats with digest
+// enabled. Failed chats are logged and not retried, so other chats don't get the digest twice.
+func (app *application) chatDigestTask(ctx context.Context, t *asynq.Task) error {
+	chats, err := app.sqlModels.Chats.GetDigestChats(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(chats) == 0 {
+		return nil
+	}
+
+	since := time.Now().Add(-topPeriods["week"]).Unix()
+
+	movers, err := app.sqlModels.Leaderboard.GetMovers(ctx, since, digestLimit)
+	if err != nil {
+		return err
+	}
+
+	sbts, err := app.sqlModels.Leaderboard.GetIssuedSBTs(ctx, since, digestLimit)
+	if err != nil {
+		return err
+	}
+
+	newcomers, newcomersCount, err := app.sqlModels.Leaderboard.GetNewcomers(ctx, since, digestLimit)
+	if err != nil {
+		return err
+	}
+
+	if len(movers) == 0 && len(sbts) == 0 && newcomersCount == 0 {
+		app.logger.Ctx(ctx).Info("nothing happened this week, digest is not posted")
+		return nil
+	}
+
+	for _, chat := range chats {
+		lang := i18n.Match(chat.Language)
+
+		msg := tgbotapi.NewMessage(chat.ID, app.digestText(lang, movers, sbts, newcomers, newcomersCount))
+		msg.ParseMode = "HTML"
+		msg.DisableWebPagePreview = true
+
+		if _, err := app.send(msg); err != nil {
+			app.logger.Ctx(ctx).Warningw("error posting digest", "chat_id", chat.ID, "error", err)
+			continue
+		}
+
+		app.logger.Ctx(ctx).Infow("posted digest", "chat_id", chat.ID)
+	}
+
+	return nil
+}
+
+func (app *application) digestText(lang string, movers []*database.LeaderboardEntry, sbts []*database.IssuedSBT, newcomers []string, newcomersCount int64) string {
+	sections := []string{"<b>" + i18n.T(lang, "bot.digest.title") + "</b>"}
+
+	if len(movers) > 0 {
+		lines := []string{i18n.T(lang, "bot.digest.movers")}
+
+		for _, mover := range movers {
+			lines = append(lines, "▪️ "+i18n.T(lang, "bot.digest.mover", app.userLink(mover.Username), *mover.PreviousRank-mover.Rank, mover.Rank))
+		}
+
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	if len(sbts) > 0 {
+		lines := []string{i18n.T(lang, "bot.digest.sbts")}
+
+		for _, sbt := range sbts {
+			lines = append(lines, "▪️ "+i18n.T(lang, "bot.digest.sbt", html.EscapeString(sbt.Name), sbt.Count))
+		}
+
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	if newcomersCount > 0 {
+		links := make([]string, len(newcomers))
+		for i, username := range newcomers {
+			links[i] = app.userLink(username)
+		}
+
+		if more := newcomersCount - int64(len(newcomers)); more > 0 {
+			links = append(links, i18n.T(lang, "bot.digest.more", more))
+		}
+
+		sections = append(sections, i18n.T(lang, "bot.digest.newcomers", newcomersCount)+"\n"+strings.Join(links, ", "))
+	}
+
+	return strings.Join(sections, "\n\n")
+}
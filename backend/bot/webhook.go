@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const (
+	webhookShutdownPeriod = 30 * time.Second
+	// updates are small, bigger requests are not from Telegram
+	webhookMaxBodyBytes = 1 << 20
+)
+
+// allowed characters of secret_token in setWebhook
+var webhookSecretRX = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// serveWebhook registers the webhook and serves updates until the quit signal,
+// then waits for in-flight requests and queued updates
+func (app *application) serveWebhook(d *dispatcher, quit <-chan os.Signal) error {
+	webhookURL, err := url.Parse(app.config.Bot.WebhookURL)
+	if err != nil || webhookURL.Scheme != "https" || webhookURL.Host == "" {
+		return errors.New("BOT_WEBHOOK_URL must be https url in webhook mode")
+	}
+
+	if !webhookSecretRX.MatchString(app.config.Bot.WebhookSecret) {
+		return errors.New("BOT_WEBHOOK_SECRET must be 1-256 characters A-Z, a-z, 0-9, _ or - in webhook mode")
+	}
+
+	path := webhookURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, app.webhookHandler(d))
+
+	srv := &http.Server{
+		Addr:         app.config.Bot.WebhookAddr,
+		Handler:      mux,
+		ErrorLog:     log.New(app.logger, "", 0),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	serveErrorChan := make(chan error, 1)
+
+	go func() {
+		app.logger.Infow("starting webhook server", "addr", srv.Addr)
+		serveErrorChan <- srv.ListenAndServe()
+	}()
+
+	err = app.setWebhook(webhookURL.String())
+	if err != nil {
+		srv.Close()
+		d.drain()
+		return err
+	}
+
+	app.logger.Infow("receiving updates with webhook", "url", webhookURL.Host+path)
+
+	select {
+	case err := <-serveErrorChan:
+		d.drain()
+		return err
+
+	case <-quit:
+	}
+
+	app.logger.Info("Stopping bot...")
+
+	// the webhook is kept, Telegram holds updates until the bot is back
+	ctx, cancel := context.WithTimeout(context.Background(), webhookShutdownPeriod)
+	defer cancel()
+
+	err = srv.Shutdown(ctx)
+
+	d.drain()
+
+	app.logger.Info("Bot stopped")
+
+	return err
+}
+
+// setWebhook is sent as raw request, the library has no secret_token parameter
+func (app *application) setWebhook(webhookURL string) error {
+	params := url.Values{}
+	params.Set("url", webhookURL)
+	params.Set("secret_token", app.config.Bot.WebhookSecret)
+	params.Set("max_connections", strconv.Itoa(app.config.Bot.Workers))
+
+	resp, err := app.bot.MakeRequest("setWebhook", params)
+	if err != nil {
+		return fmt.Errorf("setWebhook: %w", err)
+	}
+
+	if !resp.Ok {
+		return fmt.Errorf("setWebhook: %s", resp.Description)
+	}
+
+	return nil
+}
+
+// webhookHandler accepts updates with the secret token and queues them, Telegram gets
+// the response before the update is processed
+func (app *application) webhookHandler(d *dispatcher) http.HandlerFunc {
+	secret := []byte(app.config.Bot.WebhookSecret)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := []byte(r.Header.Get("X-Telegram-Bot-Api-Secret-Token"))
+		if subtle.ConstantTimeCompare(token, secret) != 1 {
+			app.logger.Warningw("webhook request with invalid secret token", "remote_addr", r.RemoteAddr)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		var update tgbotapi.Update
+
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, webhookMaxBodyBytes)).Decode(&update)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		d.dispatch(update)
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
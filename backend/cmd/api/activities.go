@@ -8,8 +8,10 @@ import (
 	"strings"
 
 	"github.com/alexedwards/flow"
+	"github.com/ton-developer-program/internal/database"
 	"github.com/ton-developer-program/internal/request"
 	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/validator"
 )
 
 
@@ -19,6 +21,8 @@ func (app *application) InsertActivityHandler(w http.ResponseWriter, r *http.Req
 		Description *string `json:"description"`
 		TokenThreshold *int64 `json:"token_threshold"`
 		SBTMetadata *string `json:"sbt_token_metadata"`
+		// rating by default
+		Metric *string `json:"metric"`
 	}
 
 	err := request.DecodeJSON(w, r, &input)
@@ -27,8 +31,23 @@ func (app *application) InsertActivityHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	metric := database.MetricRating
+
+	if input.Metric != nil {
+		metric = *input.Metric
+	}
+
+	v := validator.Validator{}
+
+	v.CheckField(validator.In(metric, database.ActivityMetrics...), "metric", "must be rating or kudos")
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
 	// get sbtMetadata by base64
-	sbtMetadata, err := app.sqlModels.Nfts.GetNFTMetadataByBase64(*input.SBTMetadata)
+	sbtMetadata, err := app.sqlModels.Nfts.GetNFTMetadataByBase64(r.Context(), *input.SBTMetadata)
 
 	var tokenThreshold int64 = 0
 
@@ -37,17 +56,15 @@ func (app *application) InsertActivityHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// Assuming you have a method in your `sqlModels` to insert the activity
-	activity, err := app.sqlModels.Activities.Insert(*input.Name, *input.Description, tokenThreshold, sbtMetadata.ID)
+	activity, err := app.sqlModels.Activities.Insert(*input.Name, *input.Description, tokenThreshold, sbtMetadata.ID, metric)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 		return
 	}
 
 	err = response.JSON(w, http.StatusCreated, activity)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 	}
 }
 
@@ -87,14 +104,12 @@ func (app *application) getActivitiesHandler(w http.ResponseWriter, r *http.Requ
 	activities, err := app.sqlModels.Activities.GetAll(startInt, endInt, filter)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 		return
 	}
 
 	err = response.JSON(w, http.StatusOK, activities)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 	}
 }
 
@@ -112,14 +127,12 @@ func (app *application) deleteActivityHandler(w http.ResponseWriter, r *http.Req
 	err = app.sqlModels.Activities.Delete(activityIDInt)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 		return
 	}
 
 	err = response.JSON(w, http.StatusOK, map[string]string{"message": "Activity deleted successfully"})
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 	}
 }
 
@@ -138,7 +151,6 @@ func (app *application) updateActivityHandler(w http.ResponseWriter, r *http.Req
 	activity, err := app.sqlModels.Activities.GetByID(idInt64)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 		return
 	}
 
@@ -147,6 +159,7 @@ func (app *application) updateActivityHandler(w http.ResponseWriter, r *http.Req
 		Name        *string `json:"name"`
 		Description *string `json:"description"`
 		TokenThreshold *int64 `json:"token_threshold"`
+		Metric *string `json:"metric"`
 	}
 
 	err = request.DecodeJSON(w, r, &input)
@@ -168,19 +181,30 @@ func (app *application) updateActivityHandler(w http.ResponseWriter, r *http.Req
 		activity.TokenThreshold = *input.TokenThreshold
 	}
 
+	if input.Metric != nil {
+		activity.Metric = *input.Metric
+	}
+
+	v := validator.Validator{}
+
+	v.CheckField(validator.In(activity.Metric, database.ActivityMetrics...), "metric", "must be rating or kudos")
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
 
 	// Update the activity in the database
-	updatedActivity, err := app.sqlModels.Activities.Update(activity.ID, activity.Name, activity.Description, activity.TokenThreshold)
+	updatedActivity, err := app.sqlModels.Activities.Update(activity.ID, activity.Name, activity.Description, activity.TokenThreshold, activity.Metric)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 		return
 	}
 
 	err = response.JSON(w, http.StatusOK, updatedActivity)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 	}
 }
 
@@ -198,14 +222,12 @@ func (app *application) getActivityHandler(w http.ResponseWriter, r *http.Reques
 	activity, err := app.sqlModels.Activities.GetByID(idInt64)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 		return
 	}
 
 	err = response.JSON(w, http.StatusOK, activity)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 	}
 }
 
@@ -252,7 +274,6 @@ func (app *application) getRewardsHandler(w http.ResponseWriter, r *http.Request
 	rewards, err := app.sqlModels.Rewards.GetAll(startInt, endInt, filter)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 		return
 	}
 
@@ -274,14 +295,12 @@ func (app *application) deleteRewardHandler(w http.ResponseWriter, r *http.Reque
 	err = app.sqlModels.Rewards.Delete(idInt64)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 		return
 	}
 
 	err = response.JSON(w, http.StatusOK, map[string]string{"message": "Reward deleted successfully"})
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 	}
 
 
@@ -302,14 +321,12 @@ func (app *application) getRewardHandler(w http.ResponseWriter, r *http.Request)
 	reward, err := app.sqlModels.Rewards.GetById(idInt64)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 		return
 	}
 
 	err = response.JSON(w, http.StatusOK, reward)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 	}
 }
 
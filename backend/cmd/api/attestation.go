@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ton-developer-program/attestation"
+	"github.com/ton-developer-program/internal/request"
+	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/tonconnect"
+	"github.com/ton-developer-program/internal/validator"
+	"github.com/ton-developer-program/util"
+	"github.com/tonkeeper/tongo"
+	"github.com/xssnick/tonutils-go/address"
+)
+
+// limit for prototype and collection lists in the policy
+const maxAttestationPolicyItems = 20
+
+// loadAttestationKey parses base64 ed25519 seed or private key from config.
+// The key is required outside development, there a random one is generated
+// and attestations can't be verified after restart.
+func loadAttestationKey(encoded, env string) (ed25519.PrivateKey, bool, error) {
+	if encoded == "" {
+		if env != util.EnvDevelopment {
+			return nil, false, errors.New("APP_ATTESTATION_KEY must be set outside development")
+		}
+
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, true, err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid attestation key: %w", err)
+	}
+
+	switch len(b) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(b), false, nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(b), false, nil
+	default:
+		return nil, false, errors.New("invalid attestation key: must be 32 byte seed or 64 byte private key")
+	}
+}
+
+// friendlyAddress converts raw or user-friendly address to the format stored in the database
+func friendlyAddress(s string) (string, error) {
+	if strings.Contains(s, ":") {
+		if _, err := tongo.ParseAccountID(s); err != nil {
+			return "", errors.New("invalid address")
+		}
+		return tonconnect.ConvertToFriendlyAddr(s).String(), nil
+	}
+
+	addr, err := address.ParseAddr(s)
+	if err != nil {
+		return "", errors.New("invalid address")
+	}
+
+	return address.NewAddress(0, 0, addr.Data()).String(), nil
+}
+
+// getAttestationKeyHandler publishes the key partners use to verify attestations
+func (app *application) getAttestationKeyHandler(w http.ResponseWriter, r *http.Request) {
+	publicKey := app.attestationKey.Public().(ed25519.PublicKey)
+
+	err := response.JSON(w, http.StatusOK, map[string]string{
+		"issuer":     attestation.Issuer,
+		"algorithm":  attestation.Algorithm,
+		"key_id":     attestation.KeyID(publicKey),
+		"public_key": attestation.EncodePublicKey(publicKey),
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// verifyAttestationHandler checks the policy for the wallet and returns signed attestation
+func (app *application) verifyAttestationHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Address  *string              `json:"address"`
+		Proof    *tonconnect.TonProof `json:"proof"`
+		Audience *string              `json:"audience"`
+		Policy   *attestation.Policy  `json:"policy"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	v := validator.Validator{}
+
+	v.Check((input.Address == nil) != (input.Proof == nil), "either address or proof must be provided")
+	v.CheckField(input.Policy != nil, "policy", "must be provided")
+	v.CheckField(input.Audience == nil || len(*input.Audience) <= 128, "audience", "must not be more than 128 bytes long")
+
+	if input.Policy != nil {
+		policy := input.Policy
+		v.CheckField(len(policy.PrototypeIDs) > 0 || len(policy.CollectionIDs) > 0 || policy.MinRating != nil || policy.MaxRank != nil, "policy", "must contain at least one condition")
+		v.CheckField(len(policy.PrototypeIDs) <= maxAttestationPolicyItems, "policy.prototype_ids", fmt.Sprintf("must not contain more than %d items", maxAttestationPolicyItems))
+		v.CheckField(len(policy.CollectionIDs) <= maxAttestationPolicyItems, "policy.collection_ids", fmt.Sprintf("must not contain more than %d items", maxAttestationPolicyItems))
+		v.CheckField(policy.MaxRank == nil || *policy.MaxRank > 0, "policy.max_rank", "must be greater than zero")
+	}
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	var addr string
+	proofVerified := false
+
+	if input.Proof != nil {
+		err = app.verifyTonProof(r.Context(), input.Proof)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+
+		addr = tonconnect.ConvertToFriendlyAddr(input.Proof.Address).String()
+		proofVerified = true
+	} else {
+		addr, err = friendlyAddress(*input.Address)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+	}
+
+	checks, satisfied, err := app.checkAttestationPolicy(r.Context(), addr, *input.Policy)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+
+	a := &attestation.Attestation{
+		Issuer:        attestation.Issuer,
+		KeyID:         attestation.KeyID(app.attestationKey.Public().(ed25519.PublicKey)),
+		Address:       addr,
+		ProofVerified: proofVerified,
+		Policy:        *input.Policy,
+		Checks:        checks,
+		Satisfied:     satisfied,
+		IssuedAt:      now.Unix(),
+		ExpiresAt:     now.Add(time.Duration(app.config.App.AttestationTTLSec) * time.Second).Unix(),
+	}
+
+	if input.Audience != nil {
+		a.Audience = *input.Audience
+	}
+
+	token, err := attestation.Sign(app.attestationKey, a)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]any{
+		"attestation": a,
+		"token":       token,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// checkAttestationPolicy evaluates every condition of the policy, wallets without profile have zero rating and no rank
+func (app *application) checkAttestationPolicy(ctx context.Context, addr string, policy attestation.Policy) (attestation.Checks, bool, error) {
+	checks := attestation.Checks{}
+	satisfied := true
+
+	if len(policy.PrototypeIDs) > 0 {
+		owned, err := app.sqlModels.Nfts.GetOwnedPrototypeIDs(ctx, addr, policy.PrototypeIDs)
+		if err != nil {
+			return checks, false, err
+		}
+
+		checks.PrototypeIDs = matchOwnedIDs(policy.PrototypeIDs, owned, &satisfied)
+	}
+
+	if len(policy.CollectionIDs) > 0 {
+		owned, err := app.sqlModels.Nfts.GetOwnedCollectionIDs(ctx, addr, policy.CollectionIDs)
+		if err != nil {
+			return checks, false, err
+		}
+
+		checks.CollectionIDs = matchOwnedIDs(policy.CollectionIDs, owned, &satisfied)
+	}
+
+	if policy.MinRating == nil && policy.MaxRank == nil {
+		return checks, satisfied, nil
+	}
+
+	user, err := app.sqlModels.Users.GetByFriendlyAddress(ctx, addr)
+	if err != nil {
+		return checks, false, err
+	}
+
+	if policy.MinRating != nil {
+		ok := user != nil && user.Rating >= *policy.MinRating
+		checks.MinRating = &ok
+		satisfied = satisfied && ok
+	}
+
+	if policy.MaxRank != nil {
+		ok := false
+
+		if user != nil {
+			position, _, err := app.sqlModels.Users.GetUserPosition(ctx, user.ID)
+			if err != nil {
+				return checks, false, err
+			}
+
+			ok = position > 0 && position <= *policy.MaxRank
+		}
+
+		checks.MaxRank = &ok
+		satisfied = satisfied && ok
+	}
+
+	return checks, satisfied, nil
+}
+
+func matchOwnedIDs(required, owned []int64, satisfied *bool) map[int64]bool {
+	result := make(map[int64]bool, len(required))
+
+	for _, id := range required {
+		result[id] = false
+	}
+
+	for _, id := range owned {
+		result[id] = true
+	}
+
+	for _, ok := range result {
+		if !ok {
+			*satisfied = false
+		}
+	}
+
+	return result
+}
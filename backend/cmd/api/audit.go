@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/flow"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/response"
+)
+
+// request and response bodies larger than this are not stored in the audit log
+const maxAuditBodySize = 64 << 10
+
+// auditRecorder keeps status code and response body of the handler
+type auditRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *auditRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *auditRecorder) Write(b []byte) (int, error) {
+	if rec.body.Len()+len(b) <= maxAuditBodySize {
+		rec.body.Write(b)
+	}
+
+	return rec.ResponseWriter.Write(b)
+}
+
+// auditLoaders returns current state of admin resources by id, used to store before and after snapshots
+func (app *application) auditLoaders() map[string]func(ctx context.Context, id int64) (any, error) {
+	return map[string]func(ctx context.Context, id int64) (any, error){
+		"users":       func(ctx context.Context, id int64) (any, error) { return app.sqlModels.Users.GetById(ctx, id) },
+		"collections": func(ctx context.Context, id int64) (any, error) { return app.sqlModels.Nfts.GetCollectionById(ctx, id) },
+		"minted-nfts": func(ctx context.Context, id int64) (any, error) { return app.sqlModels.Nfts.GetTokenByID(ctx, id) },
+		"prototype-nfts": func(ctx context.Context, id int64) (any, error) {
+			return app.sqlModels.Nfts.GetNFTMetadataByPrototypeID(ctx, id)
+		},
+		"rewards":    func(ctx context.Context, id int64) (any, error) { return app.sqlModels.Rewards.GetById(id) },
+		"activities": func(ctx context.Context, id int64) (any, error) { return app.sqlModels.Activities.GetByID(id) },
+		"roles": func(ctx context.Context, id int64) (any, error) {
+			return app.sqlModels.Permissions.GetRoleByID(ctx, id)
+		},
+		"merch":      func(ctx context.Context, id int64) (any, error) { return app.sqlModels.Rewards.GetMerchByID(id) },
+		"recoveries": func(ctx context.Context, id int64) (any, error) { return app.sqlModels.Recoveries.GetByID(ctx, id) },
+		"service-accounts": func(ctx context.Context, id int64) (any, error) {
+			return app.sqlModels.ServiceAccounts.GetByID(ctx, id)
+		},
+		"tg-chats":          func(ctx context.Context, id int64) (any, error) { return app.sqlModels.Chats.GetByID(ctx, id) },
+		"tg-failed-updates": func(ctx context.Context, id int64) (any, error) { return app.sqlModels.FailedUpdates.GetByID(ctx, id) },
+		"kudos":             func(ctx context.Context, id int64) (any, error) { return app.sqlModels.Kudos.GetByID(ctx, id) },
+	}
+}
+
+// auditAdminActions writes an audit record for every state-changing request
+func (app *application) auditAdminActions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// /v1/admin/<resource>/...
+		var targetType string
+		if segments := strings.Split(r.URL.Path, "/"); len(segments) > 3 {
+			targetType = segments[3]
+		}
+
+		targetID := flow.Param(r.Context(), "id")
+
+		loader := app.auditLoaders()[targetType]
+
+		var before any
+		if loader != nil && targetID != "" {
+			before = app.auditSnapshot(r.Context(), loader, targetID)
+		}
+
+		var requestBody []byte
+		if r.Body != nil {
+			requestBody, _ = io.ReadAll(io.LimitReader(r.Body, maxAuditBodySize+1))
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(requestBody), r.Body))
+		}
+
+		rec := &auditRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		var after any
+		switch {
+		case r.Method == http.MethodDelete:
+		case loader != nil && targetID != "":
+			after = app.auditSnapshot(r.Context(), loader, targetID)
+		case rec.body.Len() > 0:
+			after = json.RawMessage(rec.body.Bytes())
+		case len(requestBody) > 0 && len(requestBody) <= maxAuditBodySize:
+			after = json.RawMessage(requestBody)
+		}
+
+		log := &database.AuditLog{
+			Method:    r.Method,
+			Route:     r.URL.Path,
+			Status:    rec.status,
+			Before:    toAuditJSON(before),
+			After:     toAuditJSON(after),
+			RequestID: app.contextGetRequestID(r),
+			CreatedAt: time.Now().Unix(),
+		}
+
+		log.Diff = auditDiff(log.Before, log.After)
+
+		switch user := app.contextGetUser(r); {
+		case user.IsServiceAccount():
+			actor := fmt.Sprintf("service-account:%d:%s", user.ServiceAccount.ID, user.ServiceAccount.Name)
+			log.ActorAddress = &actor
+		case !user.IsAnonymous():
+			log.ActorID = &user.ID
+			log.ActorAddress = &user.FriendlyAddress
+		}
+
+		if targetType != "" {
+			log.TargetType = &targetType
+		}
+
+		if targetID != "" {
+			log.TargetID = &targetID
+		}
+
+		ip := app.clientIP(r)
+		log.IP = &ip
+
+		err := app.sqlModels.Audit.Insert(r.Context(), log)
+		if err != nil {
+			app.logger.Error(fmt.Errorf("failed to write audit log: %w", err), nil)
+		}
+	})
+}
+
+// auditSnapshot loads resource state, errors are ignored as the resource may not exist
+func (app *application) auditSnapshot(ctx context.Context, loader func(ctx context.Context, id int64) (any, error), targetID string) any {
+	id, err := strconv.ParseInt(targetID, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	value, err := loader(ctx, id)
+	if err != nil {
+		return nil
+	}
+
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil
+	}
+
+	return value
+}
+
+// toAuditJSON converts value to json object, arrays and scalars are stored under "value" key
+func toAuditJSON(value any) *database.JSONB {
+	if value == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+
+	var object database.JSONB
+	if err := json.Unmarshal(b, &object); err == nil && object != nil {
+		return &object
+	}
+
+	var raw any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil
+	}
+
+	return &database.JSONB{"value": raw}
+}
+
+// auditDiff returns changed keys with before and after values
+func auditDiff(before, after *database.JSONB) *database.JSONB {
+	if before == nil || after == nil {
+		return nil
+	}
+
+	diff := database.JSONB{}
+
+	for key, value := range *before {
+		if !reflect.DeepEqual(value, (*after)[key]) {
+			diff[key] = map[string]any{"before": value, "after": (*after)[key]}
+		}
+	}
+
+	for key, value := range *after {
+		if _, ok := (*before)[key]; !ok {
+			diff[key] = map[string]any{"before": nil, "after": value}
+		}
+	}
+
+	return &diff
+}
+
+// getAuditLogsHandler returns audit records, format=csv exports them as csv file
+func (app *application) getAuditLogsHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := getPagination(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	filter := database.AuditFilter{
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+		Method:     query.Get("method"),
+	}
+
+	if actorID := query.Get("actor_id"); actorID != "" {
+		id, err := strconv.ParseInt(actorID, 10, 64)
+		if err != nil {
+			app.badRequest(w, r, errors.New("actor_id must be an integer"))
+			return
+		}
+		filter.ActorID = &id
+	}
+
+	for key, dst := range map[string]*int64{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(key); value != "" {
+			*dst, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				app.badRequest(w, r, fmt.Errorf("%s must be unix timestamp", key))
+				return
+			}
+		}
+	}
+
+	logs, err := app.sqlModels.Audit.GetAll(r.Context(), pagination, filter)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	totalCount, err := app.sqlModels.Audit.Count(r.Context(), filter)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if query.Get("format") == "csv" {
+		app.writeAuditCSV(w, r, logs)
+		return
+	}
+
+	headers := http.Header{
+		"x-total-count": []string{strconv.FormatInt(totalCount, 10)},
+	}
+
+	response.JSONWithHeaders(w, http.StatusOK, logs, headers)
+}
+
+func (app *application) writeAuditCSV(w http.ResponseWriter, r *http.Request, logs []*database.AuditLog) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=audit-log-%d.csv", time.Now().Unix()))
+
+	writer := csv.NewWriter(w)
+
+	writer.Write([]string{"id", "created_at", "actor_id", "actor_address", "method", "route", "target_type", "target_id", "status", "request_id", "ip", "diff"})
+
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+
+	for _, log := range logs {
+		actorID := ""
+		if log.ActorID != nil {
+			actorID = strconv.FormatInt(*log.ActorID, 10)
+		}
+
+		diff := ""
+		if log.Diff != nil {
+			b, _ := json.Marshal(log.Diff)
+			diff = string(b)
+		}
+
+		writer.Write([]string{
+			strconv.FormatInt(log.ID, 10),
+			time.Unix(log.CreatedAt, 0).UTC().Format(time.RFC3339),
+			actorID,
+			str(log.ActorAddress),
+			log.Method,
+			log.Route,
+			str(log.TargetType),
+			str(log.TargetID),
+			strconv.Itoa(log.Status),
+			log.RequestID,
+			str(log.IP),
+			diff,
+		})
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		app.logger.Error(err, nil)
+	}
+}
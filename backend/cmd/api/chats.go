@@ -0,0 +1,271 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/flow"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/i18n"
+	"github.com/ton-developer-program/internal/request"
+	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/validator"
+)
+
+// chatInput is shared by create and update, nil fields are not changed on update
+type chatInput struct {
+	ID          *int64  `json:"id"`
+	Title       *string `json:"title"`
+	Enabled     *bool   `json:"enabled"`
+	Weight      *int    `json:"weight"`
+	Language    *string `json:"language"`
+	Digest      *bool   `json:"digest"`
+	WelcomeText *string `json:"welcome_text"`
+	InviteURL   *string `json:"invite_url"`
+}
+
+// apply copies provided fields to the chat and validates the result
+func (input chatInput) apply(chat *database.Chat, v *validator.Validator) {
+	if input.Title != nil {
+		chat.Title = strings.TrimSpace(*input.Title)
+	}
+
+	if input.Enabled != nil {
+		chat.Enabled = *input.Enabled
+	}
+
+	if input.Weight != nil {
+		chat.Weight = *input.Weight
+	}
+
+	if input.Language != nil {
+		chat.Language = *input.Language
+	}
+
+	if input.Digest != nil {
+		chat.Digest = *input.Digest
+	}
+
+	if input.WelcomeText != nil {
+		chat.WelcomeText = input.WelcomeText
+	}
+
+	if input.InviteURL != nil {
+		chat.InviteURL = input.InviteURL
+	}
+
+	v.CheckField(validator.NotBlank(chat.Title), "title", "must be provided")
+	v.CheckField(validator.MaxRunes(chat.Title, 255), "title", "must not be more than 255 characters long")
+	v.CheckField(validator.Between(chat.Weight, 0, 100), "weight", "must be between 0 and 100")
+	v.CheckField(validator.In(chat.Language, i18n.Languages...), "language", "must be one of "+strings.Join(i18n.Languages, ", "))
+	v.CheckField(chat.WelcomeText == nil || validator.MaxRunes(*chat.WelcomeText, 4096), "welcome_text", "must not be more than 4096 characters long")
+	v.CheckField(chat.InviteURL == nil || *chat.InviteURL == "" || strings.HasPrefix(*chat.InviteURL, "https://t.me/"), "invite_url", "must be a https://t.me/ link")
+}
+
+func (app *application) getChatsHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := getPagination(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	chats, err := app.sqlModels.Chats.GetPage(r.Context(), pagination)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	totalCount, err := app.sqlModels.Chats.Count(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	headers := http.Header{
+		"x-total-count": []string{strconv.FormatInt(totalCount, 10)},
+	}
+
+	response.JSONWithHeaders(w, http.StatusOK, chats, headers)
+}
+
+func (app *application) getChatHandler(w http.ResponseWriter, r *http.Request) {
+	chat, ok := app.readChat(w, r)
+	if !ok {
+		return
+	}
+
+	err := response.JSON(w, http.StatusOK, chat)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createChatHandler(w http.ResponseWriter, r *http.Request) {
+	var input chatInput
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	v := validator.Validator{}
+
+	// group and channel ids are negative
+	v.CheckField(input.ID != nil && *input.ID < 0, "id", "must be a negative telegram chat id")
+
+	chat := &database.Chat{
+		Enabled:  true,
+		Weight:   1,
+		Language: i18n.English,
+	}
+
+	if input.ID != nil {
+		chat.ID = *input.ID
+	}
+
+	input.apply(chat, &v)
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	err = app.sqlModels.Chats.Insert(r.Context(), chat)
+	if err != nil {
+		if errors.Is(err, database.ErrDuplicateChat) {
+			v.AddFieldError("id", "the chat is already registered")
+			app.failedValidation(w, r, v)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusCreated, chat)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) updateChatHandler(w http.ResponseWriter, r *http.Request) {
+	chat, ok := app.readChat(w, r)
+	if !ok {
+		return
+	}
+
+	var input chatInput
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	v := validator.Validator{}
+
+	input.apply(chat, &v)
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	err = app.sqlModels.Chats.Update(r.Context(), chat)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.editConclictResponse(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, chat)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteChatHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return
+	}
+
+	err = app.sqlModels.Chats.Delete(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]string{"message": "chat successfully deleted"})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// getChatsStatsHandler returns scored messages per chat, from and to are unix time, the last 30 days by default
+func (app *application) getChatsStatsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	to := time.Now().Unix()
+	from := time.Now().AddDate(0, 0, -30).Unix()
+
+	for key, dst := range map[string]*int64{"from": &from, "to": &to} {
+		if value := query.Get(key); value != "" {
+			var err error
+
+			*dst, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				app.badRequest(w, r, fmt.Errorf("%s must be unix timestamp", key))
+				return
+			}
+		}
+	}
+
+	stats, err := app.sqlModels.Chats.GetStats(r.Context(), from, to)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{
+		"from":  from,
+		"to":    to,
+		"chats": stats,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// readChat loads chat from :id route param, writes error response when it fails
+func (app *application) readChat(w http.ResponseWriter, r *http.Request) (*database.Chat, bool) {
+	id, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return nil, false
+	}
+
+	chat, err := app.sqlModels.Chats.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return nil, false
+		}
+		app.serverError(w, r, err)
+		return nil, false
+	}
+
+	return chat, true
+}
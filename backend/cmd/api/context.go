@@ -5,11 +5,14 @@ import (
 	"net/http"
 
 	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/leveledlog"
 )
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey = contextKey("user")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *database.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -24,3 +27,13 @@ func (app *application) contextGetUser(r *http.Request) *database.User {
 
 	return user
 }
+
+// contextSetRequestID stores request id for the logger and tasks enqueued by the request
+func (app *application) contextSetRequestID(r *http.Request, requestID string) *http.Request {
+	ctx := leveledlog.ContextWithRequestID(r.Context(), requestID)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetRequestID(r *http.Request) string {
+	return leveledlog.RequestIDFromContext(r.Context())
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/tasks"
+	"golang.org/x/oauth2"
+)
+
+// the link token is the oauth state, it only has to live until Discord redirects back
+const discordLinkTTL = 15 * time.Minute
+
+// createDiscordLinkHandler returns the url of the Discord authorization page. The state is
+// a link token, so the callback links the account to the user who asked for the url.
+func (app *application) createDiscordLinkHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if app.discordOauthConfig.ClientID == "" {
+		app.serverError(w, r, errors.New("AUTH_DISCORD_CLIENT_ID is not set"))
+		return
+	}
+
+	err := app.sqlModels.Tokens.DeleteAllForUser(r.Context(), database.ScopeDiscordLink, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	token, err := app.sqlModels.Tokens.New(r.Context(), user.ID, discordLinkTTL, database.ScopeDiscordLink)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusCreated, map[string]interface{}{
+		"url":    app.discordOauthConfig.AuthCodeURL(token.Plaintext, oauth2.AccessTypeOnline),
+		"expiry": token.Expiry,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// discordCallbackHandler links the Discord account and redirects to the settings,
+// the discord query parameter tells the frontend the result
+func (app *application) discordCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	redirect := func(result string) {
+		http.Redirect(w, r, app.config.App.BaseUrl+"/settings?discord="+result, http.StatusTemporaryRedirect)
+	}
+
+	user, err := app.sqlModels.Users.GetForToken(r.Context(), database.ScopeDiscordLink, r.FormValue("state"))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			app.logger.Ctx(r.Context()).Warning(fmt.Sprintf("error getting user for discord link: %v", err))
+		}
+		redirect("invalid")
+		return
+	}
+
+	// the user cancelled the authorization
+	if r.FormValue("error") != "" {
+		redirect("cancelled")
+		return
+	}
+
+	discordUser, err := app.getDiscordUser(r.Context(), r.FormValue("code"))
+	if err != nil {
+		app.logger.Ctx(r.Context()).Warning(fmt.Sprintf("error getting discord user: %v", err))
+		redirect("error")
+		return
+	}
+
+	discordUserID, err := strconv.ParseInt(discordUser.ID, 10, 64)
+	if err != nil {
+		app.logger.Ctx(r.Context()).Warning(fmt.Sprintf("invalid discord user id %q", discordUser.ID))
+		redirect("error")
+		return
+	}
+
+	linked, err := app.sqlModels.Users.GetByDiscordUserId(r.Context(), discordUserID)
+	if err != nil {
+		app.logger.Ctx(r.Context()).Warning(fmt.Sprintf("error getting user by discord user id: %v", err))
+		redirect("error")
+		return
+	}
+
+	if linked != nil && linked.ID != user.ID {
+		redirect("taken")
+		return
+	}
+
+	if linked == nil {
+		accounts, err := app.sqlModels.Users.GetLinkedAccounts(r.Context(), user.ID)
+		if err != nil {
+			app.logger.Ctx(r.Context()).Warning(fmt.Sprintf("error getting linked accounts: %v", err))
+			redirect("error")
+			return
+		}
+
+		// another Discord account is linked, it has to be unlinked first
+		for _, account := range accounts {
+			if account.Provider == database.ProviderDiscord {
+				redirect("exists")
+				return
+			}
+		}
+
+		// members without an avatar keep the default avatar of the platform
+		avatarURL := ""
+		if discordUser.Avatar != "" {
+			avatarURL = discordUser.AvatarURL("")
+		}
+
+		now := uint64(time.Now().Unix())
+
+		err = app.sqlModels.Users.InsertLinkedAccount(r.Context(), &database.LinkedAccount{
+			UserID:        user.ID,
+			DiscordUserID: &discordUserID,
+			Provider:      database.ProviderDiscord,
+			AvatarURL:     avatarURL,
+			Login:         discordUser.Username,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+			Version:       1,
+		})
+		if err != nil {
+			app.logger.Ctx(r.Context()).Warning(fmt.Sprintf("error inserting linked account: %v", err))
+			redirect("error")
+			return
+		}
+	}
+
+	err = app.sqlModels.Tokens.DeleteAllForUser(r.Context(), database.ScopeDiscordLink, user.ID)
+	if err != nil {
+		app.logger.Ctx(r.Context()).Warning(fmt.Sprintf("error deleting discord link tokens: %v", err))
+	}
+
+	err = app.rewardLinkedAccounts(r.Context(), user)
+	if err != nil {
+		app.logger.Ctx(r.Context()).Warning(fmt.Sprintf("error checking linked account reward: %v", err))
+	}
+
+	redirect("linked")
+}
+
+// getDiscordUser exchanges oauth code and fetches the discord user
+func (app *application) getDiscordUser(ctx context.Context, code string) (*discordgo.User, error) {
+	token, err := app.discordOauthConfig.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("discordOauthConfig.Exchange() failed with '%s'", err)
+	}
+
+	client := app.discordOauthConfig.Client(ctx, token)
+
+	resp, err := client.Get(app.config.Discord.APIURL + "/users/@me")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discord returned status %s", resp.Status)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var discordUser discordgo.User
+
+	err = json.Unmarshal(content, &discordUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return &discordUser, nil
+}
+
+// rewardLinkedAccounts enqueues the reward for linked accounts when the user has two of them
+// and no auth SBT yet, like the github and telegram callbacks
+func (app *application) rewardLinkedAccounts(ctx context.Context, user *database.User) error {
+	hasTwoAccounts, err := app.sqlModels.Users.HasTwoLinkedAccounts(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if !hasTwoAccounts {
+		return nil
+	}
+
+	authNft, err := app.sqlModels.Nfts.GetNFTMetadataByID(ctx, app.config.App.AuthMetadataID)
+	if err != nil {
+		return err
+	}
+
+	hasAuthNft, err := app.sqlModels.Nfts.HasAuthNFT(ctx, user.FriendlyAddress, authNft.Base64)
+	if err != nil {
+		return err
+	}
+
+	if hasAuthNft {
+		return nil
+	}
+
+	payload, err := json.Marshal(user.ID)
+	if err != nil {
+		return err
+	}
+
+	task := tasks.NewTask(ctx, database.TYPE_REWARD_FOR_LINKED_ACCOUNT, payload)
+
+	info, err := app.asynqClient.Enqueue(task, asynq.TaskID(fmt.Sprint("reward_auth", user.ID)), asynq.ProcessIn(5*time.Second), asynq.MaxRetry(5), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_URGENT))
+	switch {
+	case errors.Is(err, asynq.ErrTaskIDConflict):
+		// the reward is already scheduled
+	case err != nil:
+		return err
+	default:
+		app.logger.Ctx(ctx).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+	}
+
+	return nil
+}
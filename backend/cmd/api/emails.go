@@ -0,0 +1,294 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/i18n"
+	"github.com/ton-developer-program/internal/request"
+	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/smtp"
+	"github.com/ton-developer-program/internal/tasks"
+	"github.com/ton-developer-program/internal/validator"
+)
+
+var emailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+
+// verification links are valid for this long
+const emailVerificationTTL = 24 * time.Hour
+
+// getEmailHandler returns email of the user and notification preferences
+func (app *application) getEmailHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	email, err := app.sqlModels.Emails.Get(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, database.ErrRecordNotFound) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	preferences, err := app.sqlModels.Emails.GetPreferences(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{
+		"email":       email,
+		"preferences": preferences,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// updateEmailHandler sets email of the user and sends a verification link to it
+func (app *application) updateEmailHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Email *string `json:"email"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Email == nil {
+		app.badRequest(w, r, errors.New("email is required"))
+		return
+	}
+
+	address := strings.TrimSpace(*input.Email)
+
+	v := validator.Validator{}
+
+	v.CheckField(validator.MaxRunes(address, 254), "email", "must not be more than 254 characters long")
+	v.CheckField(validator.Matches(address, emailRX), "email", "must be a valid email address")
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	email, err := app.sqlModels.Emails.Set(r.Context(), user.ID, address)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !email.Verified {
+		err = app.sendEmailVerification(r, user, email.Email)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{
+		"email": email,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// resendEmailVerificationHandler sends a new verification link to the unverified email
+func (app *application) resendEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	email, err := app.sqlModels.Emails.Get(r.Context(), user.ID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	if email.Verified {
+		app.badRequest(w, r, errors.New("email is already verified"))
+		return
+	}
+
+	err = app.sendEmailVerification(r, user, email.Email)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendEmailVerification replaces previous verification tokens of the user and enqueues the email
+func (app *application) sendEmailVerification(r *http.Request, user *database.User, address string) error {
+	err := app.sqlModels.Tokens.DeleteAllForUser(r.Context(), database.ScopeEmailVerification, user.ID)
+	if err != nil {
+		return err
+	}
+
+	t, err := app.sqlModels.Tokens.New(r.Context(), user.ID, emailVerificationTTL, database.ScopeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	language, err := app.sqlModels.Users.GetLanguage(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(smtp.Email{
+		Recipient: address,
+		Template:  smtp.TemplateEmailVerification,
+		Language:  i18n.Match(language),
+		Data: map[string]any{
+			"Username":  user.Username,
+			"VerifyURL": app.config.App.BaseUrl + "/v1/email/verify?token=" + url.QueryEscape(t.Plaintext),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	task := tasks.NewTask(r.Context(), database.TYPE_SEND_EMAIL, payload)
+
+	info, err := app.asynqClient.Enqueue(task, asynq.MaxRetry(5), asynq.Retention(24*time.Hour), asynq.Queue(database.PRIORITY_URGENT))
+	if err != nil {
+		return err
+	}
+
+	app.logger.Ctx(r.Context()).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+
+	return nil
+}
+
+// verifyEmailHandler is opened from the verification email, the user is redirected
+// to the settings page with the result in the email query param
+func (app *application) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	redirect := func(result string) {
+		http.Redirect(w, r, app.config.App.BaseUrl+"/settings?email="+result, http.StatusTemporaryRedirect)
+	}
+
+	token := r.URL.Query().Get("token")
+
+	v := validator.Validator{}
+
+	if database.ValidateTokenPlaintext(&v, token); v.HasErrors() {
+		redirect("invalid")
+		return
+	}
+
+	user, err := app.sqlModels.Users.GetForToken(r.Context(), database.ScopeEmailVerification, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			redirect("invalid")
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	_, err = app.sqlModels.Emails.Verify(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrRecordNotFound):
+			redirect("invalid")
+		case errors.Is(err, database.ErrDuplicateEmail):
+			redirect("duplicate")
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.sqlModels.Tokens.DeleteAllForUser(r.Context(), database.ScopeEmailVerification, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	redirect("verified")
+}
+
+// deleteEmailHandler removes email of the user, no emails are sent after it
+func (app *application) deleteEmailHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	err := app.sqlModels.Emails.Delete(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.sqlModels.Tokens.DeleteAllForUser(r.Context(), database.ScopeEmailVerification, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateNotificationPreferencesHandler turns email notifications about events on and off
+func (app *application) updateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Preferences map[string]bool `json:"preferences"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if len(input.Preferences) == 0 {
+		app.badRequest(w, r, errors.New("preferences are required"))
+		return
+	}
+
+	v := validator.Validator{}
+
+	for event := range input.Preferences {
+		_, ok := database.NotificationEvents[event]
+		v.CheckField(ok, "preferences."+event, "unknown notification event")
+	}
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	err = app.sqlModels.Emails.UpdatePreferences(r.Context(), user.ID, input.Preferences)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	preferences, err := app.sqlModels.Emails.GetPreferences(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{
+		"preferences": preferences,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
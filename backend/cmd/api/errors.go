@@ -33,7 +33,13 @@ func (app *application) reportError(err error) {
 func (app *application) errorMessage(w http.ResponseWriter, r *http.Request, status int, message string, headers http.Header) {
 	message = strings.ToUpper(message[:1]) + message[1:]
 
-	err := response.JSONWithHeaders(w, status, map[string]string{"Error": message}, headers)
+	data := map[string]string{"Error": message}
+
+	if requestID := app.contextGetRequestID(r); requestID != "" {
+		data["RequestID"] = requestID
+	}
+
+	err := response.JSONWithHeaders(w, status, data, headers)
 	if err != nil {
 		app.reportError(err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -43,6 +49,8 @@ func (app *application) errorMessage(w http.ResponseWriter, r *http.Request, sta
 func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
 	// app.reportError(err)
 
+	app.logger.Ctx(r.Context()).Errorw(err, "method", r.Method, "path", r.URL.Path)
+
 	message := "The server encountered a problem and could not process your request"
 	app.errorMessage(w, r, http.StatusInternalServerError, message, nil)
 }
@@ -59,6 +67,8 @@ func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Requ
 }
 
 func (app *application) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	setRouteLabel(r, "method_not_allowed")
+
 	message := fmt.Sprintf("The %s method is not supported for this resource", r.Method)
 	app.errorMessage(w, r, http.StatusMethodNotAllowed, message, nil)
 }
@@ -69,6 +79,11 @@ func (app *application) editConclictResponse(w http.ResponseWriter, r *http.Requ
 	app.errorMessage(w, r, http.StatusConflict, message, nil)
 }
 
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
+	message := "rate limit exceeded, please retry later"
+	app.errorMessage(w, r, http.StatusTooManyRequests, message, nil)
+}
+
 func (app *application) badRequest(w http.ResponseWriter, r *http.Request, err error) {
 	app.errorMessage(w, r, http.StatusBadRequest, err.Error(), nil)
 }
@@ -77,7 +92,6 @@ func (app *application) failedValidation(w http.ResponseWriter, r *http.Request,
 	err := response.JSON(w, http.StatusUnprocessableEntity, v)
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 	}
 }
 
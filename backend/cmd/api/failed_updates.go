@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexedwards/flow"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/tasks"
+)
+
+// getFailedUpdatesHandler lists updates the bot failed to process, replayed ones only with ?all=true
+func (app *application) getFailedUpdatesHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := getPagination(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	all := r.URL.Query().Get("all") == "true"
+
+	updates, err := app.sqlModels.FailedUpdates.GetPage(r.Context(), pagination, all)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	totalCount, err := app.sqlModels.FailedUpdates.Count(r.Context(), all)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	headers := http.Header{
+		"x-total-count": []string{strconv.FormatInt(totalCount, 10)},
+	}
+
+	response.JSONWithHeaders(w, http.StatusOK, updates, headers)
+}
+
+func (app *application) getFailedUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	update, ok := app.readFailedUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	err := response.JSON(w, http.StatusOK, update)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// replayFailedUpdateHandler asks the bot to process the update again, the result is
+// written to the record
+func (app *application) replayFailedUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	update, ok := app.readFailedUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	if update.ReplayedAt != nil {
+		app.errorMessage(w, r, http.StatusConflict, "the update is already replayed", nil)
+		return
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"id": update.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	task := tasks.NewTask(r.Context(), database.TYPE_TG_REPLAY_UPDATE, payload)
+
+	// one replay of the update at a time, the id is released once the task is done
+	info, err := app.asynqClient.Enqueue(task, asynq.TaskID(fmt.Sprintf("REPLAY_UPDATE_%d", update.ID)), asynq.MaxRetry(0), asynq.Timeout(time.Minute), asynq.Queue(database.QUEUE_BOT))
+	if err != nil {
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			app.errorMessage(w, r, http.StatusConflict, "the update is already being replayed", nil)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logger.Ctx(r.Context()).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+
+	err = response.JSON(w, http.StatusAccepted, map[string]interface{}{
+		"task_id": info.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// readFailedUpdate loads failed update from :id route param, writes error response when it fails
+func (app *application) readFailedUpdate(w http.ResponseWriter, r *http.Request) (*database.FailedUpdate, bool) {
+	id, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return nil, false
+	}
+
+	update, err := app.sqlModels.FailedUpdates.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return nil, false
+		}
+		app.serverError(w, r, err)
+		return nil, false
+	}
+
+	return update, true
+}
@@ -1,12 +1,23 @@
 package main
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/tonconnect"
+	"github.com/tonkeeper/tongo"
 )
 
 func (app *application) newEmailData() map[string]any {
@@ -80,4 +91,118 @@ func getPagination(r *http.Request) (*database.Pagination, error) {
 		End: end,
 	}, nil
 
-}
\ No newline at end of file
+}
+// verifyTonProof checks that the TonConnect proof was signed by the wallet from the proof
+func (app *application) verifyTonProof(ctx context.Context, tp *tonconnect.TonProof) error {
+	// check payload
+	err := tonconnect.CheckPayload(tp.Proof.Payload, app.config.Ton.SharedSecret)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := tonconnect.ConvertTonProofMessage(ctx, tp)
+	if err != nil {
+		return err
+	}
+
+	net := networks[tp.Network]
+	if net == nil {
+		return errors.New("invalid network")
+	}
+
+	addr, err := tongo.ParseAccountID(tp.Address)
+	if err != nil {
+		return errors.New("invalid address")
+	}
+
+	check, err := tonconnect.CheckProof(ctx, addr, net, parsed, app.config.App.DomainName)
+	if err != nil {
+		return err
+	}
+
+	if !check {
+		return errors.New("proof verification failed")
+	}
+
+	return nil
+}
+
+// telegram login widget data is accepted for linking for a day, recovery takes only fresh data
+const (
+	telegramAuthMaxAge         = 24 * time.Hour
+	telegramRecoveryAuthMaxAge = 5 * time.Minute
+)
+
+// checkTelegramAuthData checks the hash and the age of the telegram login widget data
+func (app *application) checkTelegramAuthData(authData AuthData, maxAge time.Duration) error {
+	dataCheckArr := []string{}
+	dataCheckArr = append(dataCheckArr, fmt.Sprintf("auth_date=%d", authData.AuthDate))
+
+	if authData.FirstName != "" {
+		dataCheckArr = append(dataCheckArr, fmt.Sprintf("first_name=%s", authData.FirstName))
+	}
+
+	if authData.LastName != "" {
+		dataCheckArr = append(dataCheckArr, fmt.Sprintf("last_name=%s", authData.LastName))
+	}
+
+	dataCheckArr = append(dataCheckArr, fmt.Sprintf("id=%d", authData.ID))
+
+	if authData.PhotoURL != "" {
+		dataCheckArr = append(dataCheckArr, fmt.Sprintf("photo_url=%s", authData.PhotoURL))
+	}
+
+	if authData.Username != "" {
+		dataCheckArr = append(dataCheckArr, fmt.Sprintf("username=%s", authData.Username))
+	}
+
+	sort.Strings(dataCheckArr)
+	dataCheckString := strings.Join(dataCheckArr, "\n")
+
+	secretKey := sha256.Sum256([]byte(app.config.Auth.TelegramBotToken))
+
+	h := hmac.New(sha256.New, secretKey[:])
+	h.Write([]byte(dataCheckString))
+
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	if !hmac.Equal([]byte(hash), []byte(authData.Hash)) {
+		app.logger.Warningw("telegram auth data hash doesn't match", "telegram_user_id", authData.ID)
+		return errors.New("Data is NOT from Telegram")
+	}
+
+	if age := time.Since(time.Unix(authData.AuthDate, 0)); age > maxAge {
+		app.logger.Warningw("telegram auth data is outdated", "telegram_user_id", authData.ID, "age", age.String())
+		return errors.New("Data is outdated")
+	}
+
+	return nil
+}
+
+// useTelegramAuthData marks the widget data used, false when it was already used. The mark
+// lives as long as the data is accepted, so every payload works only once.
+func (app *application) useTelegramAuthData(ctx context.Context, authData AuthData, maxAge time.Duration) (bool, error) {
+	return app.redisClient.SetNX(ctx, "telegram-auth:"+authData.Hash, authData.ID, maxAge).Result()
+}
+
+// decodeTelegramAuthObj decodes base64 encoded json from the telegram login widget
+func decodeTelegramAuthObj(authObj string) (AuthData, error) {
+	var authData AuthData
+
+	decoded, err := base64.RawStdEncoding.DecodeString(authObj)
+	if err != nil {
+		return authData, err
+	}
+
+	err = json.Unmarshal(decoded, &authData)
+	return authData, err
+}
+
+// getUserRoles returns roles of the user, service accounts carry their roles from authentication
+func (app *application) getUserRoles(ctx context.Context, user *database.User) (database.Roles, error) {
+	if user.IsServiceAccount() {
+		return user.Roles, nil
+	}
+
+	return app.sqlModels.Permissions.GetUserRoles(ctx, user.ID)
+}
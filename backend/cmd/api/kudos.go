@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alexedwards/flow"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/kudos"
+	"github.com/ton-developer-program/internal/request"
+	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/validator"
+)
+
+// giveKudosHandler thanks the user with the username, like /thanks in community chats
+func (app *application) giveKudosHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Username string `json:"username"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	v := validator.Validator{}
+
+	v.CheckField(validator.NotBlank(input.Username), "username", "must be provided")
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	// wallets cost nothing to create, like in community chats only users with a linked
+	// account give kudos
+	accounts, err := app.sqlModels.Users.GetLinkedAccounts(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if len(accounts) == 0 {
+		app.errorMessage(w, r, http.StatusForbidden, "link a Telegram, Discord or GitHub account to give kudos", nil)
+		return
+	}
+
+	receiver, err := app.sqlModels.Users.GetByUsername(r.Context(), input.Username)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if receiver == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	given := &database.Kudos{
+		GiverID:          user.ID,
+		GiverUsername:    user.Username,
+		ReceiverID:       receiver.ID,
+		ReceiverUsername: receiver.Username,
+		Source:           database.KudosSourceAPI,
+	}
+
+	err = app.sqlModels.Kudos.Give(r.Context(), given, kudos.Policy(app.config.Kudos))
+	switch {
+	case errors.Is(err, database.ErrKudosSelf):
+		v.AddFieldError("username", "must not be your own username")
+		app.failedValidation(w, r, v)
+		return
+	case errors.Is(err, database.ErrKudosAlreadyGiven):
+		app.errorMessage(w, r, http.StatusConflict, "you already thanked this user today", nil)
+		return
+	case errors.Is(err, database.ErrKudosDailyLimit):
+		app.errorMessage(w, r, http.StatusTooManyRequests, fmt.Sprintf("you can give %d kudos a day", app.config.Kudos.DailyLimit), nil)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	info, err := kudos.CheckRatingRewards(r.Context(), app.asynqClient, given)
+	switch {
+	case err != nil:
+		app.logger.Ctx(r.Context()).Warningw("error enqueuing rating rewards check", "error", err)
+	case info != nil:
+		app.logger.Ctx(r.Context()).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+	}
+
+	err = response.JSON(w, http.StatusCreated, given)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// getKudosListHandler lists kudos, only reciprocal ones with ?reciprocal=true
+func (app *application) getKudosListHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := getPagination(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	reciprocal := r.URL.Query().Get("reciprocal") == "true"
+
+	kudos, err := app.sqlModels.Kudos.GetPage(r.Context(), pagination, reciprocal)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	totalCount, err := app.sqlModels.Kudos.Count(r.Context(), reciprocal)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	headers := http.Header{
+		"x-total-count": []string{strconv.FormatInt(totalCount, 10)},
+	}
+
+	response.JSONWithHeaders(w, http.StatusOK, kudos, headers)
+}
+
+func (app *application) getKudosHandler(w http.ResponseWriter, r *http.Request) {
+	kudos, ok := app.readKudos(w, r)
+	if !ok {
+		return
+	}
+
+	err := response.JSON(w, http.StatusOK, kudos)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// deleteKudosHandler revokes farmed kudos, the weight is taken back from the receiver's rating
+func (app *application) deleteKudosHandler(w http.ResponseWriter, r *http.Request) {
+	kudos, ok := app.readKudos(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.sqlModels.Kudos.Delete(r.Context(), kudos.ID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]string{"message": "Kudos deleted successfully"})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) readKudos(w http.ResponseWriter, r *http.Request) (*database.Kudos, bool) {
+	id, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return nil, false
+	}
+
+	kudos, err := app.sqlModels.Kudos.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return nil, false
+		}
+		app.serverError(w, r, err)
+		return nil, false
+	}
+
+	return kudos, true
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
 	"flag"
 	"fmt"
 	"log"
@@ -10,12 +12,17 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/hibiken/asynq"
 	"github.com/hibiken/asynqmon"
 	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/health"
 	"github.com/ton-developer-program/internal/leveledlog"
+	"github.com/ton-developer-program/internal/metrics"
 	"github.com/ton-developer-program/internal/smtp"
+	"github.com/ton-developer-program/internal/tasks"
 	"github.com/ton-developer-program/internal/tonconnect"
+	"github.com/ton-developer-program/internal/tracing"
 	"github.com/ton-developer-program/internal/version"
 	"github.com/ton-developer-program/util"
 	"github.com/tonkeeper/tongo/liteapi"
@@ -38,16 +45,22 @@ func main() {
 }
 
 type application struct {
-	config            util.Config
-	sqlModels         database.Models
-	logger            *leveledlog.Logger
-	mailer            *smtp.Mailer
-	wg                sync.WaitGroup
-	asynqClient       *asynq.Client
-	asynqScheduler    *asynq.Scheduler
-	tonLiteClient     *ton.APIClient
-	githubOauthConfig *oauth2.Config
-	oauthStateString  string
+	config             util.Config
+	sqlModels          database.Models
+	logger             *leveledlog.Logger
+	mailer             *smtp.Mailer
+	wg                 sync.WaitGroup
+	asynqClient        *asynq.Client
+	asynqScheduler     *asynq.Scheduler
+	tonLiteClient      *ton.APIClient
+	githubOauthConfig  *oauth2.Config
+	discordOauthConfig *oauth2.Config
+	oauthStateString   string
+	attestationKey     ed25519.PrivateKey
+	redisClient        *redis.Client
+	rateLimiter        rateLimiter
+	rateLimitPolicies  map[string]rateLimitPolicy
+	health             *health.Checker
 }
 
 func run(logger *leveledlog.Logger) error {
@@ -69,6 +82,10 @@ func run(logger *leveledlog.Logger) error {
 		return err
 	}
 
+	if cfg.Log.Format == "json" {
+		logger = leveledlog.NewJSONLogger(os.Stdout, leveledlog.LevelAll)
+	}
+
 	showVersion := flag.Bool("version", false, "display version and exit")
 
 	flag.Parse()
@@ -78,6 +95,23 @@ func run(logger *leveledlog.Logger) error {
 		return nil
 	}
 
+	shutdownTracing, err := tracing.Init(tracing.Config{
+		Service:     "api",
+		Version:     version.Get(),
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		shutdownTracing(ctx)
+	}()
+
 	db, err := database.New(cfg.Database.Dsn, cfg.Database.Automigrate)
 	if err != nil {
 		return err
@@ -98,6 +132,18 @@ func run(logger *leveledlog.Logger) error {
 		},
 	)
 
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		PoolSize: cfg.Redis.PoolSize,
+	})
+	defer redisClient.Close()
+
+	rateLimitPolicies, err := parseRateLimitPolicies(cfg.RateLimit.Policies)
+	if err != nil {
+		return err
+	}
+
 	// initialize scheduler
 
 	loc, err := time.LoadLocation("UTC")
@@ -124,6 +170,26 @@ func run(logger *leveledlog.Logger) error {
 		Endpoint:     github.Endpoint,
 	}
 
+	discordOauthConfig := &oauth2.Config{
+		RedirectURL:  cfg.Auth.DiscordRedirectUrl,
+		ClientID:     cfg.Auth.DiscordClientId,
+		ClientSecret: cfg.Auth.DiscordClientSecret,
+		Scopes:       []string{"identify"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://discord.com/oauth2/authorize",
+			TokenURL: cfg.Discord.APIURL + "/oauth2/token",
+		},
+	}
+
+	attestationKey, generated, err := loadAttestationKey(cfg.App.AttestationKey, cfg.App.Env)
+	if err != nil {
+		return err
+	}
+
+	if generated {
+		logger.Warning("APP_ATTESTATION_KEY is not set, attestations are signed with a temporary key")
+	}
+
 	// instantiate application
 
 	connectionPool := liteclient.NewConnectionPool()
@@ -153,16 +219,70 @@ func run(logger *leveledlog.Logger) error {
 	}()
 
 	app := &application{
-		config:            cfg,
-		sqlModels:         database.NewModels(db.DB),
-		logger:            logger,
-		mailer:            mailer,
-		asynqClient:       asynqClient,
-		tonLiteClient:     tonLiteClient,
-		asynqScheduler:    asynqScheduler,
-		githubOauthConfig: githubOauthConfig,
-		oauthStateString:  "erbEKBi3w4oirewbikjewrbuio2wkwsvjeierorbbre",
+		config:             cfg,
+		sqlModels:          database.NewModels(db.DB),
+		logger:             logger,
+		mailer:             mailer,
+		asynqClient:        asynqClient,
+		tonLiteClient:      tonLiteClient,
+		asynqScheduler:     asynqScheduler,
+		githubOauthConfig:  githubOauthConfig,
+		discordOauthConfig: discordOauthConfig,
+		oauthStateString:   "erbEKBi3w4oirewbikjewrbuio2wkwsvjeierorbbre",
+		attestationKey:     attestationKey,
+		redisClient:        redisClient,
+		rateLimitPolicies:  rateLimitPolicies,
+		health: health.NewChecker("api", version.Get(),
+			health.Postgres(db.DB),
+			health.Redis(redisClient),
+			// every replica uses the same liteservers, their outage must not take the api out of the load balancer
+			health.NonCritical(health.Liteserver(tonLiteClient)),
+			health.AdminWallet(tonLiteClient, cfg.Ton.AdminWallet, cfg.Health.MinWalletBalanceTON),
+		),
 	}
 
+	app.rateLimiter = app.newRateLimiter(redisClient)
+
+	// unique option keeps one digest per week when several api replicas run the scheduler
+	_, err = asynqScheduler.Register(cfg.Notifications.DigestCron,
+		tasks.NewTask(context.Background(), database.TYPE_SEND_WEEKLY_DIGEST, nil),
+		asynq.Queue(database.PRIORITY_LOW), asynq.Unique(time.Hour))
+	if err != nil {
+		return err
+	}
+
+	// posted by the bot to chats with digest enabled
+	_, err = asynqScheduler.Register(cfg.Bot.DigestCron,
+		tasks.NewTask(context.Background(), database.TYPE_TG_CHAT_DIGEST, nil),
+		asynq.Queue(database.QUEUE_BOT), asynq.Unique(time.Hour))
+	if err != nil {
+		return err
+	}
+
+	_, err = asynqScheduler.Register("0 3 * * *",
+		tasks.NewTask(context.Background(), database.TYPE_CLEANUP_NOTIFICATIONS, nil),
+		asynq.Queue(database.PRIORITY_LOW), asynq.Unique(time.Hour))
+	if err != nil {
+		return err
+	}
+
+	err = asynqScheduler.Start()
+	if err != nil {
+		return err
+	}
+	defer asynqScheduler.Shutdown()
+
+	metrics.BuildInfo.WithLabelValues("api", version.Get()).Set(1)
+
+	metricsMux := http.NewServeMux()
+	app.health.Register(metricsMux)
+
+	go func() {
+		err := metrics.Serve(cfg.Metrics.Addr, metricsMux)
+		if err != nil {
+			logger.Error(fmt.Errorf("metrics server: %w", err), nil)
+		}
+	}()
+
 	return app.serveHTTP()
 }
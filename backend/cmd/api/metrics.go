@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/flow"
+	"github.com/ton-developer-program/internal/metrics"
+)
+
+// route params used in patterns, values are replaced back with :name to keep the route label bounded
+var routeParamNames = []string{"id", "grant_id", "key_id", "username", "base64", "provider"}
+
+type metricsContextKey struct{}
+
+// metricsRecorder keeps status code for the request counter
+type metricsRecorder struct {
+	http.ResponseWriter
+	status int
+	// route is a fixed label for requests without a route pattern
+	route string
+}
+
+func (rec *metricsRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// routePattern restores route pattern from the path and route params
+func routePattern(r *http.Request) string {
+	segments := strings.Split(r.URL.Path, "/")
+	replaced := make([]bool, len(segments))
+
+	for _, name := range routeParamNames {
+		value := flow.Param(r.Context(), name)
+		if value == "" {
+			continue
+		}
+
+		for i, segment := range segments {
+			if !replaced[i] && segment == value {
+				segments[i] = ":" + name
+				replaced[i] = true
+				break
+			}
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// instrument counts requests and measures latency by route pattern
+func (app *application) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		rec := &metricsRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		r = r.WithContext(context.WithValue(r.Context(), metricsContextKey{}, rec))
+
+		next.ServeHTTP(rec, r)
+
+		route := requestRoute(r)
+
+		metrics.HTTPRequests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// setRouteLabel labels the request with a fixed route, the router doesn't set route params
+// for unknown paths, unsupported methods and OPTIONS, so their raw paths can't be labels
+func setRouteLabel(r *http.Request, route string) {
+	if rec, ok := r.Context().Value(metricsContextKey{}).(*metricsRecorder); ok {
+		rec.route = route
+	}
+}
+
+// requestRoute returns the label set with setRouteLabel or the route pattern
+func requestRoute(r *http.Request) string {
+	if rec, ok := r.Context().Value(metricsContextKey{}).(*metricsRecorder); ok && rec.route != "" {
+		return rec.route
+	}
+
+	return routePattern(r)
+}
+
+// routeNotFound marks the request as not matching any route, so unknown paths share one label
+func (app *application) routeNotFound(w http.ResponseWriter, r *http.Request) {
+	setRouteLabel(r, "unmatched")
+
+	app.notFound(w, r)
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/ton-developer-program/internal/database"
 )
 
@@ -24,6 +27,23 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 }
 
 
+// requestID takes request id from X-Request-Id header or generates a new one
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-Id")
+
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.NewString()
+		}
+
+		w.Header().Set("X-Request-Id", requestID)
+
+		r = app.contextSetRequestID(r, requestID)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) NoDirListingHandler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if strings.HasSuffix(r.URL.Path, "/") {
@@ -36,13 +56,95 @@ func (app *application) NoDirListingHandler(h http.Handler) http.Handler {
 
 
 
+// headers readable by browser clients from cross-origin responses
+var corsExposedHeaders = strings.Join([]string{
+	"X-Total-Count",
+	"X-Request-Id",
+	"RateLimit-Policy",
+	"RateLimit-Limit",
+	"RateLimit-Remaining",
+	"RateLimit-Reset",
+	"Retry-After",
+}, ", ")
+
+var corsAllowedHeaders = strings.Join([]string{
+	"Authorization",
+	"Content-Type",
+	"X-Request-Id",
+	database.ApiKeyHeader,
+}, ", ")
+
+// corsOrigin returns value for Access-Control-Allow-Origin, empty when the origin is not allowed.
+// Admin routes accept only the admin app origins, other routes accept both as the admin app logs in through them.
+func (app *application) corsOrigin(r *http.Request, origin string) string {
+	origins := app.config.CORS.AdminOrigins
+	if !strings.HasPrefix(r.URL.Path, "/v1/admin/") {
+		origins = append(origins[:len(origins):len(origins)], app.config.CORS.PublicOrigins...)
+	}
+
+	wildcard := false
+
+	for _, allowed := range origins {
+		if allowed == origin {
+			return origin
+		}
+
+		if allowed == "*" {
+			wildcard = true
+		}
+	}
+
+	// any origin gets "*", which browsers never send credentials with, the origin itself is
+	// reflected only when it's listed
+	if wildcard {
+		return "*"
+	}
+
+	return ""
+}
+
+// enableCORS answers preflight requests before authentication and rate limiting
 func (app *application) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "*")
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions {
+			setRouteLabel(r, "options")
+		}
+
+		origin := r.Header.Get("Origin")
+		allowedOrigin := ""
 
-		if r.Method == "OPTIONS" {
+		if origin != "" {
+			allowedOrigin = app.corsOrigin(r, origin)
+		}
+
+		if allowedOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+
+			if app.config.CORS.AllowCredentials && allowedOrigin != "*" {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+			if allowedOrigin != "" {
+				// Allow is set by the router from the methods registered for the path
+				methods := w.Header().Get("Allow")
+				if methods == "" {
+					methods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+				}
+
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(app.config.CORS.MaxAgeSec))
+			}
+
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 
@@ -50,9 +152,44 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 	})
 }
 
+// secureHeaders sets security headers for json api responses
+func (app *application) secureHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+		w.Header().Set("Cross-Origin-Opener-Policy", "same-origin")
+
+		if app.config.CORS.HSTS {
+			w.Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Authorization")
+		w.Header().Add("Vary", database.ApiKeyHeader)
+
+		if apiKey := r.Header.Get(database.ApiKeyHeader); apiKey != "" {
+			user, err := app.authenticateApiKey(r.Context(), apiKey)
+			if err != nil {
+				switch {
+				case errors.Is(err, database.ErrRecordNotFound):
+					app.invalidAuthenticationToken(w, r)
+				default:
+					app.serverError(w, r, err)
+				}
+				return
+			}
+
+			r = app.contextSetUser(r, user)
+			next.ServeHTTP(w, r)
+			return
+		}
 
 		authorizationHeader := r.Header.Get("Authorization")
 
@@ -75,23 +212,21 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			}
 
 
-			user, err := app.sqlModels.Users.GetForToken(database.ScopeAuthentication, tokenString)
+			user, err := app.sqlModels.Users.GetForToken(r.Context(), database.ScopeAuthentication, tokenString)
 			if err != nil {
 				switch {
 				case errors.Is(err, sql.ErrNoRows):
 					app.notPermittedResponse(w, r)
 				default:
 					app.serverError(w, r, err) 
-					app.logger.Error(err,nil)
 				}
 				return
 			}
 
 			// get user permissions
-			permissions, err := app.sqlModels.Users.GetAllPermissions(user.ID)
+			permissions, err := app.sqlModels.Users.GetAllPermissions(r.Context(), user.ID)
 			if err != nil {
 				app.serverError(w, r, err) 
-				app.logger.Error(err,nil)
 				return
 			}
 
@@ -106,6 +241,29 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// authenticateApiKey returns service account as a user without wallet, permissions come from its roles
+func (app *application) authenticateApiKey(ctx context.Context, apiKey string) (*database.User, error) {
+	account, err := app.sqlModels.ServiceAccounts.GetForApiKey(ctx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := app.sqlModels.ServiceAccounts.GetPermissions(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &database.User{
+		Username:       account.Name,
+		Permissions:    permissions,
+		ServiceAccount: account,
+	}
+
+	user.SetRoles(account.Roles)
+
+	return user, nil
+}
+
 func (app *application) requireAuthenticatedUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
@@ -119,77 +277,73 @@ func (app *application) requireAuthenticatedUser(next http.Handler) http.Handler
 	})
 }
 
-
-func (app *application) requirePermission(next http.Handler) http.Handler {
-	fn := func(w http.ResponseWriter, r *http.Request) {
+// requireWalletUser rejects service accounts on routes that act on the wallet owner's own account
+func (app *application) requireWalletUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
 
-		if user == nil {
-			app.authenticationRequired(w, r)
+		if user.IsAnonymous() || user.IsServiceAccount() {
+			app.notPermittedResponse(w, r)
 			return
 		}
 
-		// Split the URL path into segments.
-		pathSegments := strings.Split(r.URL.Path, "/")
-		if len(pathSegments) < 3 {
-			http.Error(w, "Invalid route", http.StatusNotFound)
-			return
-		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		// if path segments contain "admin" then check if user is admin
-		if !contains(pathSegments, "admin") {
-			next.ServeHTTP(w, r)
-			return
-		}
-		
-		// The second segment should be the resource (like "rewards").
-		resource := pathSegments[3]
-
-		// Map HTTP methods to permission actions.
-		actionMap := map[string]string{
-			"GET":    "read",
-			"POST":   "create",
-			"PUT":    "edit",
-			"PATCH":  "edit",
-			"DELETE": "delete",
-		}
-		// Get the action for this HTTP method.
-		action, exists := actionMap[r.Method]
-		if !exists {
-			app.methodNotAllowed(w, r)
-			return
+// requirePermission allows the request when the user has the permission declared for the route
+// on all resources, permission granted on a single resource doesn't pass.
+func (app *application) requirePermission(permission string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		for _, p := range user.Permissions {
+			if p.Name == permission && p.ResourceType == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
 		}
 
-		// Construct the required permission.
-		requiredPermission := "permissions:" + resource + "-" + action
+		app.notPermittedResponse(w, r)
+	}
 
-		// Check if the user has this permission.
-		hasPermission := false
-		
-		for _, permission := range user.Permissions {
+	return app.requireAuthenticatedUser(http.HandlerFunc(fn)).ServeHTTP
+}
+
+// requireScopedPermission is requirePermission for routes of database.ScopedPermissions.
+// Permission granted on a single resource is enough to pass, the handler must check the resource with userCan.
+func (app *application) requireScopedPermission(permission string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
 
-			if permission.Name == requiredPermission {
-				hasPermission = true
-				break
+		for _, p := range user.Permissions {
+			if p.Name == permission {
+				next.ServeHTTP(w, r)
+				return
 			}
 		}
 
-		if !hasPermission {
-			app.notPermittedResponse(w, r)
-			return
+		app.notPermittedResponse(w, r)
+	}
+
+	return app.requireAuthenticatedUser(http.HandlerFunc(fn)).ServeHTTP
+}
+
+// userCan reports whether the user has the permission for all resources or for the given one
+func userCan(user *database.User, permission, resourceType string, resourceID int64) bool {
+	for _, p := range user.Permissions {
+		if p.Name != permission {
+			continue
 		}
 
-		next.ServeHTTP(w, r)
+		if p.ResourceType == nil {
+			return true
+		}
 
+		if *p.ResourceType == resourceType && p.ResourceID != nil && *p.ResourceID == resourceID {
+			return true
+		}
 	}
-	return http.HandlerFunc(fn)
-}
 
-func contains(s []string, e string) bool {
-	for _, v := range s {
-			if v == e {
-					return true
-			}
-	}
 	return false
-}
\ No newline at end of file
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/ton-developer-program/internal/database"
 	"github.com/ton-developer-program/internal/request"
 	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/tasks"
 	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/tlb"
 	"github.com/xssnick/tonutils-go/ton/nft"
@@ -67,7 +68,7 @@ func (app *application) insertExistingCollectionHandler(w http.ResponseWriter, r
 		return
 	}
 
-	runGetCollection := asynq.NewTask(database.TYPE_ADD_COLLECTION, payload)
+	runGetCollection := tasks.NewTask(r.Context(), database.TYPE_ADD_COLLECTION, payload)
 
 	info, err := app.asynqClient.Enqueue(runGetCollection, asynq.ProcessIn(20*time.Second), asynq.MaxRetry(5), asynq.ProcessIn(5*time.Second), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_URGENT))
 	if err != nil {
@@ -75,7 +76,7 @@ func (app *application) insertExistingCollectionHandler(w http.ResponseWriter, r
 		return
 	}
 
-	app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))
+	app.logger.Ctx(r.Context()).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
 
 	response.JSON(w, http.StatusOK, map[string]interface{}{
 		"status": "ok",
@@ -103,32 +104,24 @@ func (app *application) insertCollectionHandler(w http.ResponseWriter, r *http.R
 
 	codeCellBytesCollection, err := hex.DecodeString(COLLECTION_CONTRACT)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
 	codeCellCollection, err := cell.FromBOC(codeCellBytesCollection)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 	nftItemCellBocBytes, err := hex.DecodeString(NFT_CONTRACT)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
 	nftItemCell, err := cell.FromBOC(nftItemCellBocBytes)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -148,9 +141,7 @@ func (app *application) insertCollectionHandler(w http.ResponseWriter, r *http.R
 	collectionContent := nft.ContentOffchain{URI: app.config.App.BaseUrl + DEPLOYED_COLLECTION_POSTFIX + base64Data + "/meta.json"}
 	collectionContentCell, err := collectionContent.ContentCell()
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -173,11 +164,9 @@ func (app *application) insertCollectionHandler(w http.ResponseWriter, r *http.R
 	}
 
 	// insert meta json to db
-	err = app.sqlModels.Nfts.InsertCollectionMetadata(metaJson)
+	err = app.sqlModels.Nfts.InsertCollectionMetadata(r.Context(), metaJson)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -204,9 +193,7 @@ func (app *application) insertCollectionHandler(w http.ResponseWriter, r *http.R
 
 	stateCell, err := tlb.ToCell(state)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -230,7 +217,7 @@ func (app *application) insertCollectionHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	runGetCollection := asynq.NewTask(database.TYPE_ADD_COLLECTION, payload)
+	runGetCollection := tasks.NewTask(r.Context(), database.TYPE_ADD_COLLECTION, payload)
 
 	info, err := app.asynqClient.Enqueue(runGetCollection, asynq.ProcessIn(20*time.Second), asynq.MaxRetry(5), asynq.ProcessIn(5*time.Second), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_URGENT))
 	if err != nil {
@@ -238,7 +225,7 @@ func (app *application) insertCollectionHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))
+	app.logger.Ctx(r.Context()).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
 
 	// url safe base64
 
@@ -266,17 +253,20 @@ func (app *application) getCollectionsHandler(w http.ResponseWriter, r *http.Req
 
 	// get role by user 
 
-	role, err := app.sqlModels.Permissions.GetUserRoles(user.ID)
-	
+	roles, err := app.getUserRoles(r.Context(), user)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
-	if role.Name == "admin" {
-		collections, err = app.sqlModels.Nfts.GetAllCollections(pagination)
+	if roles.Has("admin") {
+		collections, err = app.sqlModels.Nfts.GetAllCollections(r.Context(), pagination)
 		if err != nil {
 			app.logger.Error(err, nil)
 			return
 		}
 	} else {
-		collections, err = app.sqlModels.Nfts.GetCollectionsByOwnerAddress(pagination, user.FriendlyAddress)
+		collections, err = app.sqlModels.Nfts.GetCollectionsByOwnerAddress(r.Context(), pagination, user.FriendlyAddress)
 		if err != nil {
 			app.logger.Error(err, nil)
 			return
@@ -304,19 +294,23 @@ func (app *application) getTokensHandler(w http.ResponseWriter, r *http.Request)
 
 
 	
-	role, err := app.sqlModels.Permissions.GetUserRoles(user.ID)
+	roles, err := app.getUserRoles(r.Context(), user)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
 	var tokens []*database.SBTToken
 
-	if role.Name == "admin" {
-		tokens, err = app.sqlModels.Nfts.GetTokens(pagination, "")
+	if roles.Has("admin") {
+		tokens, err = app.sqlModels.Nfts.GetTokens(r.Context(), pagination, "")
 		if err != nil {
 			app.logger.Error(err, nil)
 			return
 		}
 	} else {
 
-		tokens, err = app.sqlModels.Nfts.GetTokens(pagination, user.FriendlyAddress)
+		tokens, err = app.sqlModels.Nfts.GetTokens(r.Context(), pagination, user.FriendlyAddress)
 		if err != nil {
 			app.logger.Error(err, nil)
 			return
@@ -325,7 +319,7 @@ func (app *application) getTokensHandler(w http.ResponseWriter, r *http.Request)
 
 
 
-	totalCount, err := app.sqlModels.Nfts.GetTotal(database.SBT_TOKENS_TABLE, "*", "")
+	totalCount, err := app.sqlModels.Nfts.GetTotal(r.Context(), database.SBT_TOKENS_TABLE, "*", "")
 	if err != nil {
 		app.logger.Error(err, nil)
 		return
@@ -334,7 +328,6 @@ func (app *application) getTokensHandler(w http.ResponseWriter, r *http.Request)
 	// could be added to response
 	headers := http.Header{
 		"x-total-count":                 []string{strconv.Itoa(totalCount)},
-		"Access-Control-Expose-Headers": []string{"X-Total-Count"},
 	}
 
 	response.JSONWithHeaders(w, http.StatusOK, tokens, headers)
@@ -357,7 +350,7 @@ func (app *application) getPrototypeTokensHandler(w http.ResponseWriter, r *http
 		filter = fmt.Sprintf("WHERE %s", strings.Join(filters, " OR "))
 	}
 
-	tokens, err := app.sqlModels.Nfts.GetPrototypes(pagination, filter, "id DESC")
+	tokens, err := app.sqlModels.Nfts.GetPrototypes(r.Context(), pagination, filter, "id DESC")
 	if err != nil {
 		app.logger.Error(err, nil)
 		return
@@ -375,7 +368,7 @@ func (app *application) getPrototypeTokensHandler(w http.ResponseWriter, r *http
 
 	// get metadata
 	for i, token := range tokens {
-		metadata, err := app.sqlModels.Nfts.GetNFTMetadataByID(token.ID)
+		metadata, err := app.sqlModels.Nfts.GetNFTMetadataByID(r.Context(), token.ID)
 		if err != nil {
 			app.logger.Error(err, nil)
 			return
@@ -390,7 +383,7 @@ func (app *application) getPrototypeTokensHandler(w http.ResponseWriter, r *http
 		resPrototype[i].Base64 = metadata.Base64
 	}
 
-	totalCount, err := app.sqlModels.Nfts.GetTotal(database.SBT_PROTOTYPE_TABLE, "*", "")
+	totalCount, err := app.sqlModels.Nfts.GetTotal(r.Context(), database.SBT_PROTOTYPE_TABLE, "*", "")
 	if err != nil {
 		app.logger.Error(err, nil)
 		return
@@ -399,7 +392,6 @@ func (app *application) getPrototypeTokensHandler(w http.ResponseWriter, r *http
 	// could be added to response
 	headers := http.Header{
 		"x-total-count":                 []string{strconv.Itoa(totalCount)},
-		"Access-Control-Expose-Headers": []string{"X-Total-Count"},
 	}
 
 	response.JSONWithHeaders(w, http.StatusOK, resPrototype, headers)
@@ -409,16 +401,14 @@ func (app *application) getMetaJsonNft(w http.ResponseWriter, r *http.Request) {
 	base64String := flow.Param(r.Context(), "base64")
 
 	// get meta json by hash
-	metaJson, err := app.sqlModels.Nfts.GetNFTMetadataByBase64(base64String)
+	metaJson, err := app.sqlModels.Nfts.GetNFTMetadataByBase64(r.Context(), base64String)
 	if err != nil {
 
 		if err == sql.ErrNoRows {
 			app.notFound(w, r)
 			return
 		}
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -437,7 +427,7 @@ func (app *application) getMetaJsonCollection(w http.ResponseWriter, r *http.Req
 	base64String := flow.Param(r.Context(), "base64")
 
 	// get meta json by hash
-	metaJson, err := app.sqlModels.Nfts.GetCollectionMetadataByBase64(base64String)
+	metaJson, err := app.sqlModels.Nfts.GetCollectionMetadataByBase64(r.Context(), base64String)
 	if err != nil {
 
 		if err == sql.ErrNoRows {
@@ -445,9 +435,7 @@ func (app *application) getMetaJsonCollection(w http.ResponseWriter, r *http.Req
 			return
 		}
 
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -490,6 +478,22 @@ func (app *application) mintHandler(w http.ResponseWriter, r *http.Request) {
 		app.badRequest(w, r, err)
 		return
 	}
+
+	// minting can be granted for a single collection
+	collectionDB, err := app.sqlModels.Nfts.GetCollectionByAddress(r.Context(), input.CollectionFriendlyAddress)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			app.badRequest(w, r, errors.New("collection not found"))
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !userCan(app.contextGetUser(r), "permissions:minted-nfts-create", database.ResourceCollection, collectionDB.ID) {
+		app.notPermittedResponse(w, r)
+		return
+	}
 	
 
 	collectionAddr := address.MustParseAddr(input.CollectionFriendlyAddress)
@@ -497,28 +501,22 @@ func (app *application) mintHandler(w http.ResponseWriter, r *http.Request) {
 
 	collectionData, err := collection.GetCollectionData(context.Background())
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
 	// convert to int64
 	// get meta json by base64
-	metaJson, err := app.sqlModels.Nfts.GetNFTMetadataByID(metaJsonID)
+	metaJson, err := app.sqlModels.Nfts.GetNFTMetadataByID(r.Context(), metaJsonID)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
 	// // insert meta json to db
-	err = app.sqlModels.Nfts.UpdateAttributesMetadata(metaJson)
+	err = app.sqlModels.Nfts.UpdateAttributesMetadata(r.Context(), metaJson)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -537,9 +535,7 @@ func (app *application) mintHandler(w http.ResponseWriter, r *http.Request) {
 		// make request to csv file
 		resp, err := http.Get(input.CSVFile[0].Response.URL)
 		if err != nil {
-			app.logger.Error(err, nil)
 			app.serverError(w, r, err) 
-			 app.logger.Error(err,nil)
 			return
 		}
 
@@ -553,9 +549,7 @@ func (app *application) mintHandler(w http.ResponseWriter, r *http.Request) {
 		// read all lines
 		lines, err := reader.ReadAll()
 		if err != nil {
-			app.logger.Error(err, nil)
 			app.serverError(w, r, err) 
-			 app.logger.Error(err,nil)
 			return
 		}
 
@@ -619,11 +613,9 @@ func (app *application) mintHandler(w http.ResponseWriter, r *http.Request) {
 
 	// get user byt username
 
-	userforMint, err := app.sqlModels.Users.GetByUsername(*input.UserFriendlyAddress)
+	userforMint, err := app.sqlModels.Users.GetByUsername(r.Context(), *input.UserFriendlyAddress)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -667,9 +659,7 @@ func (app *application) insertPrototypeNft(w http.ResponseWriter, r *http.Reques
 	err := request.DecodeJSON(w, r, &input)
 
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -694,9 +684,7 @@ func (app *application) insertPrototypeNft(w http.ResponseWriter, r *http.Reques
 	if input.Weight != "" {
 		weight, err = strconv.ParseInt(input.Weight, 10, 64)
 		if err != nil {
-			app.logger.Error(err, nil)
 			app.serverError(w, r, err) 
-			 app.logger.Error(err,nil)
 			return
 		}
 	}
@@ -718,11 +706,9 @@ func (app *application) insertPrototypeNft(w http.ResponseWriter, r *http.Reques
 	}
 
 	// // insert meta json to db
-	err = app.sqlModels.Nfts.InsertNFTMetadata(metaJson)
+	err = app.sqlModels.Nfts.InsertNFTMetadata(r.Context(), metaJson)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -731,11 +717,9 @@ func (app *application) insertPrototypeNft(w http.ResponseWriter, r *http.Reques
 		Weight:     weight,
 	}
 
-	err = app.sqlModels.Nfts.InsertPrototype(sbtPrototype)
+	err = app.sqlModels.Nfts.InsertPrototype(r.Context(), sbtPrototype)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -753,7 +737,7 @@ func (app *application) deleteCollectionHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.sqlModels.Nfts.DeleteCollection(idInt64)
+	err = app.sqlModels.Nfts.DeleteCollection(r.Context(), idInt64)
 	if err != nil {
 		app.logger.Error(err, nil)
 		return
@@ -773,7 +757,7 @@ func (app *application) deleteTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.sqlModels.Nfts.DeleteToken(idInt64)
+	err = app.sqlModels.Nfts.DeleteToken(r.Context(), idInt64)
 	if err != nil {
 		app.logger.Error(err, nil)
 		return
@@ -792,7 +776,7 @@ func (app *application) deletePrototypeHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = app.sqlModels.Nfts.DeletePrototype(idInt64)
+	err = app.sqlModels.Nfts.DeletePrototype(r.Context(), idInt64)
 	if err != nil {
 		app.logger.Error(err, nil)
 		return
@@ -812,7 +796,7 @@ func (app *application) updateCollectionHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	collection, err := app.sqlModels.Nfts.GetCollectionById(idInt64)
+	collection, err := app.sqlModels.Nfts.GetCollectionById(r.Context(), idInt64)
 	if err != nil {
 		app.errorMessage(w, r, http.StatusInternalServerError, err.Error(), nil)
 		return
@@ -877,14 +861,14 @@ func (app *application) updateCollectionHandler(w http.ResponseWriter, r *http.R
 		collection.UpdatedAt = *input.UpdatedAt
 	}
 
-	collection, err = app.sqlModels.Nfts.UpdateCollection(collection)
+	collection, err = app.sqlModels.Nfts.UpdateCollection(r.Context(), collection)
 	if err != nil {
 		app.errorMessage(w, r, http.StatusInternalServerError, err.Error(), nil)
 		return
 	}
 
 	// update collection metadata
-	err = app.sqlModels.Nfts.UpdateCollectionMetadata(metadata, strings.Split(collection.ContentUri, "/")[6])
+	err = app.sqlModels.Nfts.UpdateCollectionMetadata(r.Context(), metadata, strings.Split(collection.ContentUri, "/")[6])
 	if err != nil {
 		app.errorMessage(w, r, http.StatusInternalServerError, err.Error(), nil)
 		return
@@ -894,7 +878,6 @@ func (app *application) updateCollectionHandler(w http.ResponseWriter, r *http.R
 
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 	}
 
 }
@@ -909,10 +892,9 @@ func (app *application) updateTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	nft, err := app.sqlModels.Nfts.GetTokenByID(idInt64)
+	nft, err := app.sqlModels.Nfts.GetTokenByID(r.Context(), idInt64)
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -970,12 +952,10 @@ func (app *application) updateTokenHandler(w http.ResponseWriter, r *http.Reques
 		nft.Weight = weightInt64
 	}
 
-	nft, err = app.sqlModels.Nfts.UpdateToken(nft)
+	nft, err = app.sqlModels.Nfts.UpdateToken(r.Context(), nft)
 
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -984,7 +964,6 @@ func (app *application) updateTokenHandler(w http.ResponseWriter, r *http.Reques
 
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 	}
 
 }
@@ -999,10 +978,9 @@ func (app *application) updatePrototypeHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	nft, err := app.sqlModels.Nfts.GetNFTMetadataByPrototypeID(idInt64)
+	nft, err := app.sqlModels.Nfts.GetNFTMetadataByPrototypeID(r.Context(), idInt64)
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -1038,14 +1016,13 @@ func (app *application) updatePrototypeHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	if input.Weight != nil {
-		err = app.sqlModels.Nfts.UpdatePrototypeWeight(idInt64, *input.Weight)
+		err = app.sqlModels.Nfts.UpdatePrototypeWeight(r.Context(), idInt64, *input.Weight)
 	}
 
-	err = app.sqlModels.Nfts.UpdateNFTMetadata(nft)
+	err = app.sqlModels.Nfts.UpdateNFTMetadata(r.Context(), nft)
 
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -1053,7 +1030,6 @@ func (app *application) updatePrototypeHandler(w http.ResponseWriter, r *http.Re
 
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 	}
 
 }
@@ -1069,10 +1045,9 @@ func (app *application) getCollectionHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	collection, err := app.sqlModels.Nfts.GetCollectionById(idInt64)
+	collection, err := app.sqlModels.Nfts.GetCollectionById(r.Context(), idInt64)
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -1080,7 +1055,6 @@ func (app *application) getCollectionHandler(w http.ResponseWriter, r *http.Requ
 
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 	}
 
 }
@@ -1097,10 +1071,9 @@ func (app *application) getTokenHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	token, err := app.sqlModels.Nfts.GetTokenByID(idInt64)
+	token, err := app.sqlModels.Nfts.GetTokenByID(r.Context(), idInt64)
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -1108,7 +1081,6 @@ func (app *application) getTokenHandler(w http.ResponseWriter, r *http.Request)
 
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 	}
 
 }
@@ -1123,10 +1095,9 @@ func (app *application) getMetadataNftsHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	token, err := app.sqlModels.Nfts.GetNFTMetadataByID(idInt64)
+	token, err := app.sqlModels.Nfts.GetNFTMetadataByID(r.Context(), idInt64)
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -1134,7 +1105,6 @@ func (app *application) getMetadataNftsHandler(w http.ResponseWriter, r *http.Re
 
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 	}
 
 }
@@ -1143,10 +1113,9 @@ func (app *application) getMetaJsonNFT(w http.ResponseWriter, r *http.Request) {
 	base64String := flow.Param(r.Context(), "base64")
 
 	// get meta json by base64
-	metaJson, err := app.sqlModels.Nfts.GetNFTMetadataByBase64(base64String)
+	metaJson, err := app.sqlModels.Nfts.GetNFTMetadataByBase64(r.Context(), base64String)
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -1154,7 +1123,6 @@ func (app *application) getMetaJsonNFT(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 	}
 
 }
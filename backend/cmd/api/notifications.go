@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexedwards/flow"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/request"
+	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/tasks"
+	"github.com/ton-developer-program/internal/validator"
+)
+
+// getNotificationsHandler returns notifications of the user, ?unread=true returns only unread ones
+func (app *application) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := getPagination(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := app.sqlModels.Notifications.GetAllForUser(r.Context(), user.ID, unreadOnly, pagination)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	count, err := app.sqlModels.Notifications.CountForUser(r.Context(), user.ID, unreadOnly)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	unread, err := app.sqlModels.Notifications.CountForUser(r.Context(), user.ID, true)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{
+		"notifications": notifications,
+		"count":         count,
+		"unread":        unread,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// getUnreadNotificationsCountHandler returns number of unread notifications for the notification bell
+func (app *application) getUnreadNotificationsCountHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	unread, err := app.sqlModels.Notifications.CountForUser(r.Context(), user.ID, true)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{
+		"unread": unread,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) markNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return
+	}
+
+	err = app.sqlModels.Notifications.MarkRead(r.Context(), user.ID, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) markAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	updated, err := app.sqlModels.Notifications.MarkAllRead(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{
+		"updated": updated,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// createAdminNotificationHandler sends a message to the notification center of users,
+// without user_ids the message is sent to every user
+func (app *application) createAdminNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		UserIDs []int64 `json:"user_ids"`
+		Title   *string `json:"title"`
+		Content *string `json:"content"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	v := validator.Validator{}
+
+	v.CheckField(input.Title != nil && validator.NotBlank(*input.Title), "title", "must be provided")
+	v.CheckField(input.Title == nil || validator.MaxRunes(*input.Title, 200), "title", "must not be more than 200 characters long")
+	v.CheckField(input.Content != nil && validator.NotBlank(*input.Content), "content", "must be provided")
+	v.CheckField(input.Content == nil || validator.MaxRunes(*input.Content, 4000), "content", "must not be more than 4000 characters long")
+	v.CheckField(validator.NoDuplicates(input.UserIDs), "user_ids", "must not contain duplicates")
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"user_ids": input.UserIDs,
+		"title":    *input.Title,
+		"content":  *input.Content,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	task := tasks.NewTask(r.Context(), database.TYPE_ADMIN_NOTIFICATION, payload)
+
+	info, err := app.asynqClient.Enqueue(task, asynq.MaxRetry(5), asynq.Retention(24*time.Hour), asynq.Queue(database.PRIORITY_NORMAL))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logger.Ctx(r.Context()).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+
+	err = response.JSON(w, http.StatusAccepted, map[string]interface{}{
+		"task_id": info.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ton-developer-program/internal/database"
+)
+
+// rateLimitPolicy is a token bucket, burst tokens refilled at rate per second
+type rateLimitPolicy struct {
+	Name  string
+	Rate  float64
+	Burst int
+}
+
+// defaultRateLimitPolicies can be overridden with RATE_LIMIT_POLICIES
+var defaultRateLimitPolicies = map[string]rateLimitPolicy{
+	// every route
+	"default": {Name: "default", Rate: 10, Burst: 30},
+	// requests with a bearer token or api key per ip, before they are checked
+	"auth":    {Name: "auth", Rate: 10, Burst: 30},
+	"payload": {Name: "payload", Rate: 1, Burst: 5},
+	// routes checking TonConnect proof do liteserver requests
+	"proof": {Name: "proof", Rate: 0.2, Burst: 5},
+	"users": {Name: "users", Rate: 5, Burst: 20},
+	"meta":  {Name: "meta", Rate: 20, Burst: 50},
+	// routes sending verification emails
+	"email": {Name: "email", Rate: 1.0 / 60, Burst: 3},
+	// recoveries started for one profile, whoever starts them
+	"recovery": {Name: "recovery", Rate: 1.0 / 600, Burst: 3},
+}
+
+type rateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+type rateLimiter interface {
+	Allow(ctx context.Context, key string, policy rateLimitPolicy) (rateLimitResult, error)
+}
+
+// parseRateLimitPolicies applies overrides in format name=rate:burst,name=rate:burst
+func parseRateLimitPolicies(overrides string) (map[string]rateLimitPolicy, error) {
+	policies := make(map[string]rateLimitPolicy, len(defaultRateLimitPolicies))
+
+	for name, policy := range defaultRateLimitPolicies {
+		policies[name] = policy
+	}
+
+	for _, item := range strings.Split(overrides, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit policy %q", item)
+		}
+
+		rate, burst, ok := strings.Cut(value, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit policy %q", item)
+		}
+
+		policy := rateLimitPolicy{Name: name}
+
+		var err error
+
+		policy.Rate, err = strconv.ParseFloat(rate, 64)
+		if err != nil || policy.Rate <= 0 {
+			return nil, fmt.Errorf("invalid rate in rate limit policy %q", item)
+		}
+
+		policy.Burst, err = strconv.Atoi(burst)
+		if err != nil || policy.Burst <= 0 {
+			return nil, fmt.Errorf("invalid burst in rate limit policy %q", item)
+		}
+
+		policies[name] = policy
+	}
+
+	return policies, nil
+}
+
+// result for bucket state after the request
+func bucketResult(allowed bool, tokens float64, policy rateLimitPolicy) rateLimitResult {
+	result := rateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(policy.Burst) - tokens) / policy.Rate * float64(time.Second)),
+	}
+
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / policy.Rate * float64(time.Second))
+	}
+
+	return result
+}
+
+// redisRateLimiter keeps buckets in redis, shared by all api replicas
+type redisRateLimiter struct {
+	client *redis.Client
+}
+
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tokens, "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+
+return {allowed, tostring(tokens)}
+`)
+
+func (l *redisRateLimiter) Allow(ctx context.Context, key string, policy rateLimitPolicy) (rateLimitResult, error) {
+	res, err := tokenBucketScript.Run(ctx, l.client, []string{"ratelimit:" + key}, policy.Rate, policy.Burst, time.Now().UnixMilli()).Slice()
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+
+	if len(res) != 2 {
+		return rateLimitResult{}, fmt.Errorf("unexpected rate limit script result: %v", res)
+	}
+
+	allowed, _ := res[0].(int64)
+	tokensString, _ := res[1].(string)
+
+	tokens, err := strconv.ParseFloat(tokensString, 64)
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+
+	return bucketResult(allowed == 1, tokens, policy), nil
+}
+
+// memoryRateLimiter is used when redis is not available, limits are per replica
+type memoryRateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	tokens float64
+	ts     time.Time
+	burst  int
+	rate   float64
+}
+
+func newMemoryRateLimiter() *memoryRateLimiter {
+	return &memoryRateLimiter{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *memoryRateLimiter) Allow(ctx context.Context, key string, policy rateLimitPolicy) (rateLimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(policy.Burst), ts: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.burst = policy.Burst
+	bucket.rate = policy.Rate
+	bucket.tokens = math.Min(float64(policy.Burst), bucket.tokens+now.Sub(bucket.ts).Seconds()*policy.Rate)
+	bucket.ts = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	return bucketResult(allowed, bucket.tokens, policy), nil
+}
+
+// sweep removes buckets that are full again, once a minute
+func (l *memoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.ts).Seconds()*bucket.rate >= float64(bucket.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// fallbackRateLimiter uses redis and switches to memory buckets when redis fails
+type fallbackRateLimiter struct {
+	primary   rateLimiter
+	fallback  rateLimiter
+	onFailure func(err error)
+}
+
+func (l *fallbackRateLimiter) Allow(ctx context.Context, key string, policy rateLimitPolicy) (rateLimitResult, error) {
+	result, err := l.primary.Allow(ctx, key, policy)
+	if err == nil {
+		return result, nil
+	}
+
+	l.onFailure(err)
+
+	return l.fallback.Allow(ctx, key, policy)
+}
+
+// newRateLimiter returns redis limiter with in-memory fallback, redis errors are logged once a minute
+func (app *application) newRateLimiter(client *redis.Client) rateLimiter {
+	var mu sync.Mutex
+	var lastLogged time.Time
+
+	return &fallbackRateLimiter{
+		primary:  &redisRateLimiter{client: client},
+		fallback: newMemoryRateLimiter(),
+		onFailure: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if time.Since(lastLogged) < time.Minute {
+				return
+			}
+
+			lastLogged = time.Now()
+			app.logger.Warning("rate limiter uses in-memory buckets, redis failed: %s", err)
+		},
+	}
+}
+
+// clientIP returns client ip, X-Real-IP is used only behind the trusted proxy
+func (app *application) clientIP(r *http.Request) string {
+	if app.config.RateLimit.TrustProxy {
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			return ip
+		}
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
+
+// rateLimitKey returns bucket key, authenticated users and service accounts get their own bucket
+func (app *application) rateLimitKey(r *http.Request, policy rateLimitPolicy) string {
+	user := app.contextGetUser(r)
+
+	switch {
+	case user.IsServiceAccount():
+		return fmt.Sprintf("%s:service-account:%d", policy.Name, user.ServiceAccount.ID)
+	case !user.IsAnonymous():
+		return fmt.Sprintf("%s:user:%d", policy.Name, user.ID)
+	default:
+		return fmt.Sprintf("%s:ip:%s", policy.Name, app.clientIP(r))
+	}
+}
+
+// limit takes a token for the request, writes RateLimit-* headers and 429 response when bucket is empty
+func (app *application) limit(w http.ResponseWriter, r *http.Request, policyName string) bool {
+	return app.limitKey(w, r, policyName, func(policy rateLimitPolicy) string {
+		return app.rateLimitKey(r, policy)
+	})
+}
+
+// limitKey is limit with a bucket chosen by the handler, e.g. per target of the request
+func (app *application) limitKey(w http.ResponseWriter, r *http.Request, policyName string, key func(policy rateLimitPolicy) string) bool {
+	if !app.config.RateLimit.Enabled {
+		return true
+	}
+
+	policy, ok := app.rateLimitPolicies[policyName]
+	if !ok {
+		policy = app.rateLimitPolicies["default"]
+	}
+
+	result, err := app.rateLimiter.Allow(r.Context(), key(policy), policy)
+	if err != nil {
+		// never block requests because of limiter failure
+		app.logger.Error(err, nil)
+		return true
+	}
+
+	window := int(math.Ceil(float64(policy.Burst) / policy.Rate))
+
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;name=%q", policy.Burst, window, policy.Name))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(policy.Burst))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		app.rateLimitExceededResponse(w, r)
+		return false
+	}
+
+	return true
+}
+
+// rateLimitDefault applies the default policy to every route
+func (app *application) rateLimitDefault(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.limit(w, r, "default") {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitAuthentication limits requests with credentials per client ip before authenticate
+// looks them up, so tokens and api keys can't be guessed faster than the auth policy allows
+func (app *application) rateLimitAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" && r.Header.Get(database.ApiKeyHeader) == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limited := app.limitKey(w, r, "auth", func(policy rateLimitPolicy) string {
+			return fmt.Sprintf("%s:ip:%s", policy.Name, app.clientIP(r))
+		})
+		if !limited {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimit applies the named policy to the route in addition to the default one
+func (app *application) rateLimit(policyName string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !app.limit(w, r, policyName) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
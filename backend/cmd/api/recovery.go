@@ -0,0 +1,578 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/flow"
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/assets"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/request"
+	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/tasks"
+	"github.com/ton-developer-program/internal/tonconnect"
+	"golang.org/x/oauth2"
+)
+
+// github oauth state prefix used to confirm wallet recovery
+const recoveryStatePrefix = "recovery:"
+
+const (
+	recoveryTTL = time.Hour
+	// the page confirming the recovery after GitHub has to be submitted in this time
+	recoveryConfirmTTL = 10 * time.Minute
+	// keeps the recovery token in the browser that started the recovery, GitHub confirmation
+	// works only in that browser
+	recoveryCookie = "tdp_recovery"
+)
+
+// startRecoveryHandler starts wallet recovery: the user proves the new wallet
+// with TonConnect and gets a short-lived token to confirm the recovery
+func (app *application) startRecoveryHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Username *string              `json:"username"`
+		Proof    *tonconnect.TonProof `json:"proof"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Username == nil || input.Proof == nil {
+		app.badRequest(w, r, errors.New("username and proof are required"))
+		return
+	}
+
+	err = app.verifyTonProof(r.Context(), input.Proof)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user, err := app.sqlModels.Users.GetByUsername(r.Context(), *input.Username)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if user == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	// anyone can start a recovery of any profile, so they are limited per profile too
+	if !app.limitKey(w, r, "recovery", func(policy rateLimitPolicy) string {
+		return fmt.Sprintf("%s:target:%d", policy.Name, user.ID)
+	}) {
+		return
+	}
+
+	// a recovery in progress is not replaced, it's finished, rejected by an admin or expires
+	pending, err := app.sqlModels.Recoveries.GetPendingByUserID(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, database.ErrRecordNotFound) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if pending != nil && time.Since(time.Unix(pending.CreatedAt, 0)) < recoveryTTL {
+		app.errorMessage(w, r, http.StatusConflict, "a recovery of this profile is already in progress", nil)
+		return
+	}
+
+	// new wallet must not belong to any profile
+	existing, err := app.sqlModels.Users.GetByAddress(r.Context(), input.Proof.Address)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if existing != nil {
+		app.badRequest(w, r, errors.New("wallet is already linked to a profile"))
+		return
+	}
+
+	recovery, err := app.sqlModels.Recoveries.Insert(r.Context(), &database.WalletRecovery{
+		UserID:             user.ID,
+		OldRawAddress:      user.RawAddress,
+		OldFriendlyAddress: user.FriendlyAddress,
+		NewRawAddress:      input.Proof.Address,
+		NewFriendlyAddress: tonconnect.ConvertToFriendlyAddr(input.Proof.Address).String(),
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.sqlModels.Tokens.DeleteAllForUser(r.Context(), database.ScopeRecovery, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	t, err := app.sqlModels.Tokens.New(r.Context(), user.ID, recoveryTTL, database.ScopeRecovery)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     recoveryCookie,
+		Value:    t.Plaintext,
+		Path:     "/v1/",
+		MaxAge:   int(recoveryTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	accounts, err := app.sqlModels.Users.GetLinkedAccounts(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// providers that can be used to confirm the recovery
+	providers := []string{}
+	for _, account := range accounts {
+		if account.Verified {
+			providers = append(providers, account.Provider)
+		}
+	}
+
+	err = response.JSON(w, http.StatusCreated, map[string]interface{}{
+		"recovery":  recovery,
+		"token":     t.Plaintext,
+		"expires":   t.Expiry,
+		"providers": providers,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// confirmRecoveryTelegramHandler confirms recovery with the telegram account linked to the profile
+func (app *application) confirmRecoveryTelegramHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token   *string `json:"token"`
+		AuthObj *string `json:"auth_obj"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Token == nil || input.AuthObj == nil {
+		app.badRequest(w, r, errors.New("token and auth_obj are required"))
+		return
+	}
+
+	recovery, ok := app.getRecoveryForToken(w, r, *input.Token)
+	if !ok {
+		return
+	}
+
+	authData, err := decodeTelegramAuthObj(*input.AuthObj)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = app.checkTelegramAuthData(authData, telegramRecoveryAuthMaxAge)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	accounts, err := app.sqlModels.Users.GetLinkedAccounts(r.Context(), recovery.UserID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	matched := false
+	for _, account := range accounts {
+		if account.Provider == database.ProviderTelegram && account.TelegramUserID != nil && *account.TelegramUserID == authData.ID {
+			matched = true
+		}
+	}
+
+	if !matched {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	// a captured widget payload can't confirm another recovery
+	unused, err := app.useTelegramAuthData(r.Context(), authData, telegramRecoveryAuthMaxAge)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !unused {
+		app.errorMessage(w, r, http.StatusConflict, "telegram auth data was already used, log in with Telegram again", nil)
+		return
+	}
+
+	err = app.confirmRecovery(r.Context(), recovery, database.RecoveryConfirmedByTelegram, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{
+		"status": database.RecoveryStatusConfirmed,
+	})
+}
+
+// recoveryGithubLoginHandler redirects to github to confirm recovery with the linked github account,
+// the token must be the one kept in the cookie of the browser that started the recovery
+func (app *application) recoveryGithubLoginHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		app.badRequest(w, r, errors.New("token is required"))
+		return
+	}
+
+	if !app.recoveryCookieMatches(r, token) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	url := app.githubOauthConfig.AuthCodeURL(recoveryStatePrefix+token, oauth2.AccessTypeOnline)
+	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+}
+
+// confirmRecoveryGithub is called from the github callback when the state carries a recovery token.
+// The recovery is not confirmed here: the callback can be opened by a link in any browser
+// logged in to GitHub, so the owner confirms the new wallet on a page first.
+func (app *application) confirmRecoveryGithub(w http.ResponseWriter, r *http.Request, token string) {
+	if !app.recoveryCookieMatches(r, token) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	recovery, ok := app.getRecoveryForToken(w, r, token)
+	if !ok {
+		return
+	}
+
+	githubUser, _, err := app.getGithubUser(r.FormValue("code"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	accounts, err := app.sqlModels.Users.GetLinkedAccounts(r.Context(), recovery.UserID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	matched := false
+	for _, account := range accounts {
+		if account.Provider != database.ProviderGithub || !strings.EqualFold(account.Login, githubUser.Login) {
+			continue
+		}
+
+		// linked by username before link tokens, anyone could link their GitHub to the profile
+		if !account.Verified {
+			app.errorMessage(w, r, http.StatusForbidden, "the GitHub account has to be linked again in the settings to confirm a recovery", nil)
+			return
+		}
+
+		matched = true
+	}
+
+	if !matched {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	user, err := app.sqlModels.Users.GetById(r.Context(), recovery.UserID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.sqlModels.Tokens.DeleteAllForUser(r.Context(), database.ScopeRecoveryConfirm, recovery.UserID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	confirmToken, err := app.sqlModels.Tokens.New(r.Context(), recovery.UserID, recoveryConfirmTTL, database.ScopeRecoveryConfirm)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page, err := template.ParseFS(assets.EmbeddedFiles, "pages/recovery-confirm.tmpl")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	err = page.ExecuteTemplate(w, "page", map[string]interface{}{
+		"Login":     githubUser.Login,
+		"Username":  user.Username,
+		"Recovery":  recovery,
+		"Token":     confirmToken.Plaintext,
+		"CancelURL": app.config.App.BaseUrl,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// confirmRecoveryGithubHandler is submitted from the confirmation page, it confirms the recovery
+// checked by GitHub in the browser that started it
+func (app *application) confirmRecoveryGithubHandler(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(recoveryCookie)
+	if err != nil {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	user, err := app.sqlModels.Users.GetForToken(r.Context(), database.ScopeRecoveryConfirm, r.PostFormValue("token"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			app.invalidAuthenticationToken(w, r)
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	recovery, ok := app.getRecoveryForToken(w, r, cookie.Value)
+	if !ok {
+		return
+	}
+
+	if recovery.UserID != user.ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.confirmRecovery(r.Context(), recovery, database.RecoveryConfirmedByGithub, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     recoveryCookie,
+		Path:     "/v1/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, app.config.App.BaseUrl+"/settings", http.StatusSeeOther)
+}
+
+// recoveryCookieMatches reports whether the browser keeps the recovery token in the cookie
+func (app *application) recoveryCookieMatches(r *http.Request, token string) bool {
+	cookie, err := r.Cookie(recoveryCookie)
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(token)) == 1
+}
+
+// isHTTPS reports whether the client connected over https, directly or through nginx
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// getRecoveryForToken returns pending recovery of the token owner, writes error response if there is none
+func (app *application) getRecoveryForToken(w http.ResponseWriter, r *http.Request, token string) (*database.WalletRecovery, bool) {
+	user, err := app.sqlModels.Users.GetForToken(r.Context(), database.ScopeRecovery, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			app.invalidAuthenticationToken(w, r)
+			return nil, false
+		}
+
+		app.serverError(w, r, err)
+		return nil, false
+	}
+
+	recovery, err := app.sqlModels.Recoveries.GetPendingByUserID(r.Context(), user.ID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return nil, false
+		}
+
+		app.serverError(w, r, err)
+		return nil, false
+	}
+
+	return recovery, true
+}
+
+// confirmRecovery re-keys the profile to the new wallet and enqueues reissue of platform SBTs
+func (app *application) confirmRecovery(ctx context.Context, recovery *database.WalletRecovery, confirmedBy string, confirmedByUserID *int64) error {
+	err := app.sqlModels.Recoveries.Confirm(ctx, recovery, confirmedBy, confirmedByUserID)
+	if err != nil {
+		return err
+	}
+
+	err = app.sqlModels.Tokens.DeleteAllForUser(ctx, database.ScopeRecovery, recovery.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = app.sqlModels.Tokens.DeleteAllForUser(ctx, database.ScopeRecoveryConfirm, recovery.UserID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(recovery.ID)
+	if err != nil {
+		return err
+	}
+
+	task := tasks.NewTask(ctx, database.TYPE_RECOVER_WALLET, payload)
+
+	info, err := app.asynqClient.Enqueue(task, asynq.TaskID(fmt.Sprint("recover_wallet", recovery.ID)), asynq.MaxRetry(5), asynq.Retention(24*time.Hour), asynq.Queue(database.PRIORITY_URGENT))
+	if err != nil {
+		return err
+	}
+
+	app.logger.Ctx(ctx).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+
+	return nil
+}
+
+func (app *application) getRecoveriesHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := getPagination(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	filter := ""
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+	case database.RecoveryStatusPending, database.RecoveryStatusConfirmed, database.RecoveryStatusCompleted, database.RecoveryStatusRejected:
+		filter = fmt.Sprintf("WHERE status = '%s'", status)
+	default:
+		app.badRequest(w, r, errors.New("invalid status"))
+		return
+	}
+
+	recoveries, err := app.sqlModels.Recoveries.GetAll(r.Context(), pagination, filter)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	totalCount, err := app.sqlModels.Recoveries.Count(r.Context(), filter)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	headers := http.Header{
+		"x-total-count": []string{strconv.FormatInt(totalCount, 10)},
+	}
+
+	response.JSONWithHeaders(w, http.StatusOK, recoveries, headers)
+}
+
+// updateRecoveryHandler lets an admin confirm or reject pending recovery
+func (app *application) updateRecoveryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var input struct {
+		Status *string `json:"status"`
+	}
+
+	err = request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Status == nil {
+		app.badRequest(w, r, errors.New("status is required"))
+		return
+	}
+
+	recovery, err := app.sqlModels.Recoveries.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	if recovery.Status != database.RecoveryStatusPending {
+		app.editConclictResponse(w, r)
+		return
+	}
+
+	// service accounts have no user id
+	var adminID *int64
+	if admin := app.contextGetUser(r); !admin.IsServiceAccount() {
+		adminID = &admin.ID
+	}
+
+	switch *input.Status {
+	case database.RecoveryStatusConfirmed:
+		err = app.confirmRecovery(r.Context(), recovery, database.RecoveryConfirmedByAdmin, adminID)
+	case database.RecoveryStatusRejected:
+		err = app.sqlModels.Recoveries.Reject(r.Context(), recovery.ID)
+	default:
+		app.badRequest(w, r, errors.New("status must be confirmed or rejected"))
+		return
+	}
+
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.editConclictResponse(w, r)
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	recovery, err = app.sqlModels.Recoveries.GetByID(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, recovery)
+}
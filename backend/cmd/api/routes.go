@@ -9,37 +9,63 @@ import (
 func (app *application) routes() http.Handler {
 	mux := flow.New()
 
-	mux.NotFound = http.HandlerFunc(app.notFound)
+	mux.NotFound = http.HandlerFunc(app.routeNotFound)
 	mux.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowed)
 
 	mux.Use(app.recoverPanic)
-	mux.Use(app.authenticate)
+	mux.Use(app.instrument)
+	mux.Use(app.requestID)
+	mux.Use(app.trace)
+	mux.Use(app.secureHeaders)
+	// preflight requests are answered here and don't reach authenticate
 	mux.Use(app.enableCORS)
+	mux.Use(app.rateLimitAuthentication)
+	mux.Use(app.authenticate)
+	mux.Use(app.rateLimitDefault)
 
 	mux.HandleFunc("/v1/status", app.status, "GET")
+	mux.Handle("/v1/health/live", app.health.LiveHandler(), "GET")
+	// the report with checks is served on METRICS_ADDR
+	mux.Handle("/v1/health/ready", app.health.StatusHandler(), "GET")
 
-	mux.HandleFunc("/v1/ton-connect/generate-payload", app.payloadHandler, "GET")
-	mux.HandleFunc("/v1/ton-connect/check-proof", app.proofHandler, "POST")
+	mux.HandleFunc("/v1/ton-connect/generate-payload", app.rateLimit("payload", app.payloadHandler), "GET")
+	mux.HandleFunc("/v1/ton-connect/check-proof", app.rateLimit("proof", app.proofHandler), "POST")
 	mux.HandleFunc("/v1/manifest-ton-connect", app.manifestTonConnectHandler, "GET")
 
 	// auth
 	mux.HandleFunc("/v1/github/callback", app.githubCallbackHandler, "GET")
-	mux.HandleFunc("/v1/github/login", app.githubLoginHandler, "GET")
+	mux.HandleFunc("/v1/discord/callback", app.discordCallbackHandler, "GET")
+
+	// wallet recovery
+	mux.HandleFunc("/v1/recovery", app.rateLimit("proof", app.startRecoveryHandler), "POST")
+	mux.HandleFunc("/v1/recovery/confirm/telegram", app.confirmRecoveryTelegramHandler, "POST")
+	mux.HandleFunc("/v1/recovery/github/login", app.recoveryGithubLoginHandler, "GET")
+	mux.HandleFunc("/v1/recovery/confirm/github", app.confirmRecoveryGithubHandler, "POST")
 
+	// link from the verification email
+	mux.HandleFunc("/v1/email/verify", app.verifyEmailHandler, "GET")
 
-	mux.HandleFunc("/v1/deployed-nft/n/:base64/meta.json", app.getMetaJsonNft, "GET")
-	mux.HandleFunc("/v1/deployed-nft/c/:base64/meta.json", app.getMetaJsonCollection, "GET")
+
+	mux.HandleFunc("/v1/deployed-nft/n/:base64/meta.json", app.rateLimit("meta", app.getMetaJsonNft), "GET")
+	mux.HandleFunc("/v1/deployed-nft/c/:base64/meta.json", app.rateLimit("meta", app.getMetaJsonCollection), "GET")
 	
-	mux.HandleFunc("/v1/users", app.getTopUsersHandler, "GET")
-	mux.HandleFunc("/v1/users/:username", app.getUserByUsernameHandler, "GET")
-	mux.HandleFunc("/v1/nfts/:username", app.getNftsByUserIdHandler, "GET")
-	mux.HandleFunc("/v1/admin/csv/upload", app.uploadCSVHandler, "POST")
-	mux.HandleFunc("/v1/admin/media/upload", app.uploadImageHandler, "POST")
+	// token gating
+	mux.HandleFunc("/v1/attestation/key", app.getAttestationKeyHandler, "GET")
+	mux.HandleFunc("/v1/attestation/verify", app.rateLimit("proof", app.verifyAttestationHandler), "POST")
+
+	mux.HandleFunc("/v1/users", app.rateLimit("users", app.getTopUsersHandler), "GET")
+	mux.HandleFunc("/v1/users/:username", app.rateLimit("users", app.getUserByUsernameHandler), "GET")
+	mux.HandleFunc("/v1/nfts/:username", app.rateLimit("users", app.getNftsByUserIdHandler), "GET")
 
 
 	mux.Group(func(mux *flow.Mux) {
-		mux.Use(app.requireAuthenticatedUser)
+		mux.Use(app.requireWalletUser)
 		mux.HandleFunc("/v1/telegram/check_authorization", app.checkTelegramAuthorization, "POST")
+		mux.HandleFunc("/v1/telegram/link", app.createTelegramLinkHandler, "POST")
+		mux.HandleFunc("/v1/discord/link", app.createDiscordLinkHandler, "POST")
+		mux.HandleFunc("/v1/github/link", app.createGithubLinkHandler, "POST")
+
+		mux.HandleFunc("/v1/kudos", app.giveKudosHandler, "POST")
 
 		mux.HandleFunc("/v1/my-account", app.getMyAccountHandler, "GET")
 
@@ -53,72 +79,128 @@ func (app *application) routes() http.Handler {
 		mux.HandleFunc("/v1/incoming-achievements", app.getIncomingAchievementsHandler, "GET")
 		mux.HandleFunc("/v1/incoming-achievements/:id", app.updateIncomingAchievementHandler, "PUT")
 
+		// email notifications
+		mux.HandleFunc("/v1/email", app.getEmailHandler, "GET")
+		mux.HandleFunc("/v1/email", app.rateLimit("email", app.updateEmailHandler), "PUT")
+		mux.HandleFunc("/v1/email", app.deleteEmailHandler, "DELETE")
+		mux.HandleFunc("/v1/email/resend", app.rateLimit("email", app.resendEmailVerificationHandler), "POST")
+		mux.HandleFunc("/v1/email/preferences", app.updateNotificationPreferencesHandler, "PUT")
+
+		// language of emails and bot messages
+		mux.HandleFunc("/v1/language", app.getLanguageHandler, "GET")
+		mux.HandleFunc("/v1/language", app.updateLanguageHandler, "PUT")
+
+		// notification center
+		mux.HandleFunc("/v1/notifications", app.getNotificationsHandler, "GET")
+		mux.HandleFunc("/v1/notifications/unread-count", app.getUnreadNotificationsCountHandler, "GET")
+		mux.HandleFunc("/v1/notifications/read-all", app.markAllNotificationsReadHandler, "PUT")
+		mux.HandleFunc("/v1/notifications/:id/read", app.markNotificationReadHandler, "PUT")
+
 	})
 
 	mux.Group(func(mux *flow.Mux) {
-		mux.Use(func(next http.Handler) http.Handler {
-			return app.requirePermission(next)
-		})
+		// admin, every route declares the permission it requires
+		mux.Use(app.auditAdminActions)
 
-		mux.Use(app.requireAuthenticatedUser)
+		mux.HandleFunc("/v1/admin/csv/upload", app.requirePermission("permissions:csv-upload-create", app.uploadCSVHandler), "POST")
+		mux.HandleFunc("/v1/admin/media/upload", app.requirePermission("permissions:media-upload-create", app.uploadImageHandler), "POST")
 
-		mux.HandleFunc("/v1/admin/existing-collection", app.insertExistingCollectionHandler, "POST")
+		mux.HandleFunc("/v1/admin/existing-collection", app.requirePermission("permissions:existing-collection-create", app.insertExistingCollectionHandler), "POST")
 
-		mux.HandleFunc("/v1/admin/merch", app.createMerch, "POST")
-		mux.HandleFunc("/v1/admin/merch/:id", app.getMerchHandler, "GET")
-		mux.HandleFunc("/v1/admin/merch", app.getMerchsHandler, "GET")
+		mux.HandleFunc("/v1/admin/merch", app.requirePermission("permissions:merch-create", app.createMerch), "POST")
+		mux.HandleFunc("/v1/admin/merch/:id", app.requirePermission("permissions:merch-read", app.getMerchHandler), "GET")
+		mux.HandleFunc("/v1/admin/merch", app.requirePermission("permissions:merch-read", app.getMerchsHandler), "GET")
 
 
-		mux.HandleFunc("/v1/admin/rewards", app.getRewardsHandler, "GET")
-		mux.HandleFunc("/v1/admin/rewards/:id", app.getRewardHandler, "GET")
-		mux.HandleFunc("/v1/admin/rewards/:id", app.deleteRewardHandler, "DELETE")
+		mux.HandleFunc("/v1/admin/rewards", app.requirePermission("permissions:rewards-read", app.getRewardsHandler), "GET")
+		mux.HandleFunc("/v1/admin/rewards/:id", app.requirePermission("permissions:rewards-read", app.getRewardHandler), "GET")
+		mux.HandleFunc("/v1/admin/rewards/:id", app.requirePermission("permissions:rewards-delete", app.deleteRewardHandler), "DELETE")
 
-		mux.HandleFunc("/v1/admin/users", app.getUsersHandler, "GET")
-		mux.HandleFunc("/v1/admin/users/:id", app.getUserHandler, "GET")
+		mux.HandleFunc("/v1/admin/users", app.requirePermission("permissions:users-read", app.getUsersHandler), "GET")
+		mux.HandleFunc("/v1/admin/users/:id", app.requirePermission("permissions:users-read", app.getUserHandler), "GET")
 
-		mux.HandleFunc("/v1/admin/users", app.createUserHandler, "POST")
-		mux.HandleFunc("/v1/admin/users/:id", app.deleteUserHandler, "DELETE")
-		mux.HandleFunc("/v1/admin/users/:id", app.updateAdminUserHandler, "PATCH")
+		mux.HandleFunc("/v1/admin/users", app.requirePermission("permissions:users-create", app.createUserHandler), "POST")
+		mux.HandleFunc("/v1/admin/users/:id", app.requirePermission("permissions:users-delete", app.deleteUserHandler), "DELETE")
+		mux.HandleFunc("/v1/admin/users/:id", app.requirePermission("permissions:users-edit", app.updateAdminUserHandler), "PATCH")
 
+		mux.HandleFunc("/v1/admin/users/:id/roles", app.requirePermission("permissions:users-edit", app.updateUserRolesHandler), "PUT")
+		mux.HandleFunc("/v1/admin/users/:id/grants", app.requirePermission("permissions:users-read", app.getUserGrantsHandler), "GET")
+		mux.HandleFunc("/v1/admin/users/:id/grants", app.requirePermission("permissions:users-edit", app.insertUserGrantHandler), "POST")
+		mux.HandleFunc("/v1/admin/users/:id/grants/:grant_id", app.requirePermission("permissions:users-edit", app.deleteUserGrantHandler), "DELETE")
 
-		mux.HandleFunc("/v1/admin/collections", app.getCollectionsHandler, "GET")
-		mux.HandleFunc("/v1/admin/collections/:id", app.getCollectionHandler, "GET")
 
-		mux.HandleFunc("/v1/admin/collections", app.insertCollectionHandler, "POST")
-		mux.HandleFunc("/v1/admin/collections/:id", app.deleteCollectionHandler, "DELETE")
-		mux.HandleFunc("/v1/admin/collections/:id", app.updateCollectionHandler, "PATCH")
+		mux.HandleFunc("/v1/admin/collections", app.requirePermission("permissions:collections-read", app.getCollectionsHandler), "GET")
+		mux.HandleFunc("/v1/admin/collections/:id", app.requirePermission("permissions:collections-read", app.getCollectionHandler), "GET")
 
-		mux.HandleFunc("/v1/admin/prototype-nfts", app.getPrototypeTokensHandler, "GET")
-		mux.HandleFunc("/v1/admin/prototype-nfts/:id", app.getMetadataNftsHandler, "GET")
+		mux.HandleFunc("/v1/admin/collections", app.requirePermission("permissions:collections-create", app.insertCollectionHandler), "POST")
+		mux.HandleFunc("/v1/admin/collections/:id", app.requirePermission("permissions:collections-delete", app.deleteCollectionHandler), "DELETE")
+		mux.HandleFunc("/v1/admin/collections/:id", app.requirePermission("permissions:collections-edit", app.updateCollectionHandler), "PATCH")
 
-		mux.HandleFunc("/v1/admin/prototype-nfts", app.insertPrototypeNft, "POST")
-		mux.HandleFunc("/v1/admin/prototype-nfts/:id", app.deletePrototypeHandler, "DELETE")
-		mux.HandleFunc("/v1/admin/prototype-nfts/:id", app.updatePrototypeHandler, "PATCH")
-		
+		mux.HandleFunc("/v1/admin/prototype-nfts", app.requirePermission("permissions:prototype-nfts-read", app.getPrototypeTokensHandler), "GET")
+		mux.HandleFunc("/v1/admin/prototype-nfts/:id", app.requirePermission("permissions:prototype-nfts-read", app.getMetadataNftsHandler), "GET")
+
+		mux.HandleFunc("/v1/admin/prototype-nfts", app.requirePermission("permissions:prototype-nfts-create", app.insertPrototypeNft), "POST")
+		mux.HandleFunc("/v1/admin/prototype-nfts/:id", app.requirePermission("permissions:prototype-nfts-delete", app.deletePrototypeHandler), "DELETE")
+		mux.HandleFunc("/v1/admin/prototype-nfts/:id", app.requirePermission("permissions:prototype-nfts-edit", app.updatePrototypeHandler), "PATCH")
+
+
+		mux.HandleFunc("/v1/admin/minted-nfts", app.requirePermission("permissions:minted-nfts-read", app.getTokensHandler), "GET")
+		mux.HandleFunc("/v1/admin/minted-nfts/:id", app.requirePermission("permissions:minted-nfts-read", app.getTokenHandler), "GET")
+
+		mux.HandleFunc("/v1/admin/minted-nfts/:id", app.requirePermission("permissions:minted-nfts-delete", app.deleteTokenHandler), "DELETE")
+		// can be granted per collection, mintHandler checks the collection
+		mux.HandleFunc("/v1/admin/minted-nfts", app.requireScopedPermission("permissions:minted-nfts-create", app.mintHandler), "POST")
+		mux.HandleFunc("/v1/admin/minted-nfts/:id", app.requirePermission("permissions:minted-nfts-edit", app.updateTokenHandler), "PATCH")
+
+		mux.HandleFunc("/v1/admin/activities", app.requirePermission("permissions:activities-read", app.getActivitiesHandler), "GET")
+		mux.HandleFunc("/v1/admin/activities/:id", app.requirePermission("permissions:activities-read", app.getActivityHandler), "GET")
+
+		mux.HandleFunc("/v1/admin/activities", app.requirePermission("permissions:activities-create", app.InsertActivityHandler), "POST")
+		mux.HandleFunc("/v1/admin/activities/:id", app.requirePermission("permissions:activities-delete", app.deleteActivityHandler), "DELETE")
+		mux.HandleFunc("/v1/admin/activities/:id", app.requirePermission("permissions:activities-edit", app.updateActivityHandler), "PATCH")
+
+		mux.HandleFunc("/v1/admin/permissions", app.requirePermission("permissions:permissions-read", app.getPermissionsHandler), "GET")
+		mux.HandleFunc("/v1/admin/permissions", app.requirePermission("permissions:permissions-create", app.insertPermissionHandler), "POST")
+
+		mux.HandleFunc("/v1/admin/roles", app.requirePermission("permissions:roles-read", app.getRolesHandler), "GET")
+		mux.HandleFunc("/v1/admin/roles/:id", app.requirePermission("permissions:roles-read", app.getRoleHandler), "GET")
+
+		mux.HandleFunc("/v1/admin/roles", app.requirePermission("permissions:roles-create", app.insertRoleHandler), "POST")
+		mux.HandleFunc("/v1/admin/roles/:id", app.requirePermission("permissions:roles-delete", app.deleteRolesHandler), "DELETE")
+		mux.HandleFunc("/v1/admin/roles/:id", app.requirePermission("permissions:roles-edit", app.updateRoleHandler), "PATCH")
+
+		mux.HandleFunc("/v1/admin/recoveries", app.requirePermission("permissions:recoveries-read", app.getRecoveriesHandler), "GET")
+		mux.HandleFunc("/v1/admin/recoveries/:id", app.requirePermission("permissions:recoveries-edit", app.updateRecoveryHandler), "PUT")
+
+		mux.HandleFunc("/v1/admin/service-accounts", app.requirePermission("permissions:service-accounts-read", app.getServiceAccountsHandler), "GET")
+		mux.HandleFunc("/v1/admin/service-accounts/:id", app.requirePermission("permissions:service-accounts-read", app.getServiceAccountHandler), "GET")
 
-		mux.HandleFunc("/v1/admin/minted-nfts", app.getTokensHandler, "GET")
-		mux.HandleFunc("/v1/admin/minted-nfts/:id", app.getTokenHandler, "GET")
+		mux.HandleFunc("/v1/admin/service-accounts", app.requirePermission("permissions:service-accounts-create", app.createServiceAccountHandler), "POST")
+		mux.HandleFunc("/v1/admin/service-accounts/:id", app.requirePermission("permissions:service-accounts-edit", app.updateServiceAccountHandler), "PATCH")
+		mux.HandleFunc("/v1/admin/service-accounts/:id", app.requirePermission("permissions:service-accounts-delete", app.deleteServiceAccountHandler), "DELETE")
 
-		mux.HandleFunc("/v1/admin/minted-nfts/:id", app.deleteTokenHandler, "DELETE")
-		mux.HandleFunc("/v1/admin/minted-nfts", app.mintHandler, "POST")
-		mux.HandleFunc("/v1/admin/minted-nfts/:id", app.updateTokenHandler, "PATCH")
+		mux.HandleFunc("/v1/admin/service-accounts/:id/keys", app.requirePermission("permissions:service-accounts-edit", app.createApiKeyHandler), "POST")
+		mux.HandleFunc("/v1/admin/service-accounts/:id/keys/:key_id", app.requirePermission("permissions:service-accounts-edit", app.revokeApiKeyHandler), "DELETE")
 
-		mux.HandleFunc("/v1/admin/activities", app.getActivitiesHandler, "GET")
-		mux.HandleFunc("/v1/admin/activities/:id", app.getActivityHandler, "GET")
+		mux.HandleFunc("/v1/admin/audit-log", app.requirePermission("permissions:audit-log-read", app.getAuditLogsHandler), "GET")
 
-		mux.HandleFunc("/v1/admin/activities", app.InsertActivityHandler, "POST")
-		mux.HandleFunc("/v1/admin/activities/:id", app.deleteActivityHandler, "DELETE")
-		mux.HandleFunc("/v1/admin/activities/:id", app.updateActivityHandler, "PATCH")
+		mux.HandleFunc("/v1/admin/notifications", app.requirePermission("permissions:notifications-create", app.createAdminNotificationHandler), "POST")
 
-		mux.HandleFunc("/v1/admin/permissions", app.getPermissionsHandler, "GET")
+		mux.HandleFunc("/v1/admin/tg-chats", app.requirePermission("permissions:tg-chats-read", app.getChatsHandler), "GET")
+		mux.HandleFunc("/v1/admin/tg-chats/stats", app.requirePermission("permissions:tg-chats-read", app.getChatsStatsHandler), "GET")
+		mux.HandleFunc("/v1/admin/tg-chats/:id", app.requirePermission("permissions:tg-chats-read", app.getChatHandler), "GET")
 
-		mux.HandleFunc("/v1/admin/roles", app.getRolesHandler, "GET")
-		mux.HandleFunc("/v1/admin/roles/:id", app.getRoleHandler, "GET")
+		mux.HandleFunc("/v1/admin/tg-chats", app.requirePermission("permissions:tg-chats-create", app.createChatHandler), "POST")
+		mux.HandleFunc("/v1/admin/tg-chats/:id", app.requirePermission("permissions:tg-chats-edit", app.updateChatHandler), "PATCH")
+		mux.HandleFunc("/v1/admin/tg-chats/:id", app.requirePermission("permissions:tg-chats-delete", app.deleteChatHandler), "DELETE")
 
-		mux.HandleFunc("/v1/admin/roles", app.insertRoleHandler, "POST")
-		mux.HandleFunc("/v1/admin/roles/:id", app.deleteRolesHandler, "DELETE")
-		mux.HandleFunc("/v1/admin/roles/:id", app.updateRoleHandler, "PATCH")
+		mux.HandleFunc("/v1/admin/tg-failed-updates", app.requirePermission("permissions:tg-failed-updates-read", app.getFailedUpdatesHandler), "GET")
+		mux.HandleFunc("/v1/admin/tg-failed-updates/:id", app.requirePermission("permissions:tg-failed-updates-read", app.getFailedUpdateHandler), "GET")
+		mux.HandleFunc("/v1/admin/tg-failed-updates/:id/replay", app.requirePermission("permissions:tg-failed-updates-replay", app.replayFailedUpdateHandler), "POST")
 
+		mux.HandleFunc("/v1/admin/kudos", app.requirePermission("permissions:kudos-read", app.getKudosListHandler), "GET")
+		mux.HandleFunc("/v1/admin/kudos/:id", app.requirePermission("permissions:kudos-read", app.getKudosHandler), "GET")
+		mux.HandleFunc("/v1/admin/kudos/:id", app.requirePermission("permissions:kudos-delete", app.deleteKudosHandler), "DELETE")
 	})
 
 	return mux
@@ -27,7 +27,6 @@ func (app *application) uploadImageHandler(w http.ResponseWriter, r *http.Reques
 	file, handler, err := r.FormFile("file")
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 	defer file.Close()
@@ -44,7 +43,6 @@ func (app *application) uploadImageHandler(w http.ResponseWriter, r *http.Reques
 	})
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -57,7 +55,6 @@ func (app *application) uploadImageHandler(w http.ResponseWriter, r *http.Reques
 	})
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -77,7 +74,6 @@ func (app *application) uploadCSVHandler(w http.ResponseWriter, r *http.Request)
 	file, handler, err := r.FormFile("file")
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 	defer file.Close()
@@ -86,7 +82,6 @@ func (app *application) uploadCSVHandler(w http.ResponseWriter, r *http.Request)
 	records, err := read.ReadAll()
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -111,7 +106,6 @@ func (app *application) uploadCSVHandler(w http.ResponseWriter, r *http.Request)
 	})
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
@@ -124,7 +118,6 @@ func (app *application) uploadCSVHandler(w http.ResponseWriter, r *http.Request)
 	})
 	if err != nil {
 		app.serverError(w, r, err) 
-		 app.logger.Error(err,nil)
 		return
 	}
 
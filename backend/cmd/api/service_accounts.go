@@ -0,0 +1,311 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexedwards/flow"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/request"
+	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/validator"
+)
+
+// api keys can not live longer than this
+const maxApiKeyTTLDays = 365
+
+func (app *application) getServiceAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := getPagination(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	accounts, err := app.sqlModels.ServiceAccounts.GetAll(r.Context(), pagination)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	totalCount, err := app.sqlModels.ServiceAccounts.Count(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	headers := http.Header{
+		"x-total-count": []string{strconv.FormatInt(totalCount, 10)},
+	}
+
+	response.JSONWithHeaders(w, http.StatusOK, accounts, headers)
+}
+
+func (app *application) getServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := app.readServiceAccount(w, r)
+	if !ok {
+		return
+	}
+
+	err := response.JSON(w, http.StatusOK, account)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		RoleIDs     []int64 `json:"role_ids"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	v := validator.Validator{}
+
+	v.CheckField(input.Name != nil && *input.Name != "", "name", "must be provided")
+	v.CheckField(input.Name == nil || len(*input.Name) <= 64, "name", "must not be more than 64 bytes long")
+	v.CheckField(len(input.RoleIDs) > 0, "role_ids", "must contain at least one role")
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	if !app.checkRoleIDs(w, r, input.RoleIDs) {
+		return
+	}
+
+	account := &database.ServiceAccount{
+		Name:        *input.Name,
+		Description: input.Description,
+	}
+
+	if user := app.contextGetUser(r); !user.IsServiceAccount() {
+		account.CreatedBy = &user.ID
+	}
+
+	err = app.sqlModels.ServiceAccounts.Insert(r.Context(), account, input.RoleIDs)
+	if err != nil {
+		if errors.Is(err, database.ErrDuplicateServiceAccount) {
+			v.AddFieldError("name", "a service account with this name already exists")
+			app.failedValidation(w, r, v)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	account, err = app.sqlModels.ServiceAccounts.GetByID(r.Context(), account.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusCreated, account)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) updateServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := app.readServiceAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Description *string `json:"description"`
+		Disabled    *bool   `json:"disabled"`
+		RoleIDs     []int64 `json:"role_ids"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Description != nil {
+		account.Description = input.Description
+	}
+
+	if input.Disabled != nil {
+		account.Disabled = *input.Disabled
+	}
+
+	if input.RoleIDs != nil {
+		v := validator.Validator{}
+
+		v.CheckField(len(input.RoleIDs) > 0, "role_ids", "must contain at least one role")
+
+		if v.HasErrors() {
+			app.failedValidation(w, r, v)
+			return
+		}
+
+		if !app.checkRoleIDs(w, r, input.RoleIDs) {
+			return
+		}
+	}
+
+	err = app.sqlModels.ServiceAccounts.Update(r.Context(), account, input.RoleIDs)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.editConclictResponse(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	account, err = app.sqlModels.ServiceAccounts.GetByID(r.Context(), account.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, account)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return
+	}
+
+	err = app.sqlModels.ServiceAccounts.Delete(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]string{"message": "service account successfully deleted"})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// createApiKeyHandler creates a key for the service account, the plaintext key is returned only in this response
+func (app *application) createApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := app.readServiceAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name          *string `json:"name"`
+		ExpiresInDays *int    `json:"expires_in_days"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	v := validator.Validator{}
+
+	v.CheckField(input.Name != nil && *input.Name != "", "name", "must be provided")
+	v.CheckField(input.ExpiresInDays != nil, "expires_in_days", "must be provided")
+	v.CheckField(input.ExpiresInDays == nil || (*input.ExpiresInDays > 0 && *input.ExpiresInDays <= maxApiKeyTTLDays), "expires_in_days", fmt.Sprintf("must be between 1 and %d", maxApiKeyTTLDays))
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	key, err := app.sqlModels.ServiceAccounts.NewApiKey(r.Context(), account.ID, *input.Name, time.Duration(*input.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusCreated, key)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) revokeApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return
+	}
+
+	keyID, err := strconv.ParseInt(flow.Param(r.Context(), "key_id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("key_id must be an integer"))
+		return
+	}
+
+	err = app.sqlModels.ServiceAccounts.RevokeApiKey(r.Context(), id, keyID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]string{"message": "api key successfully revoked"})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// readServiceAccount loads service account from :id route param, writes error response when it fails
+func (app *application) readServiceAccount(w http.ResponseWriter, r *http.Request) (*database.ServiceAccount, bool) {
+	id, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return nil, false
+	}
+
+	account, err := app.sqlModels.ServiceAccounts.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return nil, false
+		}
+		app.serverError(w, r, err)
+		return nil, false
+	}
+
+	return account, true
+}
+
+// checkRoleIDs writes bad request response when one of the roles does not exist
+func (app *application) checkRoleIDs(w http.ResponseWriter, r *http.Request, roleIDs []int64) bool {
+	for _, roleID := range roleIDs {
+		_, err := app.sqlModels.Permissions.GetRoleByID(r.Context(), roleID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				app.badRequest(w, r, fmt.Errorf("role %d not found", roleID))
+				return false
+			}
+			app.serverError(w, r, err)
+			return false
+		}
+	}
+
+	return true
+}
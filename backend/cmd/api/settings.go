@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ton-developer-program/internal/i18n"
+	"github.com/ton-developer-program/internal/request"
+	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/validator"
+)
+
+// getLanguageHandler returns language of emails and bot messages chosen by the user, null if
+// the user didn't choose one
+func (app *application) getLanguageHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	language, err := app.sqlModels.Users.GetLanguage(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var value *string
+	if language != "" {
+		value = &language
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{
+		"language":  value,
+		"languages": i18n.Languages,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// updateLanguageHandler sets language of the user, null resets it to the Telegram language
+func (app *application) updateLanguageHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Language *string `json:"language"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	language := ""
+	if input.Language != nil {
+		language = *input.Language
+	}
+
+	v := validator.Validator{}
+
+	v.CheckField(language == "" || validator.In(language, i18n.Languages...), "language", "must be one of "+strings.Join(i18n.Languages, ", "))
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	err = app.sqlModels.Users.SetLanguage(r.Context(), user.ID, language)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{
+		"language": input.Language,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/version"
 	"github.com/tonkeeper/tongo/liteapi"
 )
 
@@ -12,12 +13,12 @@ var networks = map[string]*liteapi.Client{}
 
 func (app *application) status(w http.ResponseWriter, r *http.Request) {
 	data := map[string]string{
-		"Status": "OK",
+		"Status":  "OK",
+		"Version": version.Get(),
 	}
 	err := response.JSON(w, http.StatusOK, data)
 	if err != nil {
 		app.serverError(w, r, err) 
-		app.logger.Error(err,nil)
 	}
 }
 
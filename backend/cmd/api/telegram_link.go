@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/response"
+)
+
+// link tokens are valid for this long, Telegram keeps the start parameter only until the bot is opened
+const telegramLinkTTL = 15 * time.Minute
+
+// createTelegramLinkHandler returns a t.me deep link, opening it and pressing Start in the bot
+// links the Telegram account to the user. Previous links of the user stop working.
+func (app *application) createTelegramLinkHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if app.config.Bot.Username == "" {
+		app.serverError(w, r, errors.New("BOT_USERNAME is not set"))
+		return
+	}
+
+	err := app.sqlModels.Tokens.DeleteAllForUser(r.Context(), database.ScopeTelegramLink, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	token, err := app.sqlModels.Tokens.New(r.Context(), user.ID, telegramLinkTTL, database.ScopeTelegramLink)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusCreated, map[string]interface{}{
+		"url":    "https://t.me/" + app.config.Bot.Username + "?start=" + url.QueryEscape(token.Plaintext),
+		"expiry": token.Expiry,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
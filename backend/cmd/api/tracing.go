@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ton-developer-program/internal/leveledlog"
+	"github.com/ton-developer-program/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// trace starts a server span for the request, the parent is taken from the traceparent header
+func (app *application) trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := tracing.Tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", r.Method),
+				attribute.String("http.target", r.URL.Path),
+				attribute.String("http.user_agent", r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		if traceID := tracing.TraceIDFromContext(ctx); traceID != "" {
+			ctx = leveledlog.ContextWithFields(ctx, "trace_id", traceID)
+		}
+
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+
+		route := requestRoute(r)
+		status := http.StatusOK
+
+		if rec, ok := r.Context().Value(metricsContextKey{}).(*metricsRecorder); ok {
+			status = rec.status
+		}
+
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(attribute.String("http.route", route), attribute.Int("http.status_code", status))
+
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
@@ -1,17 +1,13 @@
 package main
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -21,9 +17,9 @@ import (
 	"github.com/ton-developer-program/internal/database"
 	"github.com/ton-developer-program/internal/request"
 	"github.com/ton-developer-program/internal/response"
+	"github.com/ton-developer-program/internal/tasks"
 	"github.com/ton-developer-program/internal/tonconnect"
 	"github.com/ton-developer-program/internal/validator"
-	"github.com/tonkeeper/tongo"
 	"golang.org/x/oauth2"
 )
 
@@ -43,7 +39,6 @@ func (app *application) proofHandler(w http.ResponseWriter, r *http.Request) {
 	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 	var tp tonconnect.TonProof
@@ -51,54 +46,20 @@ func (app *application) proofHandler(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(b, &tp)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
-	// check payload
-	err = tonconnect.CheckPayload(tp.Proof.Payload, app.config.Ton.SharedSecret)
+	err = app.verifyTonProof(ctx, &tp)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
-		return
-	}
-
-	parsed, err := tonconnect.ConvertTonProofMessage(ctx, &tp)
-	if err != nil {
-		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
-		return
-	}
-
-	net := networks[tp.Network]
-
-	if net == nil {
-		app.serverError(w, r, errors.New("invalid network"))
-		return
-	}
-	addr, err := tongo.ParseAccountID(tp.Address)
-	if net == nil {
-		app.serverError(w, r, errors.New("invalid address"))
-		return
-	}
-
-	check, err := tonconnect.CheckProof(ctx, addr, net, parsed, app.config.App.DomainName)
-	if err != nil {
-		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
-		return
-	}
-	if !check {
-		app.serverError(w, r, errors.New("proof verification failed"))
 		return
 	}
 
 	var user *database.User
 	// check if user exists
-	user, err = app.sqlModels.Users.GetByAddress(tp.Address)
+	user, err = app.sqlModels.Users.GetByAddress(ctx, tp.Address)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -111,28 +72,25 @@ func (app *application) proofHandler(w http.ResponseWriter, r *http.Request) {
 			FriendlyAddress: tonconnect.ConvertToFriendlyAddr(tp.Address).String(),
 		}
 
-		user, err = app.sqlModels.Users.Insert(user)
+		user, err = app.sqlModels.Users.Insert(ctx, user)
 		if err != nil {
 			app.serverError(w, r, err)
-			app.logger.Error(err, nil)
 			return
 		}
 	}
 
 	// // get roles and permissions
-	permissions, err := app.sqlModels.Users.GetAllPermissions(user.ID)
+	permissions, err := app.sqlModels.Users.GetAllPermissions(ctx, user.ID)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	user.Permissions = permissions
 
-	t, err := app.sqlModels.Tokens.New(user.ID, 24*7*time.Hour, database.ScopeAuthentication)
+	t, err := app.sqlModels.Tokens.New(ctx, user.ID, 24*7*time.Hour, database.ScopeAuthentication)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -144,7 +102,6 @@ func (app *application) proofHandler(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
@@ -173,37 +130,33 @@ func (app *application) getUsersHandler(w http.ResponseWriter, r *http.Request)
 		filter = fmt.Sprintf("WHERE %s", strings.Join(filters, " OR "))
 	}
 
-	users, err := app.sqlModels.Users.GetMany(pagination, filter, "id ASC")
+	users, err := app.sqlModels.Users.GetMany(r.Context(), pagination, filter, "id ASC")
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	for i := range users {
 		// get linked accounts
-		accounts, err := app.sqlModels.Users.GetLinkedAccounts(users[i].ID)
+		accounts, err := app.sqlModels.Users.GetLinkedAccounts(r.Context(), users[i].ID)
 		if err != nil {
 			app.serverError(w, r, err)
-			app.logger.Error(err, nil)
 			return
 		}
 		users[i].LinkedAccounts = accounts
 
 		// get user roles
-		role, err := app.sqlModels.Permissions.GetUserRoles(users[i].ID)
+		roles, err := app.sqlModels.Permissions.GetUserRoles(r.Context(), users[i].ID)
 		if err != nil {
 			app.serverError(w, r, err)
-			app.logger.Error(err, nil)
 			return
 		}
-		users[i].Role = role
+		users[i].SetRoles(roles)
 	}
 
 	err = response.JSON(w, http.StatusOK, users)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 }
 
@@ -217,40 +170,36 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := app.sqlModels.Users.GetById(idInt64)
+	user, err := app.sqlModels.Users.GetById(r.Context(), idInt64)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	// get linkee accounts
-	accounts, err := app.sqlModels.Users.GetLinkedAccounts(user.ID)
+	accounts, err := app.sqlModels.Users.GetLinkedAccounts(r.Context(), user.ID)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	user.LinkedAccounts = accounts
 
 	// get user roles
-	role, err := app.sqlModels.Permissions.GetUserRoles(user.ID)
+	roles, err := app.sqlModels.Permissions.GetUserRoles(r.Context(), user.ID)
 
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
-	user.Role = role
+	user.SetRoles(roles)
 
 	// get
 
 	err = response.JSON(w, http.StatusOK, user)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 }
 
@@ -264,10 +213,9 @@ func (app *application) updateAdminUserHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	user, err := app.sqlModels.Users.GetById(idInt64)
+	user, err := app.sqlModels.Users.GetById(r.Context(), idInt64)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -280,6 +228,7 @@ func (app *application) updateAdminUserHandler(w http.ResponseWriter, r *http.Re
 		Languages      *[]string `db:"languages" json:"languages"`
 		Certifications *[]string `db:"certifications" json:"certifications"`
 		RoleId         *int64    `db:"role_id" json:"role_id"`
+		RoleIds        *[]int64  `json:"role_ids"`
 	}
 
 	err = request.DecodeJSON(w, r, &input)
@@ -316,36 +265,29 @@ func (app *application) updateAdminUserHandler(w http.ResponseWriter, r *http.Re
 		user.Certifications = *input.Certifications
 	}
 
-	user, err = app.sqlModels.Users.Update(user)
+	user, err = app.sqlModels.Users.Update(r.Context(), user)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
-	linkedAccounts, err := app.sqlModels.Users.GetLinkedAccounts(user.ID)
+	linkedAccounts, err := app.sqlModels.Users.GetLinkedAccounts(r.Context(), user.ID)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	user.LinkedAccounts = linkedAccounts
 
-	if input.RoleId != nil {
-		// delete old role
-		err = app.sqlModels.Permissions.DeleteUserRoles(user.ID)
-		if err != nil {
-			app.serverError(w, r, err)
-			app.logger.Error(err, nil)
-			return
-		}
+	// role_id replaces all roles with the single one
+	if input.RoleId != nil && input.RoleIds == nil {
+		input.RoleIds = &[]int64{*input.RoleId}
+	}
 
-		// add new role
-		err = app.sqlModels.Permissions.InsertUserRole(user.ID, *input.RoleId)
+	if input.RoleIds != nil {
+		err = app.sqlModels.Permissions.SetUserRoles(r.Context(), user.ID, *input.RoleIds)
 		if err != nil {
 			app.serverError(w, r, err)
-			app.logger.Error(err, nil)
 			return
 		}
 
@@ -355,7 +297,6 @@ func (app *application) updateAdminUserHandler(w http.ResponseWriter, r *http.Re
 
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
@@ -386,14 +327,12 @@ func (app *application) createMerch(w http.ResponseWriter, r *http.Request) {
 	err = app.sqlModels.Rewards.InsertMerch(merch)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	err = response.JSON(w, http.StatusOK, merch)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 }
 
@@ -410,14 +349,12 @@ func (app *application) getMerchHandler(w http.ResponseWriter, r *http.Request)
 	merch, err := app.sqlModels.Rewards.GetMerchByID(idInt64)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	err = response.JSON(w, http.StatusOK, merch)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 }
@@ -427,14 +364,12 @@ func (app *application) getMerchsHandler(w http.ResponseWriter, r *http.Request)
 	merchs, err := app.sqlModels.Rewards.GetAllMerch()
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	err = response.JSON(w, http.StatusOK, merchs)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 }
 
@@ -449,6 +384,7 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		FriendlyAddress *string `json:"friendly_address"`
 		RawAddress      *string `json:"raw_address"`
 		RoleId          *int64  `json:"role_id"`
+		RoleIds         []int64 `json:"role_ids"`
 	}
 
 	err := request.DecodeJSON(w, r, &input)
@@ -467,19 +403,21 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		RawAddress:      *input.RawAddress,
 	}
 
-	user, err = app.sqlModels.Users.Create(user)
+	user, err = app.sqlModels.Users.Create(r.Context(), user)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	if input.RoleId != nil {
-		// insert role
-		err = app.sqlModels.Permissions.InsertUserRole(user.ID, *input.RoleId)
+		input.RoleIds = append(input.RoleIds, *input.RoleId)
+	}
+
+	if len(input.RoleIds) > 0 {
+		// insert roles
+		err = app.sqlModels.Permissions.UpdateUserRoles(r.Context(), user.ID, input.RoleIds)
 		if err != nil {
 			app.serverError(w, r, err)
-			app.logger.Error(err, nil)
 			return
 		}
 	}
@@ -487,7 +425,6 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 	err = response.JSON(w, http.StatusCreated, user)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 
 	}
 }
@@ -502,10 +439,9 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.sqlModels.Users.Delete(idInt64)
+	err = app.sqlModels.Users.Delete(r.Context(), idInt64)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -515,7 +451,6 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
@@ -606,10 +541,9 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		user.AvatarURL = input.AvatarUrl
 	}
 
-	user, err = app.sqlModels.Users.Update(user)
+	user, err = app.sqlModels.Users.Update(r.Context(), user)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -617,7 +551,6 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
@@ -628,10 +561,9 @@ func (app *application) unlinkAccountHandler(w http.ResponseWriter, r *http.Requ
 
 	provider := flow.Param(r.Context(), "provider")
 
-	err := app.sqlModels.Users.DeleteLinkedAccountByUserId(user.ID, provider)
+	err := app.sqlModels.Users.DeleteLinkedAccountByUserId(r.Context(), user.ID, provider)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -652,10 +584,9 @@ func (app *application) pinNftHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// check if nft belongs to user
-	nft, err := app.sqlModels.Nfts.GetTokenByID(idInt64)
+	nft, err := app.sqlModels.Nfts.GetTokenByID(r.Context(), idInt64)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -672,10 +603,9 @@ func (app *application) pinNftHandler(w http.ResponseWriter, r *http.Request) {
 		pinned = true
 	}
 
-	err = app.sqlModels.Nfts.PinNFT(idInt64, pinned)
+	err = app.sqlModels.Nfts.PinNFT(r.Context(), idInt64, pinned)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -707,12 +637,10 @@ func (app *application) getIncomingAchievementsHandler(w http.ResponseWriter, r
 			})
 			if err != nil {
 				app.serverError(w, r, err)
-				app.logger.Error(err, nil)
 			}
 			return
 		}
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -720,10 +648,9 @@ func (app *application) getIncomingAchievementsHandler(w http.ResponseWriter, r
 
 	for _, achievement := range achievements {
 		// get by achievement base 64 nft metadata
-		nftMetaData, err := app.sqlModels.Nfts.GetNFTMetadataByBase64(achievement.Base64Metadata)
+		nftMetaData, err := app.sqlModels.Nfts.GetNFTMetadataByBase64(r.Context(), achievement.Base64Metadata)
 		if err != nil {
 			app.serverError(w, r, err)
-			app.logger.Error(err, nil)
 			return
 		}
 
@@ -752,7 +679,6 @@ func (app *application) getIncomingAchievementsHandler(w http.ResponseWriter, r
 	count, err := app.sqlModels.Rewards.CountStoredRewardsByUserID(user.FriendlyAddress)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -763,7 +689,6 @@ func (app *application) getIncomingAchievementsHandler(w http.ResponseWriter, r
 
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 }
 
@@ -793,7 +718,6 @@ func (app *application) updateIncomingAchievementHandler(w http.ResponseWriter,
 	achievement, err := app.sqlModels.Rewards.GetStoredRewardByID(idInt64)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -806,14 +730,34 @@ func (app *application) updateIncomingAchievementHandler(w http.ResponseWriter,
 		achievement.ApprovedByUser = *input.ApprovedByUser
 	}
 
+	// declined achievement is never minted
+	if input.ApprovedByUser != nil && !*input.ApprovedByUser {
+		err = app.sqlModels.Rewards.DeclineStoredReward(achievement.ID)
+		if err != nil {
+			if errors.Is(err, database.ErrRecordNotFound) {
+				app.errorMessage(w, r, http.StatusConflict, "achievement is already processed", nil)
+				return
+			}
+			app.serverError(w, r, err)
+			return
+		}
+
+		err = response.JSON(w, http.StatusOK, map[string]string{
+			"message": "achievement declined",
+		})
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
 	err = app.sqlModels.Rewards.UpdateStoredRewardApprovedByUser(achievement.ID, true)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
-	runGetStoredRewards := asynq.NewTask(database.TYPE_MINT_STORED_REWARDS, nil)
+	runGetStoredRewards := tasks.NewTask(r.Context(), database.TYPE_MINT_STORED_REWARDS, nil)
 
 	info, err := app.asynqClient.Enqueue(runGetStoredRewards, asynq.TaskID("MINT_STORED_REWARDS"), asynq.ProcessIn(10*time.Second), asynq.MaxRetry(5),asynq.Retention(30 * time.Second), asynq.Queue(database.PRIORITY_URGENT))
 	if err != nil {
@@ -821,7 +765,7 @@ func (app *application) updateIncomingAchievementHandler(w http.ResponseWriter,
 		return
 	}
 
-	app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))
+	app.logger.Ctx(r.Context()).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
 
 	err = response.JSON(w, http.StatusOK, map[string]string{
 		"message": "achievement updated",
@@ -829,7 +773,6 @@ func (app *application) updateIncomingAchievementHandler(w http.ResponseWriter,
 
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
@@ -842,19 +785,17 @@ func (app *application) getTopUsersHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	users, err := app.sqlModels.Users.GetTopUsers(pagination)
+	users, err := app.sqlModels.Users.GetTopUsers(r.Context(), pagination)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	// get linked accounts
 	for _, user := range users {
-		linkedAccounts, err := app.sqlModels.Users.GetLinkedAccounts(user.ID)
+		linkedAccounts, err := app.sqlModels.Users.GetLinkedAccounts(r.Context(), user.ID)
 		if err != nil {
 			app.serverError(w, r, err)
-			app.logger.Error(err, nil)
 			return
 		}
 		user.LinkedAccounts = linkedAccounts
@@ -864,7 +805,6 @@ func (app *application) getTopUsersHandler(w http.ResponseWriter, r *http.Reques
 
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
@@ -874,31 +814,35 @@ func (app *application) getUserByUsernameHandler(w http.ResponseWriter, r *http.
 
 	username := flow.Param(ctx, "username")
 
-	user, err := app.sqlModels.Users.GetByUsername(username)
+	user, err := app.sqlModels.Users.GetByUsername(ctx, username)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	// get linked accounts
 
-	linkedAccounts, err := app.sqlModels.Users.GetLinkedAccounts(user.ID)
+	linkedAccounts, err := app.sqlModels.Users.GetLinkedAccounts(ctx, user.ID)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	user.LinkedAccounts = linkedAccounts
 
+	kudos, err := app.sqlModels.Kudos.CountReceived(ctx, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
 	// get linked accounts
 	err = response.JSON(w, http.StatusOK, map[string]interface{}{
 		"user": user,
+		"kudos": kudos,
 	})
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 }
 
@@ -914,25 +858,22 @@ func (app *application) getNftsByUserIdHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	user, err := app.sqlModels.Users.GetByUsername(username)
+	user, err := app.sqlModels.Users.GetByUsername(ctx, username)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
-	nfts, err := app.sqlModels.Nfts.GetTokensByOwnerAddress(user.FriendlyAddress, pagination)
+	nfts, err := app.sqlModels.Nfts.GetTokensByOwnerAddress(ctx, user.FriendlyAddress, pagination)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	// get total count
-	count, err := app.sqlModels.Nfts.GetTotalTokensByOwnerAddress(user.FriendlyAddress)
+	count, err := app.sqlModels.Nfts.GetTotalTokensByOwnerAddress(ctx, user.FriendlyAddress)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -943,7 +884,6 @@ func (app *application) getNftsByUserIdHandler(w http.ResponseWriter, r *http.Re
 	})
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 }
 
@@ -952,10 +892,9 @@ func (app *application) getMyAccountHandler(w http.ResponseWriter, r *http.Reque
 
 	// get linked accounts
 
-	linkedAccounts, err := app.sqlModels.Users.GetLinkedAccounts(user.ID)
+	linkedAccounts, err := app.sqlModels.Users.GetLinkedAccounts(r.Context(), user.ID)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -965,72 +904,75 @@ func (app *application) getMyAccountHandler(w http.ResponseWriter, r *http.Reque
 	err = response.JSON(w, http.StatusOK, user)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 }
 
-func (app *application) githubLoginHandler(w http.ResponseWriter, r *http.Request) {
-	// query username
-	username := r.URL.Query().Get("username")
+// the link token is the oauth state, it only has to live until GitHub redirects back
+const githubLinkTTL = 15 * time.Minute
 
-	url := app.githubOauthConfig.AuthCodeURL(username, oauth2.AccessTypeOnline)
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
-}
-
-func (app *application) githubCallbackHandler(w http.ResponseWriter, r *http.Request) {
+// createGithubLinkHandler returns the url of the GitHub authorization page, the state is
+// a link token like for Discord, so the callback links the account to the user who asked for it
+func (app *application) createGithubLinkHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
 
-	state := r.FormValue("state")
-	user, err := app.sqlModels.Users.GetByUsername(state)
+	err := app.sqlModels.Tokens.DeleteAllForUser(r.Context(), database.ScopeGithubLink, user.ID)
 	if err != nil {
-		fmt.Printf("Failed to get user: %s\n", err.Error())
-		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+		app.serverError(w, r, err)
 		return
 	}
 
-	// if username is not matched to the state
-	if user.Username != state {
-		fmt.Printf("invalid oauth state, expected '%s', got '%s'\n", user.Username, state)
-		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+	token, err := app.sqlModels.Tokens.New(r.Context(), user.ID, githubLinkTTL, database.ScopeGithubLink)
+	if err != nil {
+		app.serverError(w, r, err)
 		return
 	}
 
-	code := r.FormValue("code")
-	token, err := app.githubOauthConfig.Exchange(oauth2.NoContext, code)
+	err = response.JSON(w, http.StatusCreated, map[string]interface{}{
+		"url":    app.githubOauthConfig.AuthCodeURL(token.Plaintext, oauth2.AccessTypeOnline),
+		"expiry": token.Expiry,
+	})
 	if err != nil {
-		fmt.Printf("githubOauthConfig.Exchange() failed with '%s'\n", err)
-		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) githubCallbackHandler(w http.ResponseWriter, r *http.Request) {
+
+	state := r.FormValue("state")
+
+	if strings.HasPrefix(state, recoveryStatePrefix) {
+		app.confirmRecoveryGithub(w, r, strings.TrimPrefix(state, recoveryStatePrefix))
 		return
 	}
 
-	req, err := http.NewRequest("GET", "https://api.github.com/user", nil)
+	user, err := app.sqlModels.Users.GetForToken(r.Context(), database.ScopeGithubLink, state)
 	if err != nil {
-		fmt.Printf("Failed to create request: %s\n", err.Error())
+		if !errors.Is(err, sql.ErrNoRows) {
+			app.logger.Ctx(r.Context()).Warningw("error getting user for github link", "error", err)
+		}
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 		return
 	}
-	req.Header.Set("Authorization", "token "+token.AccessToken)
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	// the link token works once
+	err = app.sqlModels.Tokens.DeleteAllForUser(r.Context(), database.ScopeGithubLink, user.ID)
 	if err != nil {
-		fmt.Printf("Failed to get user info: %s\n", err.Error())
+		app.logger.Ctx(r.Context()).Warningw("error deleting github link tokens", "error", err)
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 		return
 	}
 
-	defer res.Body.Close()
-	content, err := ioutil.ReadAll(res.Body)
+	githubUser, token, err := app.getGithubUser(r.FormValue("code"))
 	if err != nil {
-		fmt.Printf("Failed to read response body: %s\n", err.Error())
+		fmt.Printf("Failed to get github user: %s\n", err.Error())
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 		return
 	}
 
-	var githubUser database.GithubUser
-
-	err = json.Unmarshal(content, &githubUser)
+	// replaces the account linked before link tokens, it isn't trusted for recovery
+	err = app.sqlModels.Users.DeleteLinkedAccountByUserId(r.Context(), user.ID, database.ProviderGithub)
 	if err != nil {
-		fmt.Printf("Failed to unmarshal response body: %s\n", err.Error())
+		app.logger.Ctx(r.Context()).Warningw("error replacing github account", "error", err)
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 		return
 	}
@@ -1047,7 +989,7 @@ func (app *application) githubCallbackHandler(w http.ResponseWriter, r *http.Req
 		Version:     1,
 	}
 
-	err = app.sqlModels.Users.InsertLinkedAccount(linkedAccount)
+	err = app.sqlModels.Users.InsertLinkedAccount(r.Context(), linkedAccount)
 	if err != nil {
 		fmt.Printf("Failed to insert linked account: %s\n", err.Error())
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -1055,7 +997,7 @@ func (app *application) githubCallbackHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// check if user has two linked accounts
-	hasTwoAccounts, err := app.sqlModels.Users.HasTwoLinkedAccounts(user.ID)
+	hasTwoAccounts, err := app.sqlModels.Users.HasTwoLinkedAccounts(r.Context(), user.ID)
 	if err != nil {
 		fmt.Printf("Failed to check if user has two linked accounts: %s\n", err.Error())
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -1063,7 +1005,7 @@ func (app *application) githubCallbackHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// get auth nft by metadata id
-	authNft, err := app.sqlModels.Nfts.GetNFTMetadataByID(app.config.App.AuthMetadataID)
+	authNft, err := app.sqlModels.Nfts.GetNFTMetadataByID(r.Context(), app.config.App.AuthMetadataID)
 	if err != nil {
 		fmt.Printf("Failed to get auth nft: %s\n", err.Error())
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -1071,7 +1013,7 @@ func (app *application) githubCallbackHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// check if user has auth nft
-	hasAuthNft, err := app.sqlModels.Nfts.HasAuthNFT(user.FriendlyAddress, authNft.Base64)
+	hasAuthNft, err := app.sqlModels.Nfts.HasAuthNFT(r.Context(), user.FriendlyAddress, authNft.Base64)
 	if err != nil {
 		fmt.Printf("Failed to check if user has auth nft: %s\n", err.Error())
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -1089,7 +1031,7 @@ func (app *application) githubCallbackHandler(w http.ResponseWriter, r *http.Req
 			return
 		}
 
-		runGetReward := asynq.NewTask(database.TYPE_REWARD_FOR_LINKED_ACCOUNT, payload)
+		runGetReward := tasks.NewTask(r.Context(), database.TYPE_REWARD_FOR_LINKED_ACCOUNT, payload)
 
 		info, err := app.asynqClient.Enqueue(runGetReward, asynq.TaskID(fmt.Sprint("reward_auth", user.ID)), asynq.ProcessIn(10*time.Second), asynq.MaxRetry(5), asynq.ProcessIn(5*time.Second), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_URGENT))
 		if err != nil {
@@ -1097,7 +1039,7 @@ func (app *application) githubCallbackHandler(w http.ResponseWriter, r *http.Req
 			return
 		}
 
-		app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))
+		app.logger.Ctx(r.Context()).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
 
 	}
 
@@ -1107,6 +1049,41 @@ func (app *application) githubCallbackHandler(w http.ResponseWriter, r *http.Req
 
 }
 
+// getGithubUser exchanges oauth code and fetches the github user
+func (app *application) getGithubUser(code string) (*database.GithubUser, *oauth2.Token, error) {
+	token, err := app.githubOauthConfig.Exchange(oauth2.NoContext, code)
+	if err != nil {
+		return nil, nil, fmt.Errorf("githubOauthConfig.Exchange() failed with '%s'", err)
+	}
+
+	req, err := http.NewRequest("GET", "https://api.github.com/user", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", "token "+token.AccessToken)
+
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer res.Body.Close()
+	content, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var githubUser database.GithubUser
+
+	err = json.Unmarshal(content, &githubUser)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &githubUser, token, nil
+}
+
 type AuthData struct {
 	ID        int64  `json:"id"`
 	FirstName string `json:"first_name"`
@@ -1127,7 +1104,6 @@ func (app *application) checkTelegramAuthorization(w http.ResponseWriter, r *htt
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -1138,7 +1114,6 @@ func (app *application) checkTelegramAuthorization(w http.ResponseWriter, r *htt
 	err = json.Unmarshal(body, &jsonBody)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -1146,7 +1121,6 @@ func (app *application) checkTelegramAuthorization(w http.ResponseWriter, r *htt
 	decoded, err := base64.RawStdEncoding.DecodeString(jsonBody.AuthObj)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -1156,42 +1130,25 @@ func (app *application) checkTelegramAuthorization(w http.ResponseWriter, r *htt
 	err = json.Unmarshal(decoded, &authData)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 
-	checkHash := authData.Hash
-	authData.Hash = ""
-
-	dataCheckArr := []string{}
-	dataCheckArr = append(dataCheckArr, fmt.Sprintf("auth_date=%d", authData.AuthDate))
-
 	if authData.FirstName != "" {
-		dataCheckArr = append(dataCheckArr, fmt.Sprintf("first_name=%s", authData.FirstName))
 		user.FirstName = authData.FirstName
 	}
 
 	if authData.LastName != "" {
-		dataCheckArr = append(dataCheckArr, fmt.Sprintf("last_name=%s", authData.LastName))
 		user.LastName = authData.LastName
 	}
-	
-	dataCheckArr = append(dataCheckArr, fmt.Sprintf("id=%d", authData.ID))
-
-	if authData.PhotoURL != "" {
-		dataCheckArr = append(dataCheckArr, fmt.Sprintf("photo_url=%s", authData.PhotoURL))
 
-		if user.AvatarURL == nil {
-			user.AvatarURL = &authData.PhotoURL
-		}
+	if authData.PhotoURL != "" && user.AvatarURL == nil {
+		user.AvatarURL = &authData.PhotoURL
 	}
 
 	if authData.Username != "" {
-		dataCheckArr = append(dataCheckArr, fmt.Sprintf("username=%s", authData.Username))
-
 		// check if username exists
-		_, err := app.sqlModels.Users.GetByUsername(authData.Username)
+		_, err := app.sqlModels.Users.GetByUsername(r.Context(), authData.Username)
 
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -1201,25 +1158,9 @@ func (app *application) checkTelegramAuthorization(w http.ResponseWriter, r *htt
 
 	}
 
-	sort.Strings(dataCheckArr)
-	dataCheckString := strings.Join(dataCheckArr, "\n")
-
-	secretKey := sha256.Sum256([]byte(app.config.Auth.TelegramBotToken)) // replace with your bot token
-
-	h := hmac.New(sha256.New, secretKey[:])
-	h.Write([]byte(dataCheckString))
-
-	hash := hex.EncodeToString(h.Sum(nil))
-
-	if hash != checkHash {
-		app.logger.Error(fmt.Errorf("hashes do not match: %s != %s", hash, checkHash), nil)
-		app.serverError(w, r, errors.New("Data is NOT from Telegram"))
-		return
-	}
-
-	if time.Now().Unix()-authData.AuthDate > 86400 {
-		app.logger.Error(fmt.Errorf("auth data is outdated: %d", time.Now().Unix()-authData.AuthDate), nil)
-		app.serverError(w, r, errors.New("Data is outdated"))
+	err = app.checkTelegramAuthData(authData, telegramAuthMaxAge)
+	if err != nil {
+		app.serverError(w, r, err)
 		return
 	}
 
@@ -1236,34 +1177,29 @@ func (app *application) checkTelegramAuthorization(w http.ResponseWriter, r *htt
 		Version:        1,
 	}
 
-	err = app.sqlModels.Users.InsertLinkedAccount(linkedAccount)
+	err = app.sqlModels.Users.InsertLinkedAccount(r.Context(), linkedAccount)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	// update user
-	_, err = app.sqlModels.Users.Update(user)
+	_, err = app.sqlModels.Users.Update(r.Context(), user)
 	if err != nil {
-		app.logger.Error(err, nil)
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	// check if user has two linked accounts
-	hasTwoAccounts, err := app.sqlModels.Users.HasTwoLinkedAccounts(user.ID)
+	hasTwoAccounts, err := app.sqlModels.Users.HasTwoLinkedAccounts(r.Context(), user.ID)
 	if err != nil {
 		fmt.Printf("Failed to check if user has two linked accounts: %s\n", err.Error())
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	// get auth nft by metadata id
-	authNft, err := app.sqlModels.Nfts.GetNFTMetadataByID(app.config.App.AuthMetadataID)
+	authNft, err := app.sqlModels.Nfts.GetNFTMetadataByID(r.Context(), app.config.App.AuthMetadataID)
 	if err != nil {
 		fmt.Printf("Failed to get auth nft: %s\n", err.Error())
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -1271,7 +1207,7 @@ func (app *application) checkTelegramAuthorization(w http.ResponseWriter, r *htt
 	}
 
 	// check if user has auth nft
-	hasAuthNft, err := app.sqlModels.Nfts.HasAuthNFT(user.FriendlyAddress, authNft.Base64)
+	hasAuthNft, err := app.sqlModels.Nfts.HasAuthNFT(r.Context(), user.FriendlyAddress, authNft.Base64)
 	if err != nil {
 		fmt.Printf("Failed to check if user has auth nft: %s\n", err.Error())
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -1289,7 +1225,7 @@ func (app *application) checkTelegramAuthorization(w http.ResponseWriter, r *htt
 			return
 		}
 
-		runGetReward := asynq.NewTask(database.TYPE_REWARD_FOR_LINKED_ACCOUNT, payload)
+		runGetReward := tasks.NewTask(r.Context(), database.TYPE_REWARD_FOR_LINKED_ACCOUNT, payload)
 
 		info, err := app.asynqClient.Enqueue(runGetReward, asynq.TaskID(fmt.Sprint("reward_auth", user.ID)), asynq.ProcessIn(10*time.Second), asynq.MaxRetry(5), asynq.ProcessIn(5*time.Second), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_URGENT))
 		if err != nil {
@@ -1297,7 +1233,7 @@ func (app *application) checkTelegramAuthorization(w http.ResponseWriter, r *htt
 			return
 		}
 
-		app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))
+		app.logger.Ctx(r.Context()).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
 
 	}
 
@@ -1319,7 +1255,6 @@ func (app *application) payloadHandler(w http.ResponseWriter, r *http.Request) {
 	payload, err := tonconnect.GeneratePayload(app.config.Ton.SharedSecret, ttl)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -1329,25 +1264,22 @@ func (app *application) payloadHandler(w http.ResponseWriter, r *http.Request) {
 	})
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
 func (app *application) getRolesHandler(w http.ResponseWriter, r *http.Request) {
 
-	roles, err := app.sqlModels.Permissions.GetAllRoles()
+	roles, err := app.sqlModels.Permissions.GetAllRoles(r.Context())
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	// get all permissions for these roles
 	for i, role := range roles {
-		permissions, err := app.sqlModels.Permissions.GetRolePermissions(role.ID)
+		permissions, err := app.sqlModels.Permissions.GetRolePermissions(r.Context(), role.ID)
 		if err != nil {
 			app.serverError(w, r, err)
-			app.logger.Error(err, nil)
 			return
 		}
 		roles[i].Permissions = permissions
@@ -1358,7 +1290,6 @@ func (app *application) getRolesHandler(w http.ResponseWriter, r *http.Request)
 	err = response.JSON(w, http.StatusOK, roles)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
@@ -1366,17 +1297,15 @@ func (app *application) getRolesHandler(w http.ResponseWriter, r *http.Request)
 // get all types of permissions
 func (app *application) getPermissionsHandler(w http.ResponseWriter, r *http.Request) {
 
-	permissions, err := app.sqlModels.Permissions.GetAllPermissions()
+	permissions, err := app.sqlModels.Permissions.GetAllPermissions(r.Context())
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	err = response.JSON(w, http.StatusOK, permissions)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
@@ -1386,21 +1315,18 @@ func (app *application) getRoleHandler(w http.ResponseWriter, r *http.Request) {
 	roleID, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
-	role, err := app.sqlModels.Permissions.GetRoleByID(roleID)
+	role, err := app.sqlModels.Permissions.GetRoleByID(r.Context(), roleID)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
-	permissions, err := app.sqlModels.Permissions.GetRolePermissions(role.ID)
+	permissions, err := app.sqlModels.Permissions.GetRolePermissions(r.Context(), role.ID)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -1419,7 +1345,6 @@ func (app *application) getRoleHandler(w http.ResponseWriter, r *http.Request) {
 	err = response.JSON(w, http.StatusOK, role)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
@@ -1435,7 +1360,6 @@ func (app *application) insertRoleHandler(w http.ResponseWriter, r *http.Request
 	err := request.DecodeJSON(w, r, &input)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -1444,17 +1368,15 @@ func (app *application) insertRoleHandler(w http.ResponseWriter, r *http.Request
 		Description: input.Description,
 	}
 
-	roleID, err := app.sqlModels.Permissions.InsertRole(role)
+	roleID, err := app.sqlModels.Permissions.InsertRole(r.Context(), role)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
-	err = app.sqlModels.Permissions.InsertRolePermissionsByIDs(roleID, input.Permissions)
+	err = app.sqlModels.Permissions.InsertRolePermissionsByIDs(r.Context(), roleID, input.Permissions)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -1463,7 +1385,6 @@ func (app *application) insertRoleHandler(w http.ResponseWriter, r *http.Request
 	})
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
@@ -1475,14 +1396,12 @@ func (app *application) deleteRolesHandler(w http.ResponseWriter, r *http.Reques
 	roleID, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
-	err = app.sqlModels.Permissions.DeleteRole(roleID)
+	err = app.sqlModels.Permissions.DeleteRole(r.Context(), roleID)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -1492,7 +1411,6 @@ func (app *application) deleteRolesHandler(w http.ResponseWriter, r *http.Reques
 
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
@@ -1508,22 +1426,19 @@ func (app *application) updateRoleHandler(w http.ResponseWriter, r *http.Request
 	err := request.DecodeJSON(w, r, &input)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	// delete old permissions for this role and get role by name
-	role, err := app.sqlModels.Permissions.GetRoleByName(input.Name)
+	role, err := app.sqlModels.Permissions.GetRoleByName(r.Context(), input.Name)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
-	err = app.sqlModels.Permissions.DeleteRolePermissions(role.ID)
+	err = app.sqlModels.Permissions.DeleteRolePermissions(r.Context(), role.ID)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -1531,18 +1446,16 @@ func (app *application) updateRoleHandler(w http.ResponseWriter, r *http.Request
 	role.Name = input.Name
 	role.Description = input.Description
 
-	err = app.sqlModels.Permissions.UpdateRole(role)
+	err = app.sqlModels.Permissions.UpdateRole(r.Context(), role)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
 	// insert new permissions
-	err = app.sqlModels.Permissions.InsertRolePermissionsByIDs(role.ID, input.Permissions)
+	err = app.sqlModels.Permissions.InsertRolePermissionsByIDs(r.Context(), role.ID, input.Permissions)
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 		return
 	}
 
@@ -1552,7 +1465,221 @@ func (app *application) updateRoleHandler(w http.ResponseWriter, r *http.Request
 
 	if err != nil {
 		app.serverError(w, r, err)
-		app.logger.Error(err, nil)
 	}
 
 }
+
+// create new permission, routes check permissions by name
+func (app *application) insertPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name   *string `json:"name"`
+		Route  *string `json:"route"`
+		Method *string `json:"method"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	v := validator.Validator{}
+
+	v.CheckField(input.Name != nil && strings.HasPrefix(*input.Name, "permissions:"), "name", "must start with permissions:")
+	v.CheckField(input.Route != nil && *input.Route != "", "route", "must be provided")
+	v.CheckField(input.Method != nil && *input.Method != "", "method", "must be provided")
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	permission := &database.Permission{
+		Name:   *input.Name,
+		Route:  *input.Route,
+		Method: strings.ToUpper(*input.Method),
+	}
+
+	permission.ID, err = app.sqlModels.Permissions.InsertPermission(r.Context(), permission)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			app.badRequest(w, r, errors.New("permission already exists"))
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusCreated, permission)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// replace roles of the user
+func (app *application) updateUserRolesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return
+	}
+
+	var input struct {
+		RoleIds []int64 `json:"role_ids"`
+	}
+
+	err = request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = app.sqlModels.Permissions.SetUserRoles(r.Context(), userID, input.RoleIds)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	roles, err := app.sqlModels.Permissions.GetUserRoles(r.Context(), userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, roles)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// get permissions granted to the user on single resources
+func (app *application) getUserGrantsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return
+	}
+
+	grants, err := app.sqlModels.Permissions.GetUserGrants(r.Context(), userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, grants)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// grant permission to the user on a single resource, e.g. mint into one collection
+func (app *application) insertUserGrantHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return
+	}
+
+	var input struct {
+		PermissionID *int64  `json:"permission_id"`
+		ResourceType *string `json:"resource_type"`
+		ResourceID   *int64  `json:"resource_id"`
+	}
+
+	err = request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	v := validator.Validator{}
+
+	v.CheckField(input.PermissionID != nil, "permission_id", "must be provided")
+	v.CheckField(input.ResourceType != nil && *input.ResourceType == database.ResourceCollection, "resource_type", "must be collection")
+	v.CheckField(input.ResourceID != nil, "resource_id", "must be provided")
+
+	if v.HasErrors() {
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	// only permissions whose routes check the resource can be granted on one
+	permission, err := app.sqlModels.Permissions.GetPermissionByID(r.Context(), *input.PermissionID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.badRequest(w, r, errors.New("permission not found"))
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	if database.ScopedPermissions[permission.Name] != *input.ResourceType {
+		v.AddFieldError("permission_id", "can't be granted on a single "+*input.ResourceType)
+		app.failedValidation(w, r, v)
+		return
+	}
+
+	_, err = app.sqlModels.Nfts.GetCollectionById(r.Context(), *input.ResourceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			app.badRequest(w, r, errors.New("collection not found"))
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	grant := &database.Grant{
+		UserID:       userID,
+		PermissionID: *input.PermissionID,
+		ResourceType: *input.ResourceType,
+		ResourceID:   *input.ResourceID,
+	}
+
+	grant.ID, err = app.sqlModels.Permissions.InsertGrant(r.Context(), grant)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			app.badRequest(w, r, errors.New("permission is already granted"))
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusCreated, grant)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteUserGrantHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(flow.Param(r.Context(), "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("id must be an integer"))
+		return
+	}
+
+	grantID, err := strconv.ParseInt(flow.Param(r.Context(), "grant_id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("grant_id must be an integer"))
+		return
+	}
+
+	err = app.sqlModels.Permissions.DeleteGrant(r.Context(), userID, grantID)
+	if err != nil {
+		if errors.Is(err, database.ErrRecordNotFound) {
+			app.notFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]string{
+		"status": "ok",
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
@@ -16,8 +16,18 @@ type Activity struct {
 	Description string `db:"description" json:"description"`
 	TokenThreshold int64 `db:"token_threshold" json:"token_threshold"`
 	SBTPrototypeID int64 `db:"sbt_prototype_id" json:"sbt_prototype_id"`
+	// rating or kudos, what token_threshold is compared with
+	Metric string `db:"metric" json:"metric"`
 }
 
+// metrics token_threshold of activities is compared with
+const (
+	MetricRating = "rating"
+	MetricKudos  = "kudos"
+)
+
+var ActivityMetrics = []string{MetricRating, MetricKudos}
+
 type UserActivity struct {
 	ID         int64 `db:"id" json:"id"`
 	UserID     int64 `db:"user_id" json:"user_id"`
@@ -119,11 +129,11 @@ func (m *ActivitiesModel) UpdateUserActivity(userID, activityID, sbtTokenID, poi
 
 // // insert see in backend\internal\database\users.go
 
-func (m *ActivitiesModel) Insert(name, description string, points int64, sbtId int64) (*Activity, error) {
+func (m *ActivitiesModel) Insert(name, description string, points int64, sbtId int64, metric string) (*Activity, error) {
 	
-	stmt := `INSERT INTO activities (name, description, token_threshold, sbt_prototype_id) VALUES ($1, $2, $3, $4) RETURNING *`
+	stmt := `INSERT INTO activities (name, description, token_threshold, sbt_prototype_id, metric) VALUES ($1, $2, $3, $4, $5) RETURNING *`
 
-	row := m.DB.QueryRow(stmt, name, description, points, sbtId)
+	row := m.DB.QueryRow(stmt, name, description, points, sbtId, metric)
 
 	var activity Activity
 
@@ -133,6 +143,7 @@ func (m *ActivitiesModel) Insert(name, description string, points int64, sbtId i
 		&activity.Description,
 		&activity.TokenThreshold,
 		&activity.SBTPrototypeID,
+		&activity.Metric,
 	)
 
 	if err != nil {
@@ -200,12 +211,12 @@ func (m *ActivitiesModel) GetByName(name string) (*Activity, error) {
 }
 
 // update
-func (m *ActivitiesModel) Update(id int64, name, description string, points int64) (*Activity, error) {
-	stmt := `UPDATE activities SET name = $1, description = $2, token_threshold = $3 WHERE id = $4 RETURNING *`
+func (m *ActivitiesModel) Update(id int64, name, description string, points int64, metric string) (*Activity, error) {
+	stmt := `UPDATE activities SET name = $1, description = $2, token_threshold = $3, metric = $4 WHERE id = $5 RETURNING *`
 
 	activity := &Activity{}
 
-	err := m.DB.Get(activity, stmt, name, description, points, id)
+	err := m.DB.Get(activity, stmt, name, description, points, metric, id)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditModel struct {
+	DB *sqlx.DB
+}
+
+type AuditLog struct {
+	ID           int64   `db:"id" json:"id"`
+	ActorID      *int64  `db:"actor_id" json:"actor_id"`
+	ActorAddress *string `db:"actor_address" json:"actor_address"`
+	Method       string  `db:"method" json:"method"`
+	Route        string  `db:"route" json:"route"`
+	TargetType   *string `db:"target_type" json:"target_type"`
+	TargetID     *string `db:"target_id" json:"target_id"`
+	Status       int     `db:"status" json:"status"`
+	Before       *JSONB  `db:"before" json:"before"`
+	After        *JSONB  `db:"after" json:"after"`
+	Diff         *JSONB  `db:"diff" json:"diff"`
+	RequestID    string  `db:"request_id" json:"request_id"`
+	IP           *string `db:"ip" json:"ip"`
+	CreatedAt    int64   `db:"created_at" json:"created_at"`
+}
+
+// AuditFilter holds optional filters for browsing the audit log
+type AuditFilter struct {
+	ActorID    *int64
+	TargetType string
+	TargetID   string
+	Method     string
+	From       int64
+	To         int64
+}
+
+// build where clause with positional args starting from $1
+func (f AuditFilter) where() (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.ActorID != nil {
+		add("actor_id = $%d", *f.ActorID)
+	}
+
+	if f.TargetType != "" {
+		add("target_type = $%d", f.TargetType)
+	}
+
+	if f.TargetID != "" {
+		add("target_id = $%d", f.TargetID)
+	}
+
+	if f.Method != "" {
+		add("method = $%d", strings.ToUpper(f.Method))
+	}
+
+	if f.From != 0 {
+		add("created_at >= $%d", f.From)
+	}
+
+	if f.To != 0 {
+		add("created_at <= $%d", f.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// insert audit record, records are never updated or deleted
+func (m *AuditModel) Insert(ctx context.Context, log *AuditLog) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO audit_logs (actor_id, actor_address, method, route, target_type, target_id, status, before, after, diff, request_id, ip, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id`
+
+	return m.DB.QueryRowContext(ctx, query,
+		log.ActorID,
+		log.ActorAddress,
+		log.Method,
+		log.Route,
+		log.TargetType,
+		log.TargetID,
+		log.Status,
+		log.Before,
+		log.After,
+		log.Diff,
+		log.RequestID,
+		log.IP,
+		log.CreatedAt,
+	).Scan(&log.ID)
+}
+
+// get audit records, newest first
+func (m *AuditModel) GetAll(ctx context.Context, pagination *Pagination, filter AuditFilter) ([]*AuditLog, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	where, args := filter.where()
+
+	query := fmt.Sprintf(`SELECT * FROM audit_logs %s ORDER BY id DESC LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+
+	args = append(args, pagination.End-pagination.Start, pagination.Start)
+
+	logs := []*AuditLog{}
+
+	err := m.DB.SelectContext(ctx, &logs, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// count audit records
+func (m *AuditModel) Count(ctx context.Context, filter AuditFilter) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	where, args := filter.where()
+
+	var count int64
+
+	err := m.DB.GetContext(ctx, &count, fmt.Sprintf(`SELECT COUNT(*) FROM audit_logs %s`, where), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrDuplicateChat = errors.New("duplicate chat")
+
+// Chat is a community chat served by the bot
+type Chat struct {
+	ID          int64   `db:"id" json:"id"`
+	Title       string  `db:"title" json:"title"`
+	Enabled     bool    `db:"enabled" json:"enabled"`
+	Weight      int     `db:"weight" json:"weight"`
+	Language    string  `db:"language" json:"language"`
+	Digest      bool    `db:"digest" json:"digest"`
+	WelcomeText *string `db:"welcome_text" json:"welcome_text"`
+	InviteURL   *string `db:"invite_url" json:"invite_url"`
+	CreatedAt   int64   `db:"created_at" json:"created_at"`
+	UpdatedAt   int64   `db:"updated_at" json:"updated_at"`
+	Version     int     `db:"version" json:"version"`
+}
+
+// ChatStats are scored messages of the chat in the period
+type ChatStats struct {
+	ChatID        int64  `db:"chat_id" json:"chat_id"`
+	Title         string `db:"title" json:"title"`
+	Messages      int64  `db:"messages" json:"messages"`
+	Users         int64  `db:"users" json:"users"`
+	Points        int64  `db:"points" json:"points"`
+	Bonus         int64  `db:"bonus" json:"bonus"`
+	LastMessageAt *int64 `db:"last_message_at" json:"last_message_at"`
+}
+
+type ChatModel struct {
+	DB *sqlx.DB
+}
+
+func (m *ChatModel) Insert(ctx context.Context, chat *Chat) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO tg_chats (id, title, enabled, weight, language, digest, welcome_text, invite_url, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		RETURNING created_at, updated_at, version`
+
+	err := m.DB.QueryRowContext(ctx, query,
+		chat.ID,
+		chat.Title,
+		chat.Enabled,
+		chat.Weight,
+		chat.Language,
+		chat.Digest,
+		chat.WelcomeText,
+		chat.InviteURL,
+		time.Now().Unix(),
+	).Scan(&chat.CreatedAt, &chat.UpdatedAt, &chat.Version)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrDuplicateChat
+		}
+		return err
+	}
+
+	return nil
+}
+
+// add enabled chat with default settings if it is not registered yet
+func (m *ChatModel) EnsureExists(ctx context.Context, id int64, title string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO tg_chats (id, title, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (id) DO NOTHING`
+
+	_, err := m.DB.ExecContext(ctx, query, id, title, time.Now().Unix())
+
+	return err
+}
+
+func (m *ChatModel) GetByID(ctx context.Context, id int64) (*Chat, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var chat Chat
+
+	err := m.DB.GetContext(ctx, &chat, `SELECT * FROM tg_chats WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &chat, nil
+}
+
+// get enabled chats the weekly digest is posted to
+func (m *ChatModel) GetDigestChats(ctx context.Context) ([]*Chat, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	chats := []*Chat{}
+
+	err := m.DB.SelectContext(ctx, &chats, `SELECT * FROM tg_chats WHERE enabled AND digest ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+// get every chat, used by the bot to refresh its registry
+func (m *ChatModel) GetAll(ctx context.Context) ([]*Chat, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	chats := []*Chat{}
+
+	err := m.DB.SelectContext(ctx, &chats, `SELECT * FROM tg_chats ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+func (m *ChatModel) GetPage(ctx context.Context, pagination *Pagination) ([]*Chat, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	chats := []*Chat{}
+
+	err := m.DB.SelectContext(ctx, &chats, `SELECT * FROM tg_chats ORDER BY created_at LIMIT $1 OFFSET $2`, pagination.End-pagination.Start, pagination.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+func (m *ChatModel) Count(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var count int64
+
+	err := m.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM tg_chats`)
+
+	return count, err
+}
+
+// update chat settings, ErrRecordNotFound is returned when the version doesn't match
+func (m *ChatModel) Update(ctx context.Context, chat *Chat) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE tg_chats
+		SET title = $1, enabled = $2, weight = $3, language = $4, digest = $5, welcome_text = $6, invite_url = $7, updated_at = $8, version = version + 1
+		WHERE id = $9 AND version = $10
+		RETURNING updated_at, version`
+
+	err := m.DB.QueryRowContext(ctx, query,
+		chat.Title,
+		chat.Enabled,
+		chat.Weight,
+		chat.Language,
+		chat.Digest,
+		chat.WelcomeText,
+		chat.InviteURL,
+		time.Now().Unix(),
+		chat.ID,
+		chat.Version,
+	).Scan(&chat.UpdatedAt, &chat.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// delete chat from the registry, scored messages are kept
+func (m *ChatModel) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM tg_chats WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// get scored messages of every registered chat created in [from, to)
+func (m *ChatModel) GetStats(ctx context.Context, from, to int64) ([]*ChatStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			c.id AS chat_id,
+			c.title,
+			COUNT(m.id) AS messages,
+			COUNT(DISTINCT m.user_id) AS users,
+			COALESCE(SUM(m.weight + m.bonus), 0) AS points,
+			COALESCE(SUM(m.bonus), 0) AS bonus,
+			MAX(m.created_at) AS last_message_at
+		FROM tg_chats c
+		LEFT JOIN tg_messages m ON m.chat_id = c.id AND m.created_at >= $1 AND m.created_at < $2
+		GROUP BY c.id, c.title
+		ORDER BY messages DESC, c.id`
+
+	stats := []*ChatStats{}
+
+	err := m.DB.SelectContext(ctx, &stats, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -26,11 +26,24 @@ const (
 	TYPE_ADD_COLLECTION  = "master:add_collection"
 	
 	TYPE_ADD_TG_MESSAGE  = "master:add_tg_message"
+	TYPE_ADD_DISCORD_MESSAGE  = "master:add_discord_message"
 	TYPE_REWARD_FOR_LINKED_ACCOUNT  = "master:reward_for_linked_account"
 	TYPE_ADD_REWARD_TO_ACCOUNT  = "master:add_reward_to_account"
 	TYPE_MINT_STORED_REWARDS  = "master:mint_stored_rewards"
+	TYPE_RECOVER_WALLET  = "master:recover_wallet"
+	TYPE_SEND_EMAIL  = "master:send_email"
+	TYPE_SEND_WEEKLY_DIGEST  = "master:send_weekly_digest"
+	TYPE_ADMIN_NOTIFICATION  = "master:admin_notification"
+	TYPE_CLEANUP_NOTIFICATIONS  = "master:cleanup_notifications"
+	TYPE_CHECK_RATING_REWARDS  = "master:check_rating_rewards"
 
 	TYPE_MIGRATE_NFT = "master:migrate_nft"
+
+	// served by the bot
+	TYPE_TG_REWARD_MINTED       = "bot:reward_minted"
+	TYPE_TG_ACHIEVEMENT_PENDING = "bot:achievement_pending"
+	TYPE_TG_REPLAY_UPDATE       = "bot:replay_update"
+	TYPE_TG_CHAT_DIGEST         = "bot:chat_digest"
 )
 
 const (
@@ -38,6 +51,9 @@ const (
 	PRIORITY_URGENT = "urgent"
 	PRIORITY_NORMAL = "normal"
 	PRIORITY_LOW = "low"	
+
+	// tasks of the bot are in a separate queue, the worker doesn't serve it
+	QUEUE_BOT = "bot"
 )
 
 type Pagination struct {
@@ -46,7 +62,7 @@ type Pagination struct {
 }
 
 func New(dsn string, automigrate bool) (*DB, error) {
-	db, err := sqlx.Connect("postgres", "postgres://"+dsn)
+	db, err := sqlx.Connect(tracedDriverName, "postgres://"+dsn)
 	if err != nil {
 		return nil, err
 	}
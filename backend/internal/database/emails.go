@@ -0,0 +1,265 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// events users can get notified about
+const (
+	NotifyNewSBT              = "new_sbt"
+	NotifyIncomingAchievement = "incoming_achievement"
+	NotifyRankChange          = "rank_change"
+	NotifyWeeklyDigest        = "weekly_digest"
+)
+
+// NotificationEvents holds default preference of every event, used when the user didn't change it
+var NotificationEvents = map[string]bool{
+	NotifyNewSBT:              true,
+	NotifyIncomingAchievement: true,
+	NotifyRankChange:          true,
+	NotifyWeeklyDigest:        false,
+}
+
+var ErrDuplicateEmail = errors.New("duplicate email")
+
+type UserEmail struct {
+	UserID     int64  `db:"user_id" json:"-"`
+	Email      string `db:"email" json:"email"`
+	Verified   bool   `db:"verified" json:"verified"`
+	VerifiedAt *int64 `db:"verified_at" json:"verified_at"`
+	CreatedAt  int64  `db:"created_at" json:"created_at"`
+	UpdatedAt  int64  `db:"updated_at" json:"updated_at"`
+	Version    int64  `db:"version" json:"-"`
+}
+
+// DigestStats is the content of the weekly digest email
+type DigestStats struct {
+	Rating        float64 `db:"rating"`
+	Position      int64   `db:"position"`
+	UsersCount    int64   `db:"users_count"`
+	NewRewards    int64   `db:"new_rewards"`
+	PendingCount  int64   `db:"pending_count"`
+	MessagesCount int64   `db:"messages_count"`
+}
+
+type EmailModel struct {
+	DB *sqlx.DB
+}
+
+// get email of the user
+func (m *EmailModel) Get(ctx context.Context, userID int64) (*UserEmail, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var email UserEmail
+
+	err := m.DB.GetContext(ctx, &email, `SELECT * FROM user_emails WHERE user_id = $1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+
+	return &email, err
+}
+
+// set email of the user, changed email has to be verified again
+func (m *EmailModel) Set(ctx context.Context, userID int64, address string) (*UserEmail, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO user_emails (user_id, email, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET email = EXCLUDED.email,
+			verified = user_emails.verified AND lower(user_emails.email) = lower(EXCLUDED.email),
+			verified_at = CASE WHEN lower(user_emails.email) = lower(EXCLUDED.email) THEN user_emails.verified_at END,
+			updated_at = EXCLUDED.updated_at,
+			version = user_emails.version + 1
+		RETURNING *`
+
+	var email UserEmail
+
+	err := m.DB.GetContext(ctx, &email, query, userID, address, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	return &email, nil
+}
+
+// mark email of the user as verified
+func (m *EmailModel) Verify(ctx context.Context, userID int64) (*UserEmail, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE user_emails
+		SET verified = true, verified_at = $2, updated_at = $2, version = version + 1
+		WHERE user_id = $1
+		RETURNING *`
+
+	var email UserEmail
+
+	err := m.DB.GetContext(ctx, &email, query, userID, time.Now().Unix())
+	if err != nil {
+		var pqErr *pq.Error
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		case errors.As(err, &pqErr) && pqErr.Code == "23505":
+			return nil, ErrDuplicateEmail
+		default:
+			return nil, err
+		}
+	}
+
+	return &email, nil
+}
+
+// delete email of the user, preferences are kept
+func (m *EmailModel) Delete(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM user_emails WHERE user_id = $1`, userID)
+	return err
+}
+
+// get notification preferences of the user, events without a row have the default value
+func (m *EmailModel) GetPreferences(ctx context.Context, userID int64) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, `SELECT event, email FROM notification_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	preferences := make(map[string]bool, len(NotificationEvents))
+
+	for event, enabled := range NotificationEvents {
+		preferences[event] = enabled
+	}
+
+	for rows.Next() {
+		var event string
+		var enabled bool
+
+		if err := rows.Scan(&event, &enabled); err != nil {
+			return nil, err
+		}
+
+		if _, ok := NotificationEvents[event]; ok {
+			preferences[event] = enabled
+		}
+	}
+
+	return preferences, rows.Err()
+}
+
+// update notification preferences of the user, events missing in preferences are not changed
+func (m *EmailModel) UpdatePreferences(ctx context.Context, userID int64, preferences map[string]bool) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO notification_preferences (user_id, event, email, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, event) DO UPDATE
+		SET email = EXCLUDED.email, updated_at = EXCLUDED.updated_at`
+
+	for event, enabled := range preferences {
+		_, err = tx.ExecContext(ctx, query, userID, event, enabled, time.Now().Unix())
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// get verified email of the user if email notifications about the event are enabled,
+// empty string means nothing should be sent
+func (m *EmailModel) GetRecipient(ctx context.Context, userID int64, event string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT e.email
+		FROM user_emails e
+		LEFT JOIN notification_preferences p ON p.user_id = e.user_id AND p.event = $2
+		WHERE e.user_id = $1 AND e.verified AND COALESCE(p.email, $3)`
+
+	var email string
+
+	err := m.DB.QueryRowContext(ctx, query, userID, event, NotificationEvents[event]).Scan(&email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return email, err
+}
+
+// get ids of users with verified email and enabled notifications about the event
+func (m *EmailModel) GetRecipientIDs(ctx context.Context, event string) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT e.user_id
+		FROM user_emails e
+		LEFT JOIN notification_preferences p ON p.user_id = e.user_id AND p.event = $1
+		WHERE e.verified AND COALESCE(p.email, $2)
+		ORDER BY e.user_id`
+
+	ids := []int64{}
+
+	err := m.DB.SelectContext(ctx, &ids, query, event, NotificationEvents[event])
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// get stats of the user for the weekly digest, rewards and messages are counted from since
+func (m *EmailModel) GetDigestStats(ctx context.Context, userID int64, since int64) (*DigestStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			u.rating,
+			(SELECT COUNT(*) FROM users WHERE rating > u.rating) + 1 AS position,
+			(SELECT COUNT(*) FROM users) AS users_count,
+			(SELECT COUNT(*) FROM rewards WHERE user_id = u.id AND created_at >= $2) AS new_rewards,
+			(SELECT COUNT(*) FROM stored_rewards WHERE user_address = u.friendly_address AND processed = false AND approved_by_user = false) AS pending_count,
+			(SELECT COUNT(*) FROM tg_messages m
+				INNER JOIN linked_accounts la ON la.telegram_user_id = m.user_id
+				WHERE la.user_id = u.id AND m.created_at >= $2) AS messages_count
+		FROM users u
+		WHERE u.id = $1`
+
+	var stats DigestStats
+
+	err := m.DB.GetContext(ctx, &stats, query, userID, since)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+
+	return &stats, err
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// FailedUpdate is a Telegram update the bot failed to process, Payload is the update as received
+type FailedUpdate struct {
+	ID         int64  `db:"id" json:"id"`
+	UpdateID   int64  `db:"update_id" json:"update_id"`
+	ChatID     *int64 `db:"chat_id" json:"chat_id"`
+	Kind       string `db:"kind" json:"kind"`
+	Payload    JSONB  `db:"payload" json:"payload"`
+	Error      string `db:"error" json:"error"`
+	Panicked   bool   `db:"panicked" json:"panicked"`
+	Attempts   int    `db:"attempts" json:"attempts"`
+	CreatedAt  int64  `db:"created_at" json:"created_at"`
+	ReplayedAt *int64 `db:"replayed_at" json:"replayed_at"`
+}
+
+type FailedUpdateModel struct {
+	DB *sqlx.DB
+}
+
+func (m *FailedUpdateModel) Insert(ctx context.Context, update *FailedUpdate) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO tg_failed_updates (update_id, chat_id, kind, payload, error, panicked, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, attempts, created_at`
+
+	return m.DB.QueryRowContext(ctx, query,
+		update.UpdateID,
+		update.ChatID,
+		update.Kind,
+		update.Payload,
+		update.Error,
+		update.Panicked,
+		time.Now().Unix(),
+	).Scan(&update.ID, &update.Attempts, &update.CreatedAt)
+}
+
+func (m *FailedUpdateModel) GetByID(ctx context.Context, id int64) (*FailedUpdate, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var update FailedUpdate
+
+	err := m.DB.GetContext(ctx, &update, `SELECT * FROM tg_failed_updates WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &update, nil
+}
+
+// get failed updates, newest first, replayed ones are skipped unless all is true
+func (m *FailedUpdateModel) GetPage(ctx context.Context, pagination *Pagination, all bool) ([]*FailedUpdate, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT * FROM tg_failed_updates
+		WHERE $1 OR replayed_at IS NULL
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3`
+
+	updates := []*FailedUpdate{}
+
+	err := m.DB.SelectContext(ctx, &updates, query, all, pagination.End-pagination.Start, pagination.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	return updates, nil
+}
+
+func (m *FailedUpdateModel) Count(ctx context.Context, all bool) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var count int64
+
+	err := m.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM tg_failed_updates WHERE $1 OR replayed_at IS NULL`, all)
+
+	return count, err
+}
+
+// record result of a replay, the error is kept when the replay succeeds
+func (m *FailedUpdateModel) RecordReplay(ctx context.Context, id int64, replayErr error) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var err error
+
+	if replayErr == nil {
+		_, err = m.DB.ExecContext(ctx, `UPDATE tg_failed_updates SET attempts = attempts + 1, replayed_at = $1 WHERE id = $2`, time.Now().Unix(), id)
+	} else {
+		_, err = m.DB.ExecContext(ctx, `UPDATE tg_failed_updates SET attempts = attempts + 1, error = $1 WHERE id = $2`, replayErr.Error(), id)
+	}
+
+	return err
+}
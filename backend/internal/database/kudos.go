@@ -0,0 +1,246 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// sources of kudos, kudos from the bot have the provider of the chat platform
+const (
+	KudosSourceTelegram = ProviderTelegram
+	KudosSourceDiscord  = ProviderDiscord
+	KudosSourceAPI      = "api"
+)
+
+var (
+	ErrKudosSelf         = errors.New("kudos to yourself")
+	ErrKudosDailyLimit   = errors.New("daily kudos limit reached")
+	ErrKudosAlreadyGiven = errors.New("kudos already given")
+)
+
+// Kudos is a thanks of one user to another, Weight is the rating the receiver got for it
+type Kudos struct {
+	ID               int64  `db:"id" json:"id"`
+	GiverID          int64  `db:"giver_id" json:"giver_id"`
+	GiverUsername    string `db:"giver_username" json:"giver_username"`
+	ReceiverID       int64  `db:"receiver_id" json:"receiver_id"`
+	ReceiverUsername string `db:"receiver_username" json:"receiver_username"`
+	Source           string `db:"source" json:"source"`
+	ChatID           *int64 `db:"chat_id" json:"chat_id"`
+	MessageID        *int64 `db:"message_id" json:"message_id"`
+	Weight           int64  `db:"weight" json:"weight"`
+	Reciprocal       bool   `db:"reciprocal" json:"reciprocal"`
+	CreatedAt        int64  `db:"created_at" json:"created_at"`
+}
+
+// KudosPolicy limits kudos a user gives, see KUDOS_* variables
+type KudosPolicy struct {
+	Weight     int64
+	DailyLimit int
+	// kudos back to a user who gave one within the window is reciprocal
+	ReciprocalWindow time.Duration
+}
+
+type KudosModel struct {
+	DB *sqlx.DB
+}
+
+const kudosQuery = `
+	SELECT k.*, g.username AS giver_username, r.username AS receiver_username
+	FROM kudos k
+	JOIN users g ON g.id = k.giver_id
+	JOIN users r ON r.id = k.receiver_id`
+
+// Give stores the kudos and adds its weight to the rating of the receiver. A user thanks
+// another user once a day and gives at most policy.DailyLimit kudos per UTC day. Kudos back
+// to a user who thanked the giver within policy.ReciprocalWindow is stored as reciprocal
+// with no weight, so two users can't farm rating by thanking each other.
+func (m *KudosModel) Give(ctx context.Context, kudos *Kudos, policy KudosPolicy) error {
+	if kudos.GiverID == kudos.ReceiverID {
+		return ErrKudosSelf
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// kudos between two users are given one at a time, so neither the limits nor the reciprocal
+	// check can be passed by parallel requests, A→B and B→A at once included. Rows are locked in
+	// id order, so the two requests don't deadlock.
+	_, err = tx.ExecContext(ctx, `SELECT id FROM users WHERE id IN ($1, $2) ORDER BY id FOR UPDATE`, kudos.GiverID, kudos.ReceiverID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	dayStart := now.Truncate(24 * time.Hour).Unix()
+
+	var given, givenToReceiver int
+
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE receiver_id = $2)
+		FROM kudos
+		WHERE giver_id = $1 AND created_at >= $3`
+
+	err = tx.QueryRowContext(ctx, query, kudos.GiverID, kudos.ReceiverID, dayStart).Scan(&given, &givenToReceiver)
+	if err != nil {
+		return err
+	}
+
+	if givenToReceiver > 0 {
+		return ErrKudosAlreadyGiven
+	}
+
+	if given >= policy.DailyLimit {
+		return ErrKudosDailyLimit
+	}
+
+	query = `
+		SELECT EXISTS (
+			SELECT 1 FROM kudos
+			WHERE giver_id = $1 AND receiver_id = $2 AND created_at >= $3
+		)`
+
+	err = tx.QueryRowContext(ctx, query, kudos.ReceiverID, kudos.GiverID, now.Add(-policy.ReciprocalWindow).Unix()).Scan(&kudos.Reciprocal)
+	if err != nil {
+		return err
+	}
+
+	kudos.Weight = policy.Weight
+	if kudos.Reciprocal {
+		kudos.Weight = 0
+	}
+
+	query = `
+		INSERT INTO kudos (giver_id, receiver_id, source, chat_id, message_id, weight, reciprocal, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		kudos.GiverID,
+		kudos.ReceiverID,
+		kudos.Source,
+		kudos.ChatID,
+		kudos.MessageID,
+		kudos.Weight,
+		kudos.Reciprocal,
+		now.Unix(),
+	).Scan(&kudos.ID, &kudos.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrKudosAlreadyGiven
+		}
+		return err
+	}
+
+	if kudos.Weight > 0 {
+		_, err = tx.ExecContext(ctx, `UPDATE users SET rating = rating + $1 WHERE id = $2`, kudos.Weight, kudos.ReceiverID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *KudosModel) GetByID(ctx context.Context, id int64) (*Kudos, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var kudos Kudos
+
+	err := m.DB.GetContext(ctx, &kudos, kudosQuery+` WHERE k.id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &kudos, nil
+}
+
+// get kudos, newest first, only reciprocal ones when reciprocal is true
+func (m *KudosModel) GetPage(ctx context.Context, pagination *Pagination, reciprocal bool) ([]*Kudos, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := kudosQuery + `
+		WHERE NOT $1 OR k.reciprocal
+		ORDER BY k.id DESC
+		LIMIT $2 OFFSET $3`
+
+	kudos := []*Kudos{}
+
+	err := m.DB.SelectContext(ctx, &kudos, query, reciprocal, pagination.End-pagination.Start, pagination.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	return kudos, nil
+}
+
+func (m *KudosModel) Count(ctx context.Context, reciprocal bool) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var count int64
+
+	err := m.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM kudos WHERE NOT $1 OR reciprocal`, reciprocal)
+
+	return count, err
+}
+
+// count kudos received by the user, reciprocal kudos are not counted
+func (m *KudosModel) CountReceived(ctx context.Context, userID int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var count int64
+
+	err := m.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM kudos WHERE receiver_id = $1 AND NOT reciprocal`, userID)
+
+	return count, err
+}
+
+// Delete revokes the kudos, its weight is taken from the rating of the receiver
+func (m *KudosModel) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var receiverID, weight int64
+
+	err = tx.QueryRowContext(ctx, `DELETE FROM kudos WHERE id = $1 RETURNING receiver_id, weight`, id).Scan(&receiverID, &weight)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+
+	if weight > 0 {
+		_, err = tx.ExecContext(ctx, `UPDATE users SET rating = rating - $1 WHERE id = $2`, weight, receiverID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LeaderboardEntry is a place in the leaderboard, PreviousRank is nil when the user had no points
+// in the previous period
+type LeaderboardEntry struct {
+	UserID       int64  `db:"user_id" json:"user_id"`
+	Username     string `db:"username" json:"username"`
+	Points       int64  `db:"points" json:"points"`
+	Rank         int64  `db:"rank" json:"rank"`
+	PreviousRank *int64 `db:"previous_rank" json:"previous_rank"`
+}
+
+// IssuedSBT is an SBT added to profiles in the period
+type IssuedSBT struct {
+	Name  string `db:"name" json:"name"`
+	Count int64  `db:"count" json:"count"`
+}
+
+type LeaderboardModel struct {
+	DB *sqlx.DB
+}
+
+// ranks by rating now and before rewards added since $1, users without rating are skipped
+const ratingRanksQuery = `
+	WITH gained AS (
+		SELECT r.user_id, SUM(t.weight) AS points
+		FROM rewards r
+		INNER JOIN sbt_tokens t ON t.id = r.sbt_token_id
+		WHERE r.created_at >= $1
+		GROUP BY r.user_id
+	), ranked AS (
+		SELECT
+			u.id AS user_id,
+			u.username,
+			u.rating AS points,
+			u.rating - COALESCE(g.points, 0) AS previous_points,
+			RANK() OVER (ORDER BY u.rating DESC) AS rank,
+			RANK() OVER (ORDER BY u.rating - COALESCE(g.points, 0) DESC) AS previous_rank
+		FROM users u
+		LEFT JOIN gained g ON g.user_id = u.id
+	)
+	SELECT user_id, username, points, rank, CASE WHEN previous_points > 0 THEN previous_rank END AS previous_rank
+	FROM ranked
+	WHERE points > 0`
+
+// get top users by rating, previous rank is the rank before rewards added since
+func (m *LeaderboardModel) GetRatingTop(ctx context.Context, since int64, limit int) ([]*LeaderboardEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	entries := []*LeaderboardEntry{}
+
+	err := m.DB.SelectContext(ctx, &entries, ratingRanksQuery+` ORDER BY rank, user_id LIMIT $2`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// get users who moved up the most by rating since
+func (m *LeaderboardModel) GetMovers(ctx context.Context, since int64, limit int) ([]*LeaderboardEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := ratingRanksQuery + `
+		AND previous_points > 0 AND ranked.previous_rank > rank
+		ORDER BY ranked.previous_rank - rank DESC, rank
+		LIMIT $2`
+
+	entries := []*LeaderboardEntry{}
+
+	err := m.DB.SelectContext(ctx, &entries, query, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// get top users by points of rewards added in [from, to), previous rank is the rank in the
+// period of the same length before from
+func (m *LeaderboardModel) GetPeriodTop(ctx context.Context, from, to int64, limit int) ([]*LeaderboardEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		WITH gained AS (
+			SELECT
+				r.user_id,
+				COALESCE(SUM(t.weight) FILTER (WHERE r.created_at >= $1), 0) AS points,
+				COALESCE(SUM(t.weight) FILTER (WHERE r.created_at < $1), 0) AS previous_points
+			FROM rewards r
+			INNER JOIN sbt_tokens t ON t.id = r.sbt_token_id
+			WHERE r.created_at >= $1 - ($2 - $1) AND r.created_at < $2
+			GROUP BY r.user_id
+		), ranked AS (
+			SELECT
+				g.user_id,
+				u.username,
+				g.points,
+				g.previous_points,
+				RANK() OVER (ORDER BY g.points DESC) AS rank,
+				RANK() OVER (ORDER BY g.previous_points DESC) AS previous_rank
+			FROM gained g
+			INNER JOIN users u ON u.id = g.user_id
+		)
+		SELECT user_id, username, points, rank, CASE WHEN previous_points > 0 THEN previous_rank END AS previous_rank
+		FROM ranked
+		WHERE points > 0
+		ORDER BY rank, user_id
+		LIMIT $3`
+
+	entries := []*LeaderboardEntry{}
+
+	err := m.DB.SelectContext(ctx, &entries, query, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// get SBTs added to profiles since, the most issued first
+func (m *LeaderboardModel) GetIssuedSBTs(ctx context.Context, since int64, limit int) ([]*IssuedSBT, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT t.name, COUNT(*) AS count
+		FROM rewards r
+		INNER JOIN sbt_tokens t ON t.id = r.sbt_token_id
+		WHERE r.created_at >= $1
+		GROUP BY t.name
+		ORDER BY count DESC, t.name
+		LIMIT $2`
+
+	sbts := []*IssuedSBT{}
+
+	err := m.DB.SelectContext(ctx, &sbts, query, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return sbts, nil
+}
+
+// get usernames of the first users who joined since and the number of all of them
+func (m *LeaderboardModel) GetNewcomers(ctx context.Context, since int64, limit int) ([]string, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var count int64
+
+	err := m.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM users WHERE created_at >= $1`, since)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	usernames := []string{}
+
+	err = m.DB.SelectContext(ctx, &usernames, `SELECT username FROM users WHERE created_at >= $1 ORDER BY created_at, id LIMIT $2`, since, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return usernames, count, nil
+}
@@ -9,6 +9,16 @@ type Models struct {
 	Activities ActivitiesModel
 	Permissions PermissionModel
 	Rewards RewardModel
+	Recoveries RecoveryModel
+	Audit AuditModel
+	ServiceAccounts ServiceAccountModel
+	Emails EmailModel
+	Notifications NotificationModel
+	Moderation ModerationModel
+	Chats ChatModel
+	FailedUpdates FailedUpdateModel
+	Leaderboard LeaderboardModel
+	Kudos KudosModel
 }
 
 func NewModels(db *sqlx.DB) Models {
@@ -19,5 +29,15 @@ func NewModels(db *sqlx.DB) Models {
 		Activities: ActivitiesModel{DB: db},
 		Permissions: PermissionModel{DB: db},
 		Rewards: RewardModel{DB: db},
+		Recoveries: RecoveryModel{DB: db},
+		Audit: AuditModel{DB: db},
+		ServiceAccounts: ServiceAccountModel{DB: db},
+		Emails: EmailModel{DB: db},
+		Notifications: NotificationModel{DB: db},
+		Moderation: ModerationModel{DB: db},
+		Chats: ChatModel{DB: db},
+		FailedUpdates: FailedUpdateModel{DB: db},
+		Leaderboard: LeaderboardModel{DB: db},
+		Kudos: KudosModel{DB: db},
 	}
 }
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// BotStats is shown to moderators by the /stats command
+type BotStats struct {
+	Users               int64 `db:"users"`
+	TelegramUsers       int64 `db:"telegram_users"`
+	MessagesToday       int64 `db:"messages_today"`
+	PendingAchievements int64 `db:"pending_achievements"`
+	MutedUsers          int64 `db:"muted_users"`
+}
+
+type ModerationModel struct {
+	DB *sqlx.DB
+}
+
+// stop scoring Telegram messages of the user
+func (m *ModerationModel) Mute(ctx context.Context, userID, mutedBy int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO tg_muted_users (user_id, muted_by, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO NOTHING`
+
+	_, err := m.DB.ExecContext(ctx, query, userID, mutedBy, time.Now().Unix())
+
+	return err
+}
+
+// score Telegram messages of the user again, returns false if the user wasn't muted
+func (m *ModerationModel) Unmute(ctx context.Context, userID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM tg_muted_users WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
+
+func (m *ModerationModel) IsMuted(ctx context.Context, userID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var muted bool
+
+	err := m.DB.GetContext(ctx, &muted, `SELECT EXISTS (SELECT 1 FROM tg_muted_users WHERE user_id = $1)`, userID)
+
+	return muted, err
+}
+
+// get numbers for the /stats command, messages are counted since the start of the day in UTC
+func (m *ModerationModel) GetStats(ctx context.Context) (*BotStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM users) AS users,
+			(SELECT COUNT(*) FROM linked_accounts WHERE provider = 'telegram') AS telegram_users,
+			(SELECT COUNT(*) FROM tg_messages WHERE created_at >= $1) AS messages_today,
+			(SELECT COUNT(*) FROM stored_rewards WHERE processed = false AND approved_by_user = false) AS pending_achievements,
+			(SELECT COUNT(*) FROM tg_muted_users) AS muted_users`
+
+	today := time.Now().UTC().Truncate(24 * time.Hour).Unix()
+
+	var stats BotStats
+
+	err := m.DB.GetContext(ctx, &stats, query, today)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type NftsModel struct {
@@ -225,8 +226,8 @@ type SBTPrototype struct {
 }
 
 // update weight of prototype
-func (m *NftsModel) UpdatePrototypeWeight(id int64, weight int64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) UpdatePrototypeWeight(ctx context.Context, id int64, weight int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -244,8 +245,8 @@ func (m *NftsModel) UpdatePrototypeWeight(id int64, weight int64) error {
 }
 
 // delete prototype
-func (m *NftsModel) DeletePrototype(id int64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) DeletePrototype(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -273,8 +274,8 @@ func (m *NftsModel) DeletePrototype(id int64) error {
 
 
 // insert sbt prototype into database
-func (m *NftsModel) InsertPrototype(prototype *SBTPrototype) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) InsertPrototype(ctx context.Context, prototype *SBTPrototype) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -364,8 +365,8 @@ func (m *NftsModel) InsertPrototype(prototype *SBTPrototype) error {
 // }
 
 // get prototypes from database
-func (m *NftsModel) GetPrototypes(pagination *Pagination, filters, sort string) ([]*SBTPrototype, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetPrototypes(ctx context.Context, pagination *Pagination, filters, sort string) ([]*SBTPrototype, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := fmt.Sprintf(`
@@ -433,8 +434,8 @@ type NFTMetadata struct {
 	Version      int64   `db:"version" json:"version,omitempty"`
 }
 
-func (m *NftsModel) InsertNFTMetadata(metadata *NFTMetadata) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) InsertNFTMetadata(ctx context.Context, metadata *NFTMetadata) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -466,8 +467,8 @@ func (m *NftsModel) InsertNFTMetadata(metadata *NFTMetadata) error {
 }
 
 // update nft metadata
-func (m *NftsModel) UpdateNFTMetadata(metadata *NFTMetadata) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) UpdateNFTMetadata(ctx context.Context, metadata *NFTMetadata) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -517,8 +518,8 @@ func (m *NftsModel) UpdateNFTMetadata(metadata *NFTMetadata) error {
 // }
 
 // update collection metadata: name, description and image
-func (m *NftsModel) UpdateCollectionMetadata(metadata *CollectionMetadata, base64 string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) UpdateCollectionMetadata(ctx context.Context, metadata *CollectionMetadata, base64 string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -537,8 +538,8 @@ func (m *NftsModel) UpdateCollectionMetadata(metadata *CollectionMetadata, base6
 }
 
 // insert collection metadata
-func (m *NftsModel) InsertCollectionMetadata(metadata *CollectionMetadata) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) InsertCollectionMetadata(ctx context.Context, metadata *CollectionMetadata) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -570,8 +571,8 @@ func (m *NftsModel) InsertCollectionMetadata(metadata *CollectionMetadata) error
 }
 
 // get collection metadata by base64
-func (m *NftsModel) GetCollectionMetadataByBase64(base64 string) (*CollectionMetadata, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetCollectionMetadataByBase64(ctx context.Context, base64 string) (*CollectionMetadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -609,8 +610,8 @@ func (m *NftsModel) GetCollectionMetadataByBase64(base64 string) (*CollectionMet
 
 
 // update metadata
-func (m *NftsModel) UpdateAttributesMetadata(metadata *NFTMetadata) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) UpdateAttributesMetadata(ctx context.Context, metadata *NFTMetadata) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -645,8 +646,8 @@ func (m *NftsModel) UpdateAttributesMetadata(metadata *NFTMetadata) error {
 		
 
 // get metadata by base64
-func (m *NftsModel) GetNFTMetadataByBase64(base64String string) (*NFTMetadata, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetNFTMetadataByBase64(ctx context.Context, base64String string) (*NFTMetadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -682,8 +683,8 @@ func (m *NftsModel) GetNFTMetadataByBase64(base64String string) (*NFTMetadata, e
 }
 
 // get prototype weight by base64 of nft
-func (m *NftsModel) GetWeightByBase64(base64String string) (int64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetWeightByBase64(ctx context.Context, base64String string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -712,8 +713,8 @@ func (m *NftsModel) GetWeightByBase64(base64String string) (int64, error) {
 
 }
 
-func (m *NftsModel) GetNFTMetadataByPrototypeID(prototypeID int64) (*NFTMetadata, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetNFTMetadataByPrototypeID(ctx context.Context, prototypeID int64) (*NFTMetadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -746,9 +747,9 @@ func (m *NftsModel) GetNFTMetadataByPrototypeID(prototypeID int64) (*NFTMetadata
 	return &metadata, nil
 }
 
-// get prototype by user rating
-func (m *NftsModel) GetPrototypesByRating(userId int64) ([]*NFTMetadata, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+// get prototypes the user earned by rating or received kudos, see metric of activities
+func (m *NftsModel) GetPrototypesByRating(ctx context.Context, userId int64) ([]*NFTMetadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -757,7 +758,12 @@ func (m *NftsModel) GetPrototypesByRating(userId int64) ([]*NFTMetadata, error)
 	JOIN sbt_prototype sp ON nm.ID = sp.metadata_id
 	JOIN activities a ON sp.id = a.id
 	JOIN users u ON u.id = $1
-	WHERE u.rating >= a.token_threshold AND NOT EXISTS (
+	WHERE CASE a.metric
+		WHEN 'kudos' THEN (
+			SELECT COUNT(*) FROM kudos k WHERE k.receiver_id = u.id AND NOT k.reciprocal
+		) >= a.token_threshold
+		ELSE u.rating >= a.token_threshold
+	END AND NOT EXISTS (
 		SELECT 1 
 		FROM sbt_tokens
 		WHERE friendly_owner_address = u.friendly_address
@@ -821,8 +827,8 @@ func (m *NftsModel) GetPrototypesByRating(userId int64) ([]*NFTMetadata, error)
 
 // check if user has certain nft by checking content_json (jsonb) field
 
-func (m *NftsModel) CheckIfUserHasNFTByContentJSON(addr string, metadataID int64) (bool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) CheckIfUserHasNFTByContentJSON(ctx context.Context, addr string, metadataID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -846,8 +852,8 @@ func (m *NftsModel) CheckIfUserHasNFTByContentJSON(addr string, metadataID int64
 
 
 // get nft metadata by id
-func (m *NftsModel) GetNFTMetadataByID(id int64) (*NFTMetadata, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetNFTMetadataByID(ctx context.Context, id int64) (*NFTMetadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -882,8 +888,8 @@ func (m *NftsModel) GetNFTMetadataByID(id int64) (*NFTMetadata, error) {
 
 
 // insert collection into database
-func (m *NftsModel) InsertCollection(collection *SBTCollection) (*SBTCollection, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) InsertCollection(ctx context.Context, collection *SBTCollection) (*SBTCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -920,8 +926,8 @@ func (m *NftsModel) InsertCollection(collection *SBTCollection) (*SBTCollection,
 }
 	
 
-func (m *NftsModel) GetCollectionsByOwnerAddress(pagination *Pagination, ownerAddr string) ([]*SBTCollection, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetCollectionsByOwnerAddress(ctx context.Context, pagination *Pagination, ownerAddr string) ([]*SBTCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := fmt.Sprintf(`
@@ -974,8 +980,8 @@ func (m *NftsModel) GetCollectionsByOwnerAddress(pagination *Pagination, ownerAd
 
 	return collections, nil
 }
-func (m *NftsModel) GetAllCollections(pagination *Pagination) ([]*SBTCollection, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetAllCollections(ctx context.Context, pagination *Pagination) ([]*SBTCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := fmt.Sprintf(`
@@ -1031,8 +1037,8 @@ func (m *NftsModel) GetAllCollections(pagination *Pagination) ([]*SBTCollection,
 
 // get collection by address
 
-func (m *NftsModel) GetCollectionByAddress(address string) (*SBTCollection, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetCollectionByAddress(ctx context.Context, address string) (*SBTCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1071,8 +1077,8 @@ func (m *NftsModel) GetCollectionByAddress(address string) (*SBTCollection, erro
 }
 
 // get collection by nft address
-func (m *NftsModel) GetCollectionByNftAddress(address string) (*SBTCollection, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetCollectionByNftAddress(ctx context.Context, address string) (*SBTCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1114,8 +1120,8 @@ func (m *NftsModel) GetCollectionByNftAddress(address string) (*SBTCollection, e
 
 
 // get tokens from database
-func (m *NftsModel) GetTokens(pagination *Pagination, addr string) ([]*SBTToken, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetTokens(ctx context.Context, pagination *Pagination, addr string) ([]*SBTToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	var rows *sql.Rows
@@ -1191,8 +1197,8 @@ func (m *NftsModel) GetTokens(pagination *Pagination, addr string) ([]*SBTToken,
 
 
 // get token by id
-func (m *NftsModel) GetTokenByID(id int64) (*SBTToken, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetTokenByID(ctx context.Context, id int64) (*SBTToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1235,8 +1241,8 @@ func (m *NftsModel) GetTokenByID(id int64) (*SBTToken, error) {
 }
 
 // get token by address
-func (m *NftsModel) GetTokenByAddress(address string) (*SBTToken, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetTokenByAddress(ctx context.Context, address string) (*SBTToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1282,8 +1288,8 @@ func (m *NftsModel) GetTokenByAddress(address string) (*SBTToken, error) {
 
 // check if user has sbt with name Auth NFT
 
-func (m *NftsModel) HasAuthNFT(address, base64 string) (bool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) HasAuthNFT(ctx context.Context, address, base64 string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	contentUri := fmt.Sprintf("https://tdp.tonbuilders.com/v1/deployed-nft/n/%s/meta.json", base64)
@@ -1308,8 +1314,8 @@ func (m *NftsModel) HasAuthNFT(address, base64 string) (bool, error) {
 }
 
 // pin nft
-func (m *NftsModel) PinNFT(id int64, pin bool) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) PinNFT(ctx context.Context, id int64, pin bool) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1328,8 +1334,8 @@ func (m *NftsModel) PinNFT(id int64, pin bool) error {
 
 
 // get sbt tokens by owner address 
-func (m *NftsModel) GetTokensByOwnerAddress(address string, pagination *Pagination) ([]*SBTToken, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetTokensByOwnerAddress(ctx context.Context, address string, pagination *Pagination) ([]*SBTToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1389,8 +1395,8 @@ func (m *NftsModel) GetTokensByOwnerAddress(address string, pagination *Paginati
 }
 
 // get date of last token created
-func (m *NftsModel) GetLastTokenCreated(address string) (string, string, int64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetLastTokenCreated(ctx context.Context, address string) (string, string, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1421,8 +1427,8 @@ func (m *NftsModel) GetLastTokenCreated(address string) (string, string, int64,
 }
 
 // get total number of tokens
-func (m *NftsModel) GetTotalTokensByOwnerAddress(address string) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetTotalTokensByOwnerAddress(ctx context.Context, address string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1446,8 +1452,8 @@ func (m *NftsModel) GetTotalTokensByOwnerAddress(address string) (int, error) {
 
 
 // get total number of tokens
-func (m *NftsModel) GetTotal(table, column, filters string) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetTotal(ctx context.Context, table, column, filters string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := fmt.Sprintf(`
@@ -1469,8 +1475,8 @@ func (m *NftsModel) GetTotal(table, column, filters string) (int, error) {
 }
 
 // delete collection from database
-func (m *NftsModel) DeleteCollection(id int64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) DeleteCollection(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	// and delete all tokens from this collection
@@ -1498,8 +1504,8 @@ func (m *NftsModel) DeleteCollection(id int64) error {
 }
 
 // delete token from database
-func (m *NftsModel) DeleteToken(id int64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) DeleteToken(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1516,8 +1522,8 @@ func (m *NftsModel) DeleteToken(id int64) error {
 }
 
 // update collection in database
-func (m *NftsModel) UpdateCollection(collection *SBTCollection) (*SBTCollection, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) UpdateCollection(ctx context.Context, collection *SBTCollection) (*SBTCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1555,8 +1561,8 @@ func (m *NftsModel) UpdateCollection(collection *SBTCollection) (*SBTCollection,
 }
 
 // update next item index in database
-func (m *NftsModel) UpdateNextItemIndex(collectionAddr string, nextItem int64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) UpdateNextItemIndex(ctx context.Context, collectionAddr string, nextItem int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1577,8 +1583,8 @@ func (m *NftsModel) UpdateNextItemIndex(collectionAddr string, nextItem int64) e
 }
 
 // update token in database
-func (m *NftsModel) UpdateToken(token *SBTToken) (*SBTToken, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) UpdateToken(ctx context.Context, token *SBTToken) (*SBTToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1618,8 +1624,8 @@ func (m *NftsModel) UpdateToken(token *SBTToken) (*SBTToken, error) {
 }
 
 // get collection by id
-func (m *NftsModel) GetCollectionById(id int64) (*SBTCollection, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *NftsModel) GetCollectionById(ctx context.Context, id int64) (*SBTCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -1657,3 +1663,43 @@ func (m *NftsModel) GetCollectionById(id int64) (*SBTCollection, error) {
 	return &collection, nil
 }
 
+// get ids of prototypes from the list that the address holds at least one sbt of
+func (m *NftsModel) GetOwnedPrototypeIDs(ctx context.Context, ownerAddress string, prototypeIDs []int64) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT DISTINCT p.id
+		FROM sbt_prototype p
+		INNER JOIN sbt_tokens t ON t.content_json->>'id' = p.metadata_id::text
+		WHERE t.friendly_owner_address = $1 AND p.id = ANY($2)`
+
+	ids := []int64{}
+
+	err := m.DB.SelectContext(ctx, &ids, query, ownerAddress, pq.Array(prototypeIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// get ids of collections from the list that the address holds at least one sbt of
+func (m *NftsModel) GetOwnedCollectionIDs(ctx context.Context, ownerAddress string, collectionIDs []int64) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT DISTINCT sbt_collections_id
+		FROM sbt_tokens
+		WHERE friendly_owner_address = $1 AND sbt_collections_id = ANY($2)`
+
+	ids := []int64{}
+
+	err := m.DB.SelectContext(ctx, &ids, query, ownerAddress, pq.Array(collectionIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
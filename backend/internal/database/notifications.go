@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// types of in-app notifications
+const (
+	NotificationRewardMinted       = "reward_minted"
+	NotificationAchievementPending = "achievement_pending"
+	NotificationRankMilestone      = "rank_milestone"
+	NotificationAdminMessage       = "admin_message"
+)
+
+type Notification struct {
+	ID        int64   `db:"id" json:"id"`
+	UserID    int64   `db:"user_id" json:"-"`
+	Type      string  `db:"type" json:"type"`
+	Title     string  `db:"title" json:"title"`
+	Content   *string `db:"content" json:"content"`
+	Data      JSONB   `db:"data" json:"data"`
+	ReadAt    *int64  `db:"read_at" json:"read_at"`
+	CreatedAt int64   `db:"created_at" json:"created_at"`
+}
+
+type NotificationModel struct {
+	DB *sqlx.DB
+}
+
+// insert notification of one user
+func (m *NotificationModel) Insert(ctx context.Context, notification *Notification) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if notification.Data == nil {
+		notification.Data = JSONB{}
+	}
+
+	query := `
+		INSERT INTO notifications (user_id, type, title, content, data, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return m.DB.QueryRowContext(ctx, query,
+		notification.UserID,
+		notification.Type,
+		notification.Title,
+		notification.Content,
+		notification.Data,
+		time.Now().Unix(),
+	).Scan(&notification.ID, &notification.CreatedAt)
+}
+
+// insert the same notification for users, empty userIDs sends it to every user
+func (m *NotificationModel) InsertForUsers(ctx context.Context, userIDs []int64, notification *Notification) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if notification.Data == nil {
+		notification.Data = JSONB{}
+	}
+
+	query := `
+		INSERT INTO notifications (user_id, type, title, content, data, created_at)
+		SELECT id, $1, $2, $3, $4, $5
+		FROM users
+		WHERE cardinality($6::bigint[]) = 0 OR id = ANY($6)`
+
+	result, err := m.DB.ExecContext(ctx, query,
+		notification.Type,
+		notification.Title,
+		notification.Content,
+		notification.Data,
+		time.Now().Unix(),
+		pq.Array(userIDs),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// get notifications of the user, newest first
+func (m *NotificationModel) GetAllForUser(ctx context.Context, userID int64, unreadOnly bool, pagination *Pagination) ([]*Notification, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT id, user_id, type, title, content, data, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY id DESC
+		LIMIT $3 OFFSET $4`
+
+	notifications := []*Notification{}
+
+	err := m.DB.SelectContext(ctx, &notifications, query, userID, unreadOnly, pagination.End-pagination.Start, pagination.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// count notifications of the user
+func (m *NotificationModel) CountForUser(ctx context.Context, userID int64, unreadOnly bool) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var count int64
+
+	err := m.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)`, userID, unreadOnly)
+
+	return count, err
+}
+
+// mark notification of the user as read, already read notification keeps the first read time
+func (m *NotificationModel) MarkRead(ctx context.Context, userID, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, $3)
+		WHERE id = $1 AND user_id = $2`
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// mark all unread notifications of the user as read
+func (m *NotificationModel) MarkAllRead(ctx context.Context, userID int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `UPDATE notifications SET read_at = $2 WHERE user_id = $1 AND read_at IS NULL`, userID, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// delete notifications created before the time
+func (m *NotificationModel) DeleteOlderThan(ctx context.Context, before int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM notifications WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
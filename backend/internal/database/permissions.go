@@ -2,6 +2,8 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -21,6 +23,30 @@ type Permission struct {
 	Name 	  string `db:"name" json:"name"`
 	Route 	  string `db:"route" json:"route"`
 	Method 	  string `db:"method" json:"method"`
+	// set only for permissions granted on a single resource
+	ResourceType *string `db:"resource_type" json:"resource_type,omitempty"`
+	ResourceID   *int64  `db:"resource_id" json:"resource_id,omitempty"`
+}
+
+// resource types for scoped grants
+const (
+	ResourceCollection = "collection"
+)
+
+// ScopedPermissions are the permissions that can be granted on a single resource,
+// with the type of the resource. Routes of these permissions check the resource with userCan.
+var ScopedPermissions = map[string]string{
+	"permissions:minted-nfts-create": ResourceCollection,
+}
+
+type Grant struct {
+	ID             int64  `db:"id" json:"id"`
+	UserID         int64  `db:"user_id" json:"user_id"`
+	PermissionID   int64  `db:"permission_id" json:"permission_id"`
+	PermissionName string `db:"permission_name" json:"permission_name"`
+	ResourceType   string `db:"resource_type" json:"resource_type"`
+	ResourceID     int64  `db:"resource_id" json:"resource_id"`
+	CreatedAt      int64  `db:"created_at" json:"created_at"`
 }
 
 
@@ -31,10 +57,10 @@ type PermissionModel struct {
 type Roles []Role
 
 // insert new role
-func (m PermissionModel) InsertRole(role Role) (int64, error) {
+func (m PermissionModel) InsertRole(ctx context.Context, role Role) (int64, error) {
 	query := `INSERT INTO roles (name, description) VALUES ($1, $2) RETURNING id`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	var id int64
@@ -48,10 +74,10 @@ func (m PermissionModel) InsertRole(role Role) (int64, error) {
 }
 
 // insert role permissions
-func (m PermissionModel) InsertRolePermissions(roleID int64, permissions []string) error {
+func (m PermissionModel) InsertRolePermissions(ctx context.Context, roleID int64, permissions []string) error {
 	query := `INSERT INTO roles_permissions (role_id, permission_id) VALUES ($1, (SELECT id FROM permissions WHERE code = $2))`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	for _, permission := range permissions {
@@ -65,9 +91,9 @@ func (m PermissionModel) InsertRolePermissions(roleID int64, permissions []strin
 }
 
 // insert role permissions by array of permission ids
-func (m PermissionModel) InsertRolePermissionsByIDs(roleID int64, permissionIDs []int64) error {
+func (m PermissionModel) InsertRolePermissionsByIDs(ctx context.Context, roleID int64, permissionIDs []int64) error {
 	query := `INSERT INTO roles_permissions (role_id, permission_id) VALUES ($1, $2)`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	for _, permissionID := range permissionIDs {
@@ -81,9 +107,9 @@ func (m PermissionModel) InsertRolePermissionsByIDs(roleID int64, permissionIDs
 }
 
 // insert role to user
-func (m PermissionModel) InsertUserRole(userID int64, roleID int64) error {
+func (m PermissionModel) InsertUserRole(ctx context.Context, userID int64, roleID int64) error {
 	query := `INSERT INTO users_roles (user_id, role_id) VALUES ($1, $2)`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, userID, roleID)
@@ -95,10 +121,10 @@ func (m PermissionModel) InsertUserRole(userID int64, roleID int64) error {
 }
 
 // get all roles
-func (m PermissionModel) GetAllRoles() (Roles, error) {
+func (m PermissionModel) GetAllRoles(ctx context.Context) (Roles, error) {
 	query := `SELECT id, name, description FROM roles ORDER BY id`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query)
@@ -129,10 +155,10 @@ func (m PermissionModel) GetAllRoles() (Roles, error) {
 }
 
 // get role by id
-func (m PermissionModel) GetRoleByID(id int64) (*Role, error) {
+func (m PermissionModel) GetRoleByID(ctx context.Context, id int64) (*Role, error) {
 	query := `SELECT id, name, description FROM roles WHERE id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	row := m.DB.QueryRowContext(ctx, query, id)
@@ -151,10 +177,10 @@ func (m PermissionModel) GetRoleByID(id int64) (*Role, error) {
 
 
 // get role by name
-func (m PermissionModel) GetRoleByName(name string) (*Role, error) {
+func (m PermissionModel) GetRoleByName(ctx context.Context, name string) (*Role, error) {
 	query := `SELECT id, name, description FROM roles WHERE name = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	row := m.DB.QueryRowContext(ctx, query, name)
@@ -171,10 +197,10 @@ func (m PermissionModel) GetRoleByName(name string) (*Role, error) {
 }
 
 // get role permissions
-func (m PermissionModel) GetRolePermissions(roleID int64) ([]Permission, error) {
+func (m PermissionModel) GetRolePermissions(ctx context.Context, roleID int64) ([]Permission, error) {
 	query := `SELECT permissions.id, permissions.route, permissions.method, permissions.name FROM permissions INNER JOIN roles_permissions ON roles_permissions.permission_id = permissions.id WHERE roles_permissions.role_id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, roleID)
@@ -205,10 +231,10 @@ func (m PermissionModel) GetRolePermissions(roleID int64) ([]Permission, error)
 }
 
 // get user roles
-func (m PermissionModel) GetUserRoles(userID int64) (*Role, error) {
-	query := `SELECT roles.id, roles.name, roles.description FROM roles INNER JOIN users_roles ON users_roles.role_id = roles.id WHERE users_roles.user_id = $1 LIMIT 1`
+func (m PermissionModel) GetUserRoles(ctx context.Context, userID int64) (Roles, error) {
+	query := `SELECT roles.id, roles.name, roles.description FROM roles INNER JOIN users_roles ON users_roles.role_id = roles.id WHERE users_roles.user_id = $1 ORDER BY roles.id`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, userID)
@@ -218,28 +244,43 @@ func (m PermissionModel) GetUserRoles(userID int64) (*Role, error) {
 
 	defer rows.Close()
 
-	var role Role
+	roles := Roles{}
 
 	for rows.Next() {
+		var role Role
+
 		err := rows.Scan(&role.ID, &role.Name, &role.Description)
 		if err != nil {
 			return nil, err
 		}
+
+		roles = append(roles, role)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return &role, nil
+	return roles, nil
 
 }
 
+// check if roles contain role with the name
+func (r Roles) Has(name string) bool {
+	for _, role := range r {
+		if role.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 // get user permissions by user id using roles
-func (m PermissionModel) GetUserPermissions(userID int64) ([]Permission, error) {
+func (m PermissionModel) GetUserPermissions(ctx context.Context, userID int64) ([]Permission, error) {
 	query := `SELECT permissions.code FROM permissions INNER JOIN roles_permissions ON roles_permissions.permission_id = permissions.id INNER JOIN users_roles ON users_roles.role_id = roles_permissions.role_id WHERE users_roles.user_id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, userID)
@@ -271,10 +312,10 @@ func (m PermissionModel) GetUserPermissions(userID int64) ([]Permission, error)
 
 
 // delete role
-func (m PermissionModel) DeleteRole(id int64) error {
+func (m PermissionModel) DeleteRole(ctx context.Context, id int64) error {
 	query := `DELETE FROM roles WHERE id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, id)
@@ -286,10 +327,10 @@ func (m PermissionModel) DeleteRole(id int64) error {
 }
 
 // delete role permissions
-func (m PermissionModel) DeleteRolePermissions(roleID int64) error {
+func (m PermissionModel) DeleteRolePermissions(ctx context.Context, roleID int64) error {
 	query := `DELETE FROM roles_permissions WHERE role_id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, roleID)
@@ -301,10 +342,10 @@ func (m PermissionModel) DeleteRolePermissions(roleID int64) error {
 }
 
 // delete user roles
-func (m PermissionModel) DeleteUserRoles(userID int64) error {
+func (m PermissionModel) DeleteUserRoles(ctx context.Context, userID int64) error {
 	query := `DELETE FROM users_roles WHERE user_id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, userID)
@@ -316,10 +357,10 @@ func (m PermissionModel) DeleteUserRoles(userID int64) error {
 }
 
 // update role
-func (m PermissionModel) UpdateRole(role *Role) error {
+func (m PermissionModel) UpdateRole(ctx context.Context, role *Role) error {
 	query := `UPDATE roles SET name = $1, description = $2 WHERE id = $3`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, role.Name, role.Description, role.ID)
@@ -332,10 +373,10 @@ func (m PermissionModel) UpdateRole(role *Role) error {
 }
 
 // update role permissions
-func (m PermissionModel) UpdateRolePermissions(roleID int64, permissions []string) error {
+func (m PermissionModel) UpdateRolePermissions(ctx context.Context, roleID int64, permissions []string) error {
 	query := `INSERT INTO roles_permissions (role_id, permission_id) VALUES ($1, (SELECT id FROM permissions WHERE code = $2))`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	for _, permission := range permissions {
@@ -349,9 +390,9 @@ func (m PermissionModel) UpdateRolePermissions(roleID int64, permissions []strin
 }
 
 // update user roles
-func (m PermissionModel) UpdateUserRoles(userID int64, roles []int64) error {
-	query := `INSERT INTO users_roles (user_id, role_id) VALUES ($1, $2)`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m PermissionModel) UpdateUserRoles(ctx context.Context, userID int64, roles []int64) error {
+	query := `INSERT INTO users_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	for _, role := range roles {
@@ -365,11 +406,141 @@ func (m PermissionModel) UpdateUserRoles(userID int64, roles []int64) error {
 	return nil
 }
 
+// replace all user roles
+func (m PermissionModel) SetUserRoles(ctx context.Context, userID int64, roles []int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM users_roles WHERE user_id = $1`, userID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, role := range roles {
+		_, err = tx.ExecContext(ctx, `INSERT INTO users_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, role)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insert permission
+func (m PermissionModel) InsertPermission(ctx context.Context, permission *Permission) (int64, error) {
+	query := `INSERT INTO permissions (name, route, method) VALUES ($1, $2, $3) RETURNING id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var id int64
+
+	err := m.DB.QueryRowContext(ctx, query, permission.Name, permission.Route, permission.Method).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// insert permission granted on a single resource
+func (m PermissionModel) InsertGrant(ctx context.Context, grant *Grant) (int64, error) {
+	query := `
+		INSERT INTO permission_grants (user_id, permission_id, resource_type, resource_id, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var id int64
+
+	err := m.DB.QueryRowContext(ctx, query, grant.UserID, grant.PermissionID, grant.ResourceType, grant.ResourceID, time.Now().Unix()).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// get permission by id
+func (m PermissionModel) GetPermissionByID(ctx context.Context, id int64) (*Permission, error) {
+	query := `SELECT id, name, route, method FROM permissions WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var permission Permission
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&permission.ID, &permission.Name, &permission.Route, &permission.Method)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &permission, nil
+}
+
+// get user grants
+func (m PermissionModel) GetUserGrants(ctx context.Context, userID int64) ([]Grant, error) {
+	query := `
+		SELECT g.id, g.user_id, g.permission_id, p.name AS permission_name, g.resource_type, g.resource_id, g.created_at
+		FROM permission_grants g
+		INNER JOIN permissions p ON p.id = g.permission_id
+		WHERE g.user_id = $1
+		ORDER BY g.id`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	grants := []Grant{}
+
+	err := m.DB.SelectContext(ctx, &grants, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return grants, nil
+}
+
+// delete user grant
+func (m PermissionModel) DeleteGrant(ctx context.Context, userID, id int64) error {
+	query := `DELETE FROM permission_grants WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
 // get all permissions
-func (m PermissionModel) GetAllPermissions() ([]Permission, error) {
+func (m PermissionModel) GetAllPermissions(ctx context.Context) ([]Permission, error) {
 	query := `SELECT * FROM permissions`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query)
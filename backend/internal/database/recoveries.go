@@ -0,0 +1,373 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	RecoveryStatusPending   = "pending"
+	RecoveryStatusConfirmed = "confirmed"
+	RecoveryStatusCompleted = "completed"
+	RecoveryStatusRejected  = "rejected"
+)
+
+const (
+	RecoveryConfirmedByTelegram = "telegram"
+	RecoveryConfirmedByGithub   = "github"
+	RecoveryConfirmedByAdmin    = "admin"
+)
+
+type RecoveryModel struct {
+	DB *sqlx.DB
+}
+
+type WalletRecovery struct {
+	ID                 int64   `db:"id" json:"id"`
+	UserID             int64   `db:"user_id" json:"user_id"`
+	OldRawAddress      string  `db:"old_raw_address" json:"old_raw_address"`
+	OldFriendlyAddress string  `db:"old_friendly_address" json:"old_friendly_address"`
+	NewRawAddress      string  `db:"new_raw_address" json:"new_raw_address"`
+	NewFriendlyAddress string  `db:"new_friendly_address" json:"new_friendly_address"`
+	Status             string  `db:"status" json:"status"`
+	ConfirmedBy        *string `db:"confirmed_by" json:"confirmed_by"`
+	ConfirmedByUserID  *int64  `db:"confirmed_by_user_id" json:"confirmed_by_user_id"`
+	CreatedAt          int64   `db:"created_at" json:"created_at"`
+	UpdatedAt          int64   `db:"updated_at" json:"updated_at"`
+	Version            int64   `db:"version" json:"version"`
+}
+
+// insert recovery request, previous pending requests of the user are rejected
+func (m *RecoveryModel) Insert(ctx context.Context, recovery *WalletRecovery) (*WalletRecovery, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE wallet_recoveries SET status = $1, updated_at = $2, version = version + 1 WHERE user_id = $3 AND status = $4`,
+		RecoveryStatusRejected, time.Now().Unix(), recovery.UserID, RecoveryStatusPending)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO wallet_recoveries (user_id, old_raw_address, old_friendly_address, new_raw_address, new_friendly_address, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING *
+		`
+
+	var inserted WalletRecovery
+
+	err = tx.GetContext(ctx, &inserted, query,
+		recovery.UserID,
+		recovery.OldRawAddress,
+		recovery.OldFriendlyAddress,
+		recovery.NewRawAddress,
+		recovery.NewFriendlyAddress,
+		RecoveryStatusPending,
+		time.Now().Unix(),
+		time.Now().Unix(),
+	)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &inserted, tx.Commit()
+}
+
+// get recovery by id
+func (m *RecoveryModel) GetByID(ctx context.Context, id int64) (*WalletRecovery, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var recovery WalletRecovery
+
+	err := m.DB.GetContext(ctx, &recovery, `SELECT * FROM wallet_recoveries WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+
+	return &recovery, err
+}
+
+// get last pending recovery of the user
+func (m *RecoveryModel) GetPendingByUserID(ctx context.Context, userID int64) (*WalletRecovery, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var recovery WalletRecovery
+
+	query := `SELECT * FROM wallet_recoveries WHERE user_id = $1 AND status = $2 ORDER BY id DESC LIMIT 1`
+
+	err := m.DB.GetContext(ctx, &recovery, query, userID, RecoveryStatusPending)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+
+	return &recovery, err
+}
+
+// get recoveries with pagination
+func (m *RecoveryModel) GetAll(ctx context.Context, pagination *Pagination, filter string) ([]*WalletRecovery, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`SELECT * FROM wallet_recoveries %s ORDER BY id DESC LIMIT $1 OFFSET $2`, filter)
+
+	recoveries := []*WalletRecovery{}
+
+	err := m.DB.SelectContext(ctx, &recoveries, query, pagination.End-pagination.Start, pagination.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveries, nil
+}
+
+// count recoveries
+func (m *RecoveryModel) Count(ctx context.Context, filter string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var count int64
+
+	err := m.DB.GetContext(ctx, &count, fmt.Sprintf(`SELECT COUNT(*) FROM wallet_recoveries %s`, filter))
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Confirm re-keys the user to the new wallet and marks the recovery as confirmed.
+// Auth tokens of the user are removed, so the old wallet sessions stop working.
+func (m *RecoveryModel) Confirm(ctx context.Context, recovery *WalletRecovery, confirmedBy string, confirmedByUserID *int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE wallet_recoveries
+		SET status = $1, confirmed_by = $2, confirmed_by_user_id = $3, updated_at = $4, version = version + 1
+		WHERE id = $5 AND status = $6 AND version = $7`,
+		RecoveryStatusConfirmed, confirmedBy, confirmedByUserID, time.Now().Unix(), recovery.ID, RecoveryStatusPending, recovery.Version)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if rows == 0 {
+		tx.Rollback()
+		return ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users
+		SET raw_address = $1, friendly_address = $2, updated_at = $3, version = version + 1
+		WHERE id = $4`,
+		recovery.NewRawAddress, recovery.NewFriendlyAddress, time.Now().Unix(), recovery.UserID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE user_id = $1 AND scope = $2`, recovery.UserID, ScopeAuthentication)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// reject pending recovery
+func (m *RecoveryModel) Reject(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, `UPDATE wallet_recoveries SET status = $1, updated_at = $2, version = version + 1 WHERE id = $3 AND status = $4`,
+		RecoveryStatusRejected, time.Now().Unix(), id, RecoveryStatusPending)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// get sbt tokens of the old wallet that were minted by the platform
+func (m *RecoveryModel) GetPlatformTokens(ctx context.Context, ownerAddress, domainName string) ([]*SBTToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT id, friendly_address, sbt_collections_id, content_uri, weight
+		FROM sbt_tokens
+		WHERE friendly_owner_address = $1 AND content_uri LIKE '%' || $2 || '%'
+		`
+
+	rows, err := m.DB.QueryContext(ctx, query, ownerAddress, domainName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tokens := []*SBTToken{}
+
+	for rows.Next() {
+		var token SBTToken
+
+		err := rows.Scan(
+			&token.ID,
+			&token.FriendlyAddress,
+			&token.SBTCollectionID,
+			&token.ContentUri,
+			&token.Weight,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, &token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// get ids of sbt tokens the recovery has already sent a revoke for
+func (m *RecoveryModel) GetRevokedTokenIDs(ctx context.Context, recoveryID int64) (map[int64]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var ids []int64
+
+	err := m.DB.SelectContext(ctx, &ids, `SELECT sbt_token_id FROM wallet_recovery_revokes WHERE recovery_id = $1`, recoveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		revoked[id] = true
+	}
+
+	return revoked, nil
+}
+
+// InsertRevokes records the revokes before they are sent, so a retry of the recovery
+// doesn't send them again
+func (m *RecoveryModel) InsertRevokes(ctx context.Context, recoveryID int64, tokenIDs []int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO wallet_recovery_revokes (recovery_id, sbt_token_id, created_at)
+		SELECT $1, unnest($2::BIGINT[]), $3
+		ON CONFLICT DO NOTHING`
+
+	_, err := m.DB.ExecContext(ctx, query, recoveryID, pq.Array(tokenIDs), time.Now().Unix())
+
+	return err
+}
+
+// DeleteRevokes drops the records of revokes that failed to send
+func (m *RecoveryModel) DeleteRevokes(ctx context.Context, recoveryID int64, tokenIDs []int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM wallet_recovery_revokes WHERE recovery_id = $1 AND sbt_token_id = ANY($2)`, recoveryID, pq.Array(tokenIDs))
+
+	return err
+}
+
+// Reissue queues the platform tokens of the old wallet for minting to the new one
+// and drops the old rewards with their rating, SetReward adds them back after mint.
+func (m *RecoveryModel) Reissue(ctx context.Context, recovery *WalletRecovery, tokens []*SBTToken, collections map[int64]string, base64s map[int64]string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO stored_rewards (user_address, collection_address, base64_metadata, approved_by_user, created_at, updated_at)
+			VALUES ($1, $2, $3, true, $4, $5)`,
+			recovery.NewFriendlyAddress, collections[token.ID], base64s[token.ID], time.Now().Unix(), time.Now().Unix())
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM rewards WHERE user_id = $1 AND sbt_token_id = $2`, recovery.UserID, token.ID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if rows == 0 {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE users
+			SET rating = GREATEST(rating - $1, 0), awards_count = GREATEST(awards_count - 1, 0)
+			WHERE id = $2`,
+			token.Weight, recovery.UserID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE wallet_recoveries SET status = $1, updated_at = $2, version = version + 1 WHERE id = $3`,
+		RecoveryStatusCompleted, time.Now().Unix(), recovery.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
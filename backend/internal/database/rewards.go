@@ -28,6 +28,8 @@ type TelegramMessage struct {
 	UserID int `db:"user_id" json:"user_id"`
 	MessageID int `db:"message_id" json:"message_id"`
 	ChatID int64 `db:"chat_id" json:"chat_id"`
+	Weight int `db:"weight" json:"weight"`
+	Bonus int `db:"bonus" json:"bonus"`
 	CreatedAt int64 `db:"created_at" json:"created_at"`
 	UpdatedAt int64 `db:"updated_at" json:"updated_at"`
 	Version int64 `db:"version" json:"version"`
@@ -106,6 +108,19 @@ func (m *RewardModel) CountTelegramMessagesByUserID(userID int64) (int64, error)
 
 
 
+// message scored in a Discord guild, user_id is the Discord user id
+type DiscordMessage struct {
+	ID int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+	MessageID int64 `db:"message_id" json:"message_id"`
+	ChannelID int64 `db:"channel_id" json:"channel_id"`
+	GuildID int64 `db:"guild_id" json:"guild_id"`
+	Weight int `db:"weight" json:"weight"`
+	CreatedAt int64 `db:"created_at" json:"created_at"`
+	UpdatedAt int64 `db:"updated_at" json:"updated_at"`
+	Version int64 `db:"version" json:"version"`
+}
+
 type StoredReward struct {
 	ID int64 `db:"id" json:"id"`
 	UserAddress string `db:"user_address" json:"user_address"`
@@ -212,6 +227,29 @@ func (m *RewardModel) UpdateStoredRewardApprovedByUser(id int64, approved bool)
 }
 
 
+// decline stored reward, it is marked as processed without approval and never minted.
+// ErrRecordNotFound is returned when the reward is already processed.
+
+func (m *RewardModel) DeclineStoredReward(id int64) error {
+	sql := `UPDATE stored_rewards SET approved_by_user = false, processed = true, updated_at = $1 WHERE id = $2 AND processed = false`
+
+	res, err := m.DB.Exec(sql, time.Now().Unix(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
 // get last time of stored reward
 
 func (m *RewardModel) GetLastTimeStoredReward() (int64, error) {
@@ -323,12 +361,12 @@ func (m *RewardModel) GetById(id int64) (*Reward, error) {
 
 
 
-func (m *RewardModel) InsertTelegramMessage(tx *sql.Tx, userId, messageId int, chatId int64) (int64, error) {
-	sql := `INSERT INTO tg_messages (user_id, message_id, chat_id, created_at, updated_at, version) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+func (m *RewardModel) InsertTelegramMessage(tx *sql.Tx, userId, messageId int, chatId int64, weight int) (int64, error) {
+	sql := `INSERT INTO tg_messages (user_id, message_id, chat_id, weight, created_at, updated_at, version) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
 
 	var id int64
 
-	err := tx.QueryRow(sql, userId, messageId, chatId, time.Now().Unix(), time.Now().Unix(), 1).Scan(&id)
+	err := tx.QueryRow(sql, userId, messageId, chatId, weight, time.Now().Unix(), time.Now().Unix(), 1).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
@@ -336,9 +374,54 @@ func (m *RewardModel) InsertTelegramMessage(tx *sql.Tx, userId, messageId int, c
 	return id, nil
 }
 
+func (m *RewardModel) InsertDiscordMessage(tx *sql.Tx, message *DiscordMessage) (int64, error) {
+	query := `
+		INSERT INTO discord_messages (user_id, message_id, channel_id, guild_id, weight, created_at, updated_at, version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	var id int64
+
+	now := time.Now().Unix()
+
+	err := tx.QueryRow(query, message.UserID, message.MessageID, message.ChannelID, message.GuildID, message.Weight, now, now, 1).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// add bonus to the scored message once per reacting member, own messages are skipped.
+// Returns false when the message is not scored or the member already reacted.
+func (m *RewardModel) AddReactionBonus(ctx context.Context, chatId int64, messageId, reactorId, bonus, maxBonus int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		WITH reaction AS (
+			INSERT INTO tg_message_reactions (chat_id, message_id, user_id, created_at)
+			SELECT $1, $2, $3, $6
+			WHERE EXISTS (SELECT 1 FROM tg_messages WHERE chat_id = $1 AND message_id = $2 AND user_id <> $3)
+			ON CONFLICT DO NOTHING
+			RETURNING 1
+		)
+		UPDATE tg_messages
+		SET bonus = LEAST(bonus + $4, $5), updated_at = $6, version = version + 1
+		WHERE chat_id = $1 AND message_id = $2 AND EXISTS (SELECT 1 FROM reaction)`
+
+	result, err := m.DB.ExecContext(ctx, query, chatId, messageId, reactorId, bonus, maxBonus, time.Now().Unix())
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
+
 // update user rating by fetching count and +1
-func (m *RewardModel) UpdateRating(tx *sql.Tx, telegramUserId int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *RewardModel) UpdateRating(ctx context.Context, tx *sql.Tx, telegramUserId int) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -358,8 +441,8 @@ func (m *RewardModel) UpdateRating(tx *sql.Tx, telegramUserId int) error {
 	return nil
 }
 
-func (m *RewardModel) UpdateRatingByReward(tx *sqlx.Tx, userId, lastAwardsAt, weight int64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *RewardModel) UpdateRatingByReward(ctx context.Context, tx *sqlx.Tx, userId, lastAwardsAt, weight int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
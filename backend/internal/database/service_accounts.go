@@ -0,0 +1,415 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// api keys are sent in this header, separate from user bearer tokens
+const ApiKeyHeader = "X-Api-Key"
+
+// prefix makes api keys recognizable in configs and secret scanners
+const apiKeyPrefix = "tdp_"
+
+// last_used_at is written at most once per this interval
+const apiKeyTouchInterval = time.Minute
+
+var ErrDuplicateServiceAccount = errors.New("duplicate service account name")
+
+type ServiceAccount struct {
+	ID          int64    `db:"id" json:"id"`
+	Name        string   `db:"name" json:"name"`
+	Description *string  `db:"description" json:"description"`
+	Disabled    bool     `db:"disabled" json:"disabled"`
+	CreatedBy   *int64   `db:"created_by" json:"created_by"`
+	CreatedAt   int64    `db:"created_at" json:"created_at"`
+	UpdatedAt   int64    `db:"updated_at" json:"updated_at"`
+	Version     int      `db:"version" json:"version"`
+	Roles       Roles    `json:"roles"`
+	ApiKeys     []ApiKey `json:"api_keys,omitempty"`
+}
+
+type ApiKey struct {
+	ID               int64  `db:"id" json:"id"`
+	ServiceAccountID int64  `db:"service_account_id" json:"service_account_id"`
+	Name             string `db:"name" json:"name"`
+	Prefix           string `db:"prefix" json:"prefix"`
+	// returned only once, when the key is created
+	Plaintext  string `db:"-" json:"key,omitempty"`
+	Hash       []byte `db:"hash" json:"-"`
+	Expiry     *int64 `db:"expiry" json:"expiry"`
+	LastUsedAt *int64 `db:"last_used_at" json:"last_used_at"`
+	Revoked    bool   `db:"revoked" json:"revoked"`
+	CreatedAt  int64  `db:"created_at" json:"created_at"`
+}
+
+type ServiceAccountModel struct {
+	DB *sqlx.DB
+}
+
+func generateApiKey() (plaintext string, hash []byte, err error) {
+	randomBytes := make([]byte, 32)
+
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	plaintext = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	sum := sha256.Sum256([]byte(plaintext))
+
+	return plaintext, sum[:], nil
+}
+
+// insert service account with roles
+func (m *ServiceAccountModel) Insert(ctx context.Context, account *ServiceAccount, roleIDs []int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+
+	query := `
+		INSERT INTO service_accounts (name, description, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		RETURNING id, disabled, created_at, updated_at, version`
+
+	err = tx.QueryRowContext(ctx, query, account.Name, account.Description, account.CreatedBy, now).Scan(
+		&account.ID,
+		&account.Disabled,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+		&account.Version,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrDuplicateServiceAccount
+		}
+		return err
+	}
+
+	err = setServiceAccountRoles(ctx, tx, account.ID, roleIDs)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func setServiceAccountRoles(ctx context.Context, tx *sqlx.Tx, accountID int64, roleIDs []int64) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM service_accounts_roles WHERE service_account_id = $1`, accountID)
+	if err != nil {
+		return err
+	}
+
+	for _, roleID := range roleIDs {
+		_, err = tx.ExecContext(ctx, `INSERT INTO service_accounts_roles (service_account_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, accountID, roleID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// get service account with roles and keys
+func (m *ServiceAccountModel) GetByID(ctx context.Context, id int64) (*ServiceAccount, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var account ServiceAccount
+
+	err := m.DB.GetContext(ctx, &account, `SELECT id, name, description, disabled, created_by, created_at, updated_at, version FROM service_accounts WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	account.Roles, err = m.getRoles(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	account.ApiKeys = []ApiKey{}
+
+	err = m.DB.SelectContext(ctx, &account.ApiKeys, `SELECT * FROM api_keys WHERE service_account_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (m *ServiceAccountModel) getRoles(ctx context.Context, accountID int64) (Roles, error) {
+	roles := Roles{}
+
+	query := `
+		SELECT roles.id, roles.name, roles.description
+		FROM roles
+		INNER JOIN service_accounts_roles sar ON sar.role_id = roles.id
+		WHERE sar.service_account_id = $1
+		ORDER BY roles.id`
+
+	err := m.DB.SelectContext(ctx, &roles, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// get service accounts with roles
+func (m *ServiceAccountModel) GetAll(ctx context.Context, pagination *Pagination) ([]*ServiceAccount, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	accounts := []*ServiceAccount{}
+
+	query := `
+		SELECT id, name, description, disabled, created_by, created_at, updated_at, version
+		FROM service_accounts
+		ORDER BY id
+		LIMIT $1 OFFSET $2`
+
+	err := m.DB.SelectContext(ctx, &accounts, query, pagination.End-pagination.Start, pagination.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, account := range accounts {
+		account.Roles, err = m.getRoles(ctx, account.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return accounts, nil
+}
+
+// count service accounts
+func (m *ServiceAccountModel) Count(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var count int64
+
+	err := m.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM service_accounts`)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// update description and disabled flag, roles are replaced when roleIDs is not nil
+func (m *ServiceAccountModel) Update(ctx context.Context, account *ServiceAccount, roleIDs []int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE service_accounts
+		SET description = $1, disabled = $2, updated_at = $3, version = version + 1
+		WHERE id = $4 AND version = $5
+		RETURNING updated_at, version`
+
+	err = tx.QueryRowContext(ctx, query, account.Description, account.Disabled, time.Now().Unix(), account.ID, account.Version).Scan(
+		&account.UpdatedAt,
+		&account.Version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+
+	if roleIDs != nil {
+		err = setServiceAccountRoles(ctx, tx, account.ID, roleIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// delete service account, its keys and roles are removed by cascade
+func (m *ServiceAccountModel) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM service_accounts WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// create api key for service account, plaintext is set only on the returned key
+func (m *ServiceAccountModel) NewApiKey(ctx context.Context, accountID int64, name string, ttl time.Duration) (*ApiKey, error) {
+	plaintext, hash, err := generateApiKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &ApiKey{
+		ServiceAccountID: accountID,
+		Name:             name,
+		Prefix:           plaintext[:len(apiKeyPrefix)+6],
+		Plaintext:        plaintext,
+		Hash:             hash,
+		CreatedAt:        time.Now().Unix(),
+	}
+
+	if ttl > 0 {
+		expiry := time.Now().Add(ttl).Unix()
+		key.Expiry = &expiry
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO api_keys (service_account_id, name, prefix, hash, expiry, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`
+
+	err = m.DB.QueryRowContext(ctx, query, key.ServiceAccountID, key.Name, key.Prefix, key.Hash, key.Expiry, key.CreatedAt).Scan(&key.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// revoke api key, revoked keys are kept for the audit trail
+func (m *ServiceAccountModel) RevokeApiKey(ctx context.Context, accountID, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `UPDATE api_keys SET revoked = TRUE WHERE id = $1 AND service_account_id = $2`, id, accountID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// get active service account for api key plaintext and record key usage
+func (m *ServiceAccountModel) GetForApiKey(ctx context.Context, plaintext string) (*ServiceAccount, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	now := time.Now().Unix()
+
+	var account ServiceAccount
+	var keyID int64
+
+	query := `
+		SELECT sa.id, sa.name, sa.description, sa.disabled, sa.created_by, sa.created_at, sa.updated_at, sa.version, k.id
+		FROM api_keys k
+		INNER JOIN service_accounts sa ON sa.id = k.service_account_id
+		WHERE k.hash = $1
+		AND k.revoked = FALSE
+		AND (k.expiry IS NULL OR k.expiry > $2)
+		AND sa.disabled = FALSE`
+
+	err := m.DB.QueryRowContext(ctx, query, hash[:], now).Scan(
+		&account.ID,
+		&account.Name,
+		&account.Description,
+		&account.Disabled,
+		&account.CreatedBy,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+		&account.Version,
+		&keyID,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	_, err = m.DB.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = $1
+		WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)`,
+		now, keyID, now-int64(apiKeyTouchInterval.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+
+	account.Roles, err = m.getRoles(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+// get permissions of service account roles
+func (m *ServiceAccountModel) GetPermissions(ctx context.Context, accountID int64) ([]Permission, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	permissions := []Permission{}
+
+	query := `
+		SELECT DISTINCT p.id, p.name, p.route, p.method
+		FROM service_accounts_roles sar
+		INNER JOIN roles_permissions rp ON rp.role_id = sar.role_id
+		INNER JOIN permissions p ON p.id = rp.permission_id
+		WHERE sar.service_account_id = $1`
+
+	err := m.DB.SelectContext(ctx, &permissions, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
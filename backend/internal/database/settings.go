@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// get language chosen in the profile, empty if the user didn't choose one
+func (m *UserModel) GetLanguage(ctx context.Context, userID int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var language sql.NullString
+
+	err := m.DB.GetContext(ctx, &language, `SELECT language FROM user_settings WHERE user_id = $1`, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	return language.String, nil
+}
+
+// get language chosen in the profile of the user linked to the Telegram account
+func (m *UserModel) GetLanguageByTelegramUserId(ctx context.Context, telegramUserID int) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		SELECT s.language
+		FROM user_settings s
+		JOIN linked_accounts a ON a.user_id = s.user_id
+		WHERE a.telegram_user_id = $1`
+
+	var language sql.NullString
+
+	err := m.DB.GetContext(ctx, &language, query, telegramUserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	return language.String, nil
+}
+
+// set language of the user, empty language resets the choice
+func (m *UserModel) SetLanguage(ctx context.Context, userID int64, language string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO user_settings (user_id, language, updated_at)
+		VALUES ($1, NULLIF($2, ''), $3)
+		ON CONFLICT (user_id) DO UPDATE SET language = EXCLUDED.language, updated_at = EXCLUDED.updated_at`
+
+	_, err := m.DB.ExecContext(ctx, query, userID, language, time.Now().Unix())
+
+	return err
+}
@@ -12,7 +12,17 @@ import (
 )
 
 const (
-	ScopeAuthentication = "authentication"
+	ScopeAuthentication    = "authentication"
+	ScopeRecovery          = "recovery"
+	ScopeEmailVerification = "email-verification"
+	// token in the t.me deep link that links Telegram account from the bot
+	ScopeTelegramLink = "telegram-link"
+	// oauth state of the Discord authorization linking the account
+	ScopeDiscordLink = "discord-link"
+	// oauth state of the GitHub authorization linking the account
+	ScopeGithubLink = "github-link"
+	// issued after GitHub confirmed the owner of the recovered profile, posted by the confirmation page
+	ScopeRecoveryConfirm = "recovery-confirm"
 )
 
 type Token struct {
@@ -59,36 +69,36 @@ func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
 	v.Check(len(tokenPlaintext) == 43, "token must be 43 bytes long")
 }
 
-func (m *TokensModel) New(userID int64, ttl time.Duration, scope string) (*Token, error) {
+func (m *TokensModel) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
 	token, err := generateToken(userID, ttl, scope)
 	if err != nil {		
 		return nil, err
 	}
 
-	err = m.Insert(token)
+	err = m.Insert(ctx, token)
 	return token, err
 }
 
-func (m *TokensModel) Insert(token *Token) error {
+func (m *TokensModel) Insert(ctx context.Context, token *Token) error {
 	query := `
 	    INSERT INTO tokens (hash, user_id, expiry, scope)
 	    VALUES($1,$2,$3,$4)`
 
 	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, args...)
 	return err
 }
 
-func (m *TokensModel) DeleteAllForUser(scope string, userID int64) error {
+func (m *TokensModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
 	query := `
 	    DELETE FROM tokens
 	    WHERE scope = $1 AND user_id = $2`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, scope, userID)
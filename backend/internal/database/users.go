@@ -18,6 +18,7 @@ import (
 const (
 	ProviderGithub = "github"
 	ProviderTelegram = "telegram"
+	ProviderDiscord = "discord"
 )
 
 var (
@@ -32,6 +33,10 @@ func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+func (u *User) IsServiceAccount() bool {
+	return u.ServiceAccount != nil
+}
+
 type GithubUser struct {
     Login string `json:"login"`
     ID int `json:"id"`
@@ -72,10 +77,13 @@ type LinkedAccount struct {
 	ID         int64  `db:"id" json:"id"`
 	UserID     int64  `db:"user_id" json:"user_id"`
 	TelegramUserID *int64  `db:"telegram_user_id" json:"telegram_user_id"`
+	DiscordUserID *int64  `db:"discord_user_id" json:"discord_user_id"`
 	Provider   string `db:"provider" json:"provider"`
 	AvatarURL  string `db:"avatar_url" json:"avatar_url"`
 	Login      string `db:"login" json:"login"`
 	AccessToken string `db:"access_token" json:"-"`
+	// false for GitHub accounts linked by username, they have to be linked again for recovery
+	Verified   bool   `db:"verified" json:"verified"`
 	CreatedAt  uint64 `db:"created_at" json:"created_at"`
 	UpdatedAt  uint64 `db:"updated_at" json:"updated_at"`
 	Version    int    `db:"version" json:"version"`
@@ -105,10 +113,23 @@ type User struct {
 	LinkedAccounts LinkedAccounts `db:"linked_accounts" json:"linked_accounts"`
 	Permissions     []Permission `json:"permissions,omitempty"`
 	Role 		  *Role       `json:"role,omitempty"`	
+	Roles          Roles       `json:"roles,omitempty"`
+	// set when the request is authenticated with an api key
+	ServiceAccount *ServiceAccount `json:"service_account,omitempty"`
 	Version        int       `db:"version" json:"version"`
 }
 
 
+// SetRoles sets user roles, role keeps the first one for clients that expect a single role
+func (u *User) SetRoles(roles Roles) {
+	u.Roles = roles
+	u.Role = &Role{}
+
+	if len(roles) > 0 {
+		u.Role = &roles[0]
+	}
+}
+
 type UserModel struct {
 	DB *sqlx.DB
 }
@@ -117,7 +138,7 @@ type UserModel struct {
 
 // get for token
 
-func (m *UserModel) GetForToken(tokenScope string, tokenString string) (*User, error) {
+func (m *UserModel) GetForToken(ctx context.Context, tokenScope string, tokenString string) (*User, error) {
 	tokenHash := sha256.Sum256([]byte(tokenString))
 
 	query := ` 
@@ -150,7 +171,7 @@ func (m *UserModel) GetForToken(tokenScope string, tokenString string) (*User, e
 
 	var user User
 
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 
@@ -185,8 +206,8 @@ func (m *UserModel) GetForToken(tokenScope string, tokenString string) (*User, e
 
 }
 
-func (m *UserModel) Insert(user *User) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) Insert(ctx context.Context, user *User) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	// generate random string with faker
@@ -229,8 +250,8 @@ func (m *UserModel) Insert(user *User) (*User, error) {
 
 //  take the rating from the user
 
-func (m *UserModel) TakeRating(userID int64, rating int64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) TakeRating(ctx context.Context, userID int64, rating int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -249,8 +270,8 @@ func (m *UserModel) TakeRating(userID int64, rating int64) error {
 
 // get top users by rating
 
-func (m *UserModel) GetTopUsers(pagination *Pagination) ([]*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) GetTopUsers(ctx context.Context, pagination *Pagination) ([]*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -328,8 +349,8 @@ func (m *UserModel) GetTopUsers(pagination *Pagination) ([]*User, error) {
 }
 
 // get user position based on rating and all user counts
-func (m *UserModel) GetUserPosition(userID int64) (int64, int64, error) {
-    ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) GetUserPosition(ctx context.Context, userID int64) (int64, int64, error) {
+    ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
     defer cancel()
 
     var position, count int64
@@ -367,8 +388,8 @@ func (m *UserModel) GetUserPosition(userID int64) (int64, int64, error) {
 }
 
 
-func (m *UserModel) Update(user *User) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) Update(ctx context.Context, user *User) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -445,8 +466,8 @@ func (m *UserModel) Update(user *User) (*User, error) {
 
 
 // delete user by id
-func (m *UserModel) Delete(id int64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -464,8 +485,8 @@ func (m *UserModel) Delete(id int64) error {
 }
 
 // create user
-func (m *UserModel) Create(user *User) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) Create(ctx context.Context, user *User) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
@@ -506,8 +527,8 @@ func (m *UserModel) Create(user *User) (*User, error) {
 //
 
 // get users f
-func (m *UserModel) GetMany(pagination *Pagination, filter, sort string) ([]*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) GetMany(ctx context.Context, pagination *Pagination, filter, sort string) ([]*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	var users []*User
@@ -577,18 +598,23 @@ type UserPermissions struct {
 
 
 // get all permissions for user
-func (m *UserModel) GetAllPermissions(id int64) ([]Permission, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) GetAllPermissions(ctx context.Context, id int64) ([]Permission, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	var permissions []Permission
 
 	query := `
-	SELECT p.id, p.name, p.route, p.method
+	SELECT DISTINCT p.id, p.name, p.route, p.method, NULL::text, NULL::bigint
 	FROM users_roles ur
 	INNER JOIN roles_permissions rp ON rp.role_id = ur.role_id
 	INNER JOIN permissions p ON p.id = rp.permission_id
 	WHERE ur.user_id = $1
+	UNION ALL
+	SELECT p.id, p.name, p.route, p.method, g.resource_type, g.resource_id
+	FROM permission_grants g
+	INNER JOIN permissions p ON p.id = g.permission_id
+	WHERE g.user_id = $1
 			`
 
 	rows, err := m.DB.QueryContext(ctx, query, id)
@@ -608,6 +634,8 @@ func (m *UserModel) GetAllPermissions(id int64) ([]Permission, error) {
 			&permission.Name,
 			&permission.Route,
 			&permission.Method,
+			&permission.ResourceType,
+			&permission.ResourceID,
 		)
 
 		if err != nil {
@@ -629,8 +657,8 @@ func (m *UserModel) GetAllPermissions(id int64) ([]Permission, error) {
 
 	
 // get user by address
-func (m *UserModel) GetByAddress(address string) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) GetByAddress(ctx context.Context, address string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 
 	defer cancel()
 
@@ -647,8 +675,8 @@ func (m *UserModel) GetByAddress(address string) (*User, error) {
 	return &user, err
 }
 
-func (m *UserModel) GetByFriendlyAddress(address string) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) GetByFriendlyAddress(ctx context.Context, address string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 
 	defer cancel()
 
@@ -668,8 +696,8 @@ func (m *UserModel) GetByFriendlyAddress(address string) (*User, error) {
 
 
 // Get user by username
-func (m *UserModel) GetByUsername(username string) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) GetByUsername(ctx context.Context, username string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 
 	defer cancel()
 
@@ -689,8 +717,8 @@ func (m *UserModel) GetByUsername(username string) (*User, error) {
 	return &user, nil
 }
 
-func (m *UserModel) GetByTelegramUsername(username string) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) GetByTelegramUsername(ctx context.Context, username string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 
 	defer cancel()
 
@@ -712,8 +740,8 @@ func (m *UserModel) GetByTelegramUsername(username string) (*User, error) {
 
 
 // delete linked account
-func (m *UserModel) DeleteLinkedAccountByUserId(userId int64, provider string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) DeleteLinkedAccountByUserId(ctx context.Context, userId int64, provider string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `DELETE FROM linked_accounts WHERE user_id = $1 AND provider = $2`
@@ -728,14 +756,15 @@ func (m *UserModel) DeleteLinkedAccountByUserId(userId int64, provider string) e
 }
 
 // get linked accounts
-func (m *UserModel) GetLinkedAccounts(userId int64) ([]*LinkedAccount, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) GetLinkedAccounts(ctx context.Context, userId int64) ([]*LinkedAccount, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	var accounts []*LinkedAccount
 
 	query := `
-		SELECT * FROM linked_accounts WHERE user_id = $1
+		SELECT id, user_id, telegram_user_id, discord_user_id, provider, avatar_url, login, access_token, verified, created_at, updated_at, version
+		FROM linked_accounts WHERE user_id = $1
 		`
 
 	rows, err := m.DB.QueryContext(ctx, query, userId)
@@ -753,10 +782,12 @@ func (m *UserModel) GetLinkedAccounts(userId int64) ([]*LinkedAccount, error) {
 			&account.ID,
 			&account.UserID,
 			&account.TelegramUserID,
+			&account.DiscordUserID,
 			&account.Provider,
 			&account.AvatarURL,
 			&account.Login,
 			&account.AccessToken,
+			&account.Verified,
 			&account.CreatedAt,
 			&account.UpdatedAt,
 			&account.Version,
@@ -773,8 +804,8 @@ func (m *UserModel) GetLinkedAccounts(userId int64) ([]*LinkedAccount, error) {
 }
 
 // get user by telegram user id
-func (m *UserModel) GetByTelegramUserId(telegramUserId int) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) GetByTelegramUserId(ctx context.Context, telegramUserId int) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	
 	defer cancel()
 
@@ -790,9 +821,26 @@ func (m *UserModel) GetByTelegramUserId(telegramUserId int) (*User, error) {
 	return &user, err
 }
 
+// get user by discord user id
+func (m *UserModel) GetByDiscordUserId(ctx context.Context, discordUserId int64) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var user User
+
+	query := `SELECT * FROM users WHERE id = (SELECT user_id FROM linked_accounts WHERE discord_user_id = $1 AND provider = 'discord')`
+
+	err := m.DB.GetContext(ctx, &user, query, discordUserId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return &user, err
+}
+
 // check if user has 2 linked accounts
-func (m *UserModel) HasTwoLinkedAccounts(userId int64) (bool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) HasTwoLinkedAccounts(ctx context.Context, userId int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	var count int
@@ -807,7 +855,8 @@ func (m *UserModel) HasTwoLinkedAccounts(userId int64) (bool, error) {
 		return false, err
 	}
 
-	if count == 2 {
+	// the reward is given for the second account, github, telegram and discord can be linked
+	if count >= 2 {
 		return true, nil
 	}
 
@@ -817,18 +866,19 @@ func (m *UserModel) HasTwoLinkedAccounts(userId int64) (bool, error) {
 
 
 // insert linked account
-func (m *UserModel) InsertLinkedAccount(account *LinkedAccount) error {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) InsertLinkedAccount(ctx context.Context, account *LinkedAccount) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	query := `
-		INSERT INTO linked_accounts (user_id, telegram_user_id, provider, avatar_url, login, access_token, created_at, updated_at, version)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO linked_accounts (user_id, telegram_user_id, discord_user_id, provider, avatar_url, login, access_token, created_at, updated_at, version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		`
 
 	_, err := m.DB.ExecContext(ctx, query,
 		account.UserID,
 		account.TelegramUserID,
+		account.DiscordUserID,
 		account.Provider,
 		account.AvatarURL,
 		account.Login,
@@ -854,8 +904,8 @@ func (m *UserModel) InsertLinkedAccount(account *LinkedAccount) error {
 
 
 
-func (m *UserModel) GetById(id int64) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+func (m *UserModel) GetById(ctx context.Context, id int64) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
 	var user User
@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jmoiron/sqlx"
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/ton"
+)
+
+func Postgres(db *sqlx.DB) Check {
+	return Check{
+		Name:     "postgres",
+		Critical: true,
+		Run: func(ctx context.Context) (map[string]any, error) {
+			stats := db.Stats()
+
+			details := map[string]any{
+				"open_connections": stats.OpenConnections,
+				"in_use":           stats.InUse,
+			}
+
+			return details, db.PingContext(ctx)
+		},
+	}
+}
+
+func Redis(client *redis.Client) Check {
+	return Check{
+		Name:     "redis",
+		Critical: true,
+		Run: func(ctx context.Context) (map[string]any, error) {
+			return nil, client.Ping(ctx).Err()
+		},
+	}
+}
+
+func Liteserver(client *ton.APIClient) Check {
+	return Check{
+		Name:     "liteserver",
+		Critical: true,
+		Run: func(ctx context.Context) (map[string]any, error) {
+			master, err := client.GetMasterchainInfo(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return map[string]any{"masterchain_seqno": master.SeqNo}, nil
+		},
+	}
+}
+
+// AdminWallet warns when the wallet paying for mints has less than minBalance TON,
+// low balance does not make the service unavailable
+func AdminWallet(client *ton.APIClient, walletAddress string, minBalance float64) Check {
+	return Check{
+		Name: "admin_wallet",
+		Run: func(ctx context.Context) (map[string]any, error) {
+			if walletAddress == "" {
+				return nil, errors.New("admin wallet address is not configured")
+			}
+
+			addr, err := address.ParseAddr(walletAddress)
+			if err != nil {
+				return nil, fmt.Errorf("invalid admin wallet address: %w", err)
+			}
+
+			master, err := client.GetMasterchainInfo(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			account, err := client.GetAccount(ctx, master, addr)
+			if err != nil {
+				return nil, err
+			}
+
+			balance := float64(0)
+			if account.IsActive {
+				balance = NanoToTON(account.State.Balance.NanoTON())
+			}
+
+			details := map[string]any{
+				"address":         walletAddress,
+				"balance_ton":     balance,
+				"min_balance_ton": minBalance,
+			}
+
+			if balance < minBalance {
+				return details, fmt.Errorf("balance %.2f TON is below %.2f TON", balance, minBalance)
+			}
+
+			return details, nil
+		},
+	}
+}
+
+// Heartbeat fails when the last activity reported by last is older than maxAge
+func Heartbeat(name string, last func() time.Time, maxAge time.Duration) Check {
+	return Check{
+		Name:     name,
+		Critical: true,
+		Run: func(ctx context.Context) (map[string]any, error) {
+			lastAt := last()
+			age := time.Since(lastAt)
+
+			details := map[string]any{
+				"last_at":     lastAt.Unix(),
+				"age_sec":     int64(age.Seconds()),
+				"max_age_sec": int64(maxAge.Seconds()),
+			}
+
+			if age > maxAge {
+				return details, fmt.Errorf("no activity for %s", age.Truncate(time.Second))
+			}
+
+			return details, nil
+		},
+	}
+}
+
+func NanoToTON(nano *big.Int) float64 {
+	ton, _ := new(big.Float).Quo(new(big.Float).SetInt(nano), big.NewFloat(1e9)).Float64()
+	return ton
+}
@@ -0,0 +1,217 @@
+// Package health runs readiness checks of service dependencies and reports
+// the result of every check in JSON. A failing critical check makes the
+// service unavailable, a failing non critical check only degrades it.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ton-developer-program/internal/response"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+
+	// overall report status
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+const (
+	// how long a single check can run
+	checkTimeout = 5 * time.Second
+	// reports are cached so frequent probes don't hammer postgres and liteservers
+	cacheTTL = 5 * time.Second
+)
+
+type Check struct {
+	Name string
+	// failing critical check makes the service not ready
+	Critical bool
+	// Run returns details shown in the report, error fails the check
+	Run func(ctx context.Context) (map[string]any, error)
+}
+
+type Result struct {
+	Status    string         `json:"status"`
+	Critical  bool           `json:"critical"`
+	LatencyMs int64          `json:"latency_ms"`
+	Error     string         `json:"error,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+type Report struct {
+	Status    string            `json:"status"`
+	Service   string            `json:"service"`
+	Version   string            `json:"version"`
+	CheckedAt int64             `json:"checked_at"`
+	Checks    map[string]Result `json:"checks"`
+}
+
+type Checker struct {
+	service string
+	version string
+	started time.Time
+	checks  []Check
+
+	mu        sync.Mutex
+	last      *Report
+	lastRunAt time.Time
+}
+
+// NonCritical returns the check that only degrades the service when it fails
+func NonCritical(check Check) Check {
+	check.Critical = false
+	return check
+}
+
+func NewChecker(service, version string, checks ...Check) *Checker {
+	return &Checker{
+		service: service,
+		version: version,
+		started: time.Now(),
+		checks:  checks,
+	}
+}
+
+// Run runs all checks in parallel, the report is reused for cacheTTL
+func (c *Checker) Run(ctx context.Context) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last != nil && time.Since(c.lastRunAt) < cacheTTL {
+		return c.last
+	}
+
+	report := &Report{
+		Status:    StatusOK,
+		Service:   c.service,
+		Version:   c.version,
+		CheckedAt: time.Now().Unix(),
+		Checks:    make(map[string]Result, len(c.checks)),
+	}
+
+	results := make([]Result, len(c.checks))
+
+	var wg sync.WaitGroup
+
+	for i, check := range c.checks {
+		wg.Add(1)
+
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+
+	wg.Wait()
+
+	for i, check := range c.checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+
+		switch {
+		case result.Status == StatusFail:
+			report.Status = StatusUnavailable
+		case result.Status == StatusWarn && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+
+	c.last = report
+	c.lastRunAt = time.Now()
+
+	return report
+}
+
+func runCheck(ctx context.Context, check Check) (result Result) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+
+	result.Critical = check.Critical
+
+	defer func() {
+		if err := recover(); err != nil {
+			result.Status = StatusFail
+			result.Error = "check panicked"
+		}
+
+		result.LatencyMs = time.Since(start).Milliseconds()
+	}()
+
+	details, err := check.Run(ctx)
+
+	result.Details = details
+	result.Status = StatusOK
+
+	if err != nil {
+		result.Error = err.Error()
+		result.Status = StatusWarn
+
+		if check.Critical {
+			result.Status = StatusFail
+		}
+	}
+
+	return result
+}
+
+// statusCode is 200 when the service is ok or degraded and 503 when a critical check fails
+func (r Report) statusCode() int {
+	if r.Status == StatusUnavailable {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusOK
+}
+
+// LiveHandler reports that the process is up, dependencies are not checked
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		response.JSON(w, http.StatusOK, map[string]any{
+			"status":     StatusOK,
+			"service":    c.service,
+			"version":    c.version,
+			"uptime_sec": int64(time.Since(c.started).Seconds()),
+		})
+	})
+}
+
+// ReadyHandler responds with 200 when the service is ok or degraded and 503 when a critical check fails
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// checks have their own timeouts, cached report must not depend on the probe connection
+		report := c.Run(context.Background())
+
+		w.Header().Set("Cache-Control", "no-store")
+
+		response.JSON(w, report.statusCode(), report)
+	})
+}
+
+// StatusHandler is ReadyHandler for public listeners, it responds with the same status code
+// and only the overall status, errors and details of the checks are not shown
+func (c *Checker) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(context.Background())
+
+		w.Header().Set("Cache-Control", "no-store")
+
+		response.JSON(w, report.statusCode(), map[string]any{"status": report.Status})
+	})
+}
+
+// Register adds /health/live and /health/ready to the mux of services without http api
+func (c *Checker) Register(mux *http.ServeMux) {
+	mux.Handle("/health/live", c.LiveHandler())
+	mux.Handle("/health/ready", c.ReadyHandler())
+}
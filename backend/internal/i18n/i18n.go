@@ -0,0 +1,85 @@
+// Package i18n holds texts of the bot and emails in every supported language.
+package i18n
+
+import (
+	"fmt"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// supported languages, English is used when nothing else matches
+const (
+	English = "en"
+	Russian = "ru"
+)
+
+var Languages = []string{English, Russian}
+
+var tags = map[string]language.Tag{
+	English: language.English,
+	Russian: language.Russian,
+}
+
+var matcher = language.NewMatcher([]language.Tag{language.English, language.Russian})
+
+var printers = map[string]*message.Printer{}
+
+func init() {
+	builder := catalog.NewBuilder(catalog.Fallback(language.English))
+
+	for key, texts := range messages {
+		for lang, text := range map[string]string{English: texts.en, Russian: texts.ru} {
+			if text == "" {
+				panic(fmt.Sprintf("i18n: message %q has no %s text", key, lang))
+			}
+
+			err := builder.SetString(tags[lang], key, text)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	for lang, tag := range tags {
+		printers[lang] = message.NewPrinter(tag, message.Catalog(builder))
+	}
+}
+
+// Match returns the supported language of the first preference it understands, preferences are
+// language codes like "ru" or "en-US", empty and unsupported ones are skipped
+func Match(preferences ...string) string {
+	for _, preference := range preferences {
+		if preference == "" {
+			continue
+		}
+
+		tag, err := language.Parse(preference)
+		if err != nil {
+			continue
+		}
+
+		_, index, confidence := matcher.Match(tag)
+		if confidence == language.No {
+			continue
+		}
+
+		return Languages[index]
+	}
+
+	return English
+}
+
+// T formats the message in the language, unknown languages fall back to English
+func T(lang, key string, args ...any) string {
+	return Printer(lang).Sprintf(key, args...)
+}
+
+func Printer(lang string) *message.Printer {
+	if printer, ok := printers[lang]; ok {
+		return printer
+	}
+
+	return printers[English]
+}
@@ -0,0 +1,525 @@
+package i18n
+
+type texts struct {
+	en string
+	ru string
+}
+
+// messages are fmt formats, arguments are the same in every language
+var messages = map[string]texts{
+	// bot
+	"bot.start": {
+		en: `👋 Hello, I'm your personal bot for TON Developers Platform!
+
+I will help you stay up-to-date with your new rewards and all the events for developers in TON.%s
+
+Commands:
+
+/rating - check your current rating
+
+/whois - get information about a chat participant via reply
+
+/whois [@username] - get information about a participant via Telegram username
+
+/thanks - thank a chat participant via reply
+
+/top [week|month] - show the leaderboard
+
+/help - display the list of commands`,
+		ru: `👋 Привет, я ваш персональный бот TON Developers Platform!
+
+Я буду сообщать о новых наградах и обо всех событиях для разработчиков в TON.%s
+
+Команды:
+
+/rating - узнать свой рейтинг
+
+/whois - информация об участнике чата, ответом на его сообщение
+
+/whois [@username] - информация об участнике по имени пользователя в Telegram
+
+/thanks - поблагодарить участника чата, ответом на его сообщение
+
+/top [week|month] - таблица лидеров
+
+/help - список команд`,
+	},
+	"bot.start.chats": {
+		en: " I'm also available in community chats: %s.",
+		ru: " Я также работаю в чатах сообщества: %s.",
+	},
+	"bot.help": {
+		en: `Commands:
+
+/rating - check your current rating
+
+/whois - get information about a chat participant via reply
+
+/whois [@username] - get information about a participant via Telegram username
+
+/thanks - thank a chat participant via reply
+
+/top [week|month] - show the leaderboard
+
+/help - display the list of commands`,
+		ru: `Команды:
+
+/rating - узнать свой рейтинг
+
+/whois - информация об участнике чата, ответом на его сообщение
+
+/whois [@username] - информация об участнике по имени пользователя в Telegram
+
+/thanks - поблагодарить участника чата, ответом на его сообщение
+
+/top [week|month] - таблица лидеров
+
+/help - список команд`,
+	},
+	"bot.rating": {
+		en: `🏆 TON Developers Leaderboard 🏆
+
+▪️ Username: %s
+▪️ Position: %d out of %d
+▪️ Rating: %d 💎
+
+%s`,
+		ru: `🏆 Рейтинг разработчиков TON 🏆
+
+▪️ Пользователь: %s
+▪️ Место: %d из %d
+▪️ Рейтинг: %d 💎
+
+%s`,
+	},
+	"bot.whois": {
+		en: `🏆 TON Developers Platform 🏆
+
+▪️ Username: %s
+▪️ Position: %d out of %d
+▪️ Rating: %d 💎
+
+%s`,
+		ru: `🏆 TON Developers Platform 🏆
+
+▪️ Пользователь: %s
+▪️ Место: %d из %d
+▪️ Рейтинг: %d 💎
+
+%s`,
+	},
+	"bot.whois.unknown": {
+		en: "ℹ️ This member has no profile on TON Developers Platform yet.",
+		ru: "ℹ️ У этого участника ещё нет профиля на TON Developers Platform.",
+	},
+	"bot.thanks": {
+		en: "🙏 %s thanked %s! (+%d 💎)",
+		ru: "🙏 %s благодарит %s! (+%d 💎)",
+	},
+	"bot.thanks.reciprocal": {
+		en: "🙏 %s thanked %s! Kudos given back to each other add no rating.",
+		ru: "🙏 %s благодарит %s! Взаимные благодарности не добавляют рейтинг.",
+	},
+	"bot.thanks.usage": {
+		en: "Reply /thanks to a message of the member you want to thank in a community chat.",
+		ru: "Ответьте /thanks на сообщение участника в чате сообщества, чтобы поблагодарить его.",
+	},
+	"bot.thanks.self": {
+		en: "🙂 You can't thank yourself.",
+		ru: "🙂 Нельзя благодарить самого себя.",
+	},
+	"bot.thanks.already": {
+		en: "ℹ️ You already thanked %s today.",
+		ru: "ℹ️ Вы уже благодарили %s сегодня.",
+	},
+	"bot.thanks.limit": {
+		en: "⚠️ You can give %d kudos a day, come back tomorrow.",
+		ru: "⚠️ В день можно поблагодарить %d раз, возвращайтесь завтра.",
+	},
+	"bot.last_reward": {
+		en: `<a href="%s">Last reward: %s (+%d)</a>`,
+		ru: `<a href="%s">Последняя награда: %s (+%d)</a>`,
+	},
+	"bot.last_reward.none": {
+		en: "Last reward: none",
+		ru: "Последняя награда: нет",
+	},
+	"bot.open_profile": {
+		en: "🔗 Open profile",
+		ru: "🔗 Открыть профиль",
+	},
+	"bot.welcome": {
+		en: `🏆 TON Developers Platform 🏆
+
+Hey! You don't have an account yet. 🙂
+
+Join now, become #1 in the TON Community and get <a href="%s">unique rewards</a> in TON merch store with your rating!`,
+		ru: `🏆 TON Developers Platform 🏆
+
+Привет! У вас ещё нет аккаунта. 🙂
+
+Присоединяйтесь, станьте №1 в сообществе TON и получайте за рейтинг <a href="%s">уникальные награды</a> в магазине мерча TON!`,
+	},
+	"bot.join": {
+		en: "💎 Join Developers Platform",
+		ru: "💎 Присоединиться к платформе",
+	},
+	"bot.link.success": {
+		en: "✅ Your Telegram account is now linked to %s on TON Developers Platform.",
+		ru: "✅ Ваш Telegram-аккаунт привязан к профилю %s на TON Developers Platform.",
+	},
+	"bot.link.already": {
+		en: "ℹ️ Your Telegram account is already linked to %s.",
+		ru: "ℹ️ Ваш Telegram-аккаунт уже привязан к профилю %s.",
+	},
+	"bot.link.invalid": {
+		en: "⚠️ This link is invalid or expired. Create a new one in the settings on the platform.",
+		ru: "⚠️ Ссылка недействительна или устарела. Создайте новую в настройках на платформе.",
+	},
+	"bot.link.taken": {
+		en: "⚠️ This Telegram account is already linked to another profile.",
+		ru: "⚠️ Этот Telegram-аккаунт уже привязан к другому профилю.",
+	},
+	"bot.link.exists": {
+		en: "⚠️ Another Telegram account is already linked to %s. Unlink it in the settings first.",
+		ru: "⚠️ К профилю %s уже привязан другой Telegram-аккаунт. Сначала отвяжите его в настройках.",
+	},
+	"bot.top.all": {
+		en: `🏆 TON Developers Leaderboard 🏆
+
+%s
+
+▲▼ compared to a week ago`,
+		ru: `🏆 Рейтинг разработчиков TON 🏆
+
+%s
+
+▲▼ по сравнению с прошлой неделей`,
+	},
+	"bot.top.week": {
+		en: `🏆 Top of the week 🏆
+
+%s
+
+▲▼ compared to the previous week`,
+		ru: `🏆 Лидеры недели 🏆
+
+%s
+
+▲▼ по сравнению с прошлой неделей`,
+	},
+	"bot.top.month": {
+		en: `🏆 Top of the month 🏆
+
+%s
+
+▲▼ compared to the previous 30 days`,
+		ru: `🏆 Лидеры месяца 🏆
+
+%s
+
+▲▼ по сравнению с предыдущими 30 днями`,
+	},
+	"bot.top.row": {
+		en: "%d. %s — %d 💎 %s",
+		ru: "%d. %s — %d 💎 %s",
+	},
+	"bot.top.empty": {
+		en: "Nobody got points in this period yet.",
+		ru: "За этот период ещё никто не получил баллов.",
+	},
+	"bot.top.usage": {
+		en: "Usage: /top [week|month]",
+		ru: "Использование: /top [week|month]",
+	},
+	"bot.digest.title": {
+		en: "📊 Week on TON Developers Platform",
+		ru: "📊 Неделя на TON Developers Platform",
+	},
+	"bot.digest.movers": {
+		en: "🚀 Top movers",
+		ru: "🚀 Поднялись в рейтинге",
+	},
+	"bot.digest.mover": {
+		en: "%s ▲%d to #%d",
+		ru: "%s ▲%d, теперь #%d",
+	},
+	"bot.digest.sbts": {
+		en: "🏅 New SBTs",
+		ru: "🏅 Новые SBT",
+	},
+	"bot.digest.sbt": {
+		en: "%s × %d",
+		ru: "%s × %d",
+	},
+	"bot.digest.newcomers": {
+		en: "👋 Newcomers: %d",
+		ru: "👋 Новые участники: %d",
+	},
+	"bot.digest.more": {
+		en: "and %d more",
+		ru: "и ещё %d",
+	},
+	"bot.reward.minted": {
+		en: `🎉 Congratulations %s! Your SBT has been minted! 🎉
+
+▪️ SBT: <a href="%s">%s</a>%s
+▪️ Rating: +%d points
+
+Keep up the great work! Enjoy!`,
+		ru: `🎉 Поздравляем, %s! Ваш SBT выпущен! 🎉
+
+▪️ SBT: <a href="%s">%s</a>%s
+▪️ Рейтинг: +%d баллов
+
+Так держать!`,
+	},
+	"bot.reward.description": {
+		en: "\n▪️ Description: %s",
+		ru: "\n▪️ Описание: %s",
+	},
+	"bot.reward.profile": {
+		en: "🔗 See In My Profile",
+		ru: "🔗 Открыть в профиле",
+	},
+	"bot.achievement.pending": {
+		en: `🏆 You have a new achievement waiting for your approval!
+
+▪️ Achievement: %s
+▪️ Description: %s
+
+Accept it to get the SBT to your wallet.`,
+		ru: `🏆 Новое достижение ждёт вашего подтверждения!
+
+▪️ Достижение: %s
+▪️ Описание: %s
+
+Примите его, чтобы получить SBT на свой кошелёк.`,
+	},
+	"bot.achievement.accept": {
+		en: "✅ Accept",
+		ru: "✅ Принять",
+	},
+	"bot.achievement.decline": {
+		en: "❌ Decline",
+		ru: "❌ Отклонить",
+	},
+	"bot.achievement.accepted": {
+		en: "✅ Achievement accepted! The SBT will be minted to your wallet soon.",
+		ru: "✅ Достижение принято! SBT скоро будет выпущен на ваш кошелёк.",
+	},
+	"bot.achievement.accepted.short": {
+		en: "Accepted",
+		ru: "Принято",
+	},
+	"bot.achievement.declined": {
+		en: "❌ Achievement declined.",
+		ru: "❌ Достижение отклонено.",
+	},
+	"bot.achievement.declined.short": {
+		en: "Declined",
+		ru: "Отклонено",
+	},
+	"bot.achievement.processed": {
+		en: "ℹ️ This achievement was already processed.",
+		ru: "ℹ️ Это достижение уже обработано.",
+	},
+	"bot.achievement.processed.short": {
+		en: "Already processed",
+		ru: "Уже обработано",
+	},
+	"bot.achievement.not_found": {
+		en: "Achievement not found",
+		ru: "Достижение не найдено",
+	},
+	"bot.achievement.not_yours": {
+		en: "Achievement does not belong to you",
+		ru: "Это достижение принадлежит другому пользователю",
+	},
+	"bot.achievement.unlinked": {
+		en: "Link your Telegram account on the platform first",
+		ru: "Сначала привяжите Telegram-аккаунт на платформе",
+	},
+	"bot.callback.unknown": {
+		en: "Unknown action",
+		ru: "Неизвестное действие",
+	},
+	"bot.admin.forbidden": {
+		en: "⛔️ You are not allowed to use this command.",
+		ru: "⛔️ У вас нет прав на эту команду.",
+	},
+	"bot.award.usage": {
+		en: "Usage: /award @username <prototype_id> or reply to a message with /award <prototype_id>",
+		ru: "Использование: /award @username <prototype_id> или ответ на сообщение с /award <prototype_id>",
+	},
+	"bot.award.invalid_id": {
+		en: "Prototype id must be an integer",
+		ru: "Id прототипа должен быть целым числом",
+	},
+	"bot.award.not_found": {
+		en: "Prototype not found",
+		ru: "Прототип не найден",
+	},
+	"bot.award.success": {
+		en: "🏆 %s was awarded <b>%s</b>! The achievement is waiting for approval in the profile.",
+		ru: "🏆 %s получает <b>%s</b>! Достижение ждёт подтверждения в профиле.",
+	},
+	"bot.revoke.usage": {
+		en: "Usage: /revoke <achievement_id>",
+		ru: "Использование: /revoke <achievement_id>",
+	},
+	"bot.revoke.invalid_id": {
+		en: "Achievement id must be an integer",
+		ru: "Id достижения должен быть целым числом",
+	},
+	"bot.revoke.processed": {
+		en: "Achievement is already minted or declined and can't be revoked",
+		ru: "Достижение уже выпущено или отклонено, его нельзя отозвать",
+	},
+	"bot.revoke.success": {
+		en: "Achievement %d revoked",
+		ru: "Достижение %d отозвано",
+	},
+	"bot.mute.usage": {
+		en: "Usage: /mute_points @username or reply to a message with /mute_points",
+		ru: "Использование: /mute_points @username или ответ на сообщение с /mute_points",
+	},
+	"bot.mute.success": {
+		en: "🔇 Messages of %s are not scored anymore",
+		ru: "🔇 Сообщения %s больше не приносят баллов",
+	},
+	"bot.unmute.usage": {
+		en: "Usage: /unmute_points @username or reply to a message with /unmute_points",
+		ru: "Использование: /unmute_points @username или ответ на сообщение с /unmute_points",
+	},
+	"bot.unmute.not_muted": {
+		en: "%s is not muted",
+		ru: "%s не заглушён",
+	},
+	"bot.unmute.success": {
+		en: "🔊 Messages of %s are scored again",
+		ru: "🔊 Сообщения %s снова приносят баллы",
+	},
+	"bot.stats": {
+		en: `📊 Platform stats
+
+▪️ Users: %d
+▪️ Linked Telegram accounts: %d
+▪️ Scored messages today: %d
+▪️ Achievements waiting for approval: %d
+▪️ Muted users: %d`,
+		ru: `📊 Статистика платформы
+
+▪️ Пользователи: %d
+▪️ Привязанные Telegram-аккаунты: %d
+▪️ Сообщений с баллами сегодня: %d
+▪️ Достижений ждут подтверждения: %d
+▪️ Заглушённые пользователи: %d`,
+	},
+
+	// emails
+	"email.greeting": {
+		en: "Hi %s,",
+		ru: "Привет, %s!",
+	},
+	"email.unsubscribe": {
+		en: "You can turn these emails off in the settings",
+		ru: "Эти письма можно отключить в настройках",
+	},
+	"email.token": {
+		en: "Token",
+		ru: "Токен",
+	},
+	"email.profile": {
+		en: "Profile",
+		ru: "Профиль",
+	},
+	"email.view_token": {
+		en: "View token",
+		ru: "Открыть токен",
+	},
+	"email.open_profile": {
+		en: "Open profile",
+		ru: "Открыть профиль",
+	},
+	"email.rating": {
+		en: "Your rating is now %v.",
+		ru: "Ваш рейтинг теперь %v.",
+	},
+	"email.new_sbt.subject": {
+		en: "You received %s",
+		ru: "Вы получили %s",
+	},
+	"email.new_sbt.minted": {
+		en: "%s has been minted to your wallet.",
+		ru: "%s выпущен на ваш кошелёк.",
+	},
+	"email.incoming.subject": {
+		en: "New achievement is waiting for you: %s",
+		ru: "Новое достижение ждёт вас: %s",
+	},
+	"email.incoming.body": {
+		en: "You earned %s. Accept it to get the SBT minted to your wallet.",
+		ru: "Вы получили достижение %s. Примите его, чтобы SBT был выпущен на ваш кошелёк.",
+	},
+	"email.review_achievements": {
+		en: "Review achievements",
+		ru: "Посмотреть достижения",
+	},
+	"email.rank.subject": {
+		en: "You moved up to #%v",
+		ru: "Вы поднялись на %v место",
+	},
+	"email.rank.body": {
+		en: "Your position in the leaderboard changed from #%v to #%v of %v.",
+		ru: "Ваше место в рейтинге изменилось с %v на %v из %v.",
+	},
+	"email.digest.subject": {
+		en: "Your week on TON Developers Platform",
+		ru: "Ваша неделя на TON Developers Platform",
+	},
+	"email.digest.intro": {
+		en: "Here is your week:",
+		ru: "Итоги вашей недели:",
+	},
+	"email.digest.rating": {
+		en: "Rating",
+		ru: "Рейтинг",
+	},
+	"email.digest.position": {
+		en: "Position",
+		ru: "Место",
+	},
+	"email.digest.position_of": {
+		en: "#%v of %v",
+		ru: "%v из %v",
+	},
+	"email.digest.new_rewards": {
+		en: "New SBTs",
+		ru: "Новые SBT",
+	},
+	"email.digest.messages": {
+		en: "Messages in chats",
+		ru: "Сообщения в чатах",
+	},
+	"email.digest.pending": {
+		en: "%v achievements are waiting for your approval",
+		ru: "Достижений ждут вашего подтверждения: %v",
+	},
+	"email.verification.subject": {
+		en: "Confirm your email for TON Developers Platform",
+		ru: "Подтвердите email для TON Developers Platform",
+	},
+	"email.verification.body": {
+		en: "Please confirm that you want to receive notifications at this address.",
+		ru: "Подтвердите, что хотите получать уведомления на этот адрес.",
+	},
+	"email.verification.confirm": {
+		en: "Confirm email",
+		ru: "Подтвердить email",
+	},
+	"email.verification.ttl": {
+		en: "The link is valid for 24 hours. If you didn't add this email to your profile, ignore this message.",
+		ru: "Ссылка действительна 24 часа. Если вы не добавляли этот адрес в профиль, просто проигнорируйте письмо.",
+	},
+}
@@ -0,0 +1,42 @@
+// Package kudos holds the kudos rules shared by POST /v1/kudos of the API and
+// /thanks of the bot, so both give kudos with the same limits and enqueue the
+// same rewards check.
+package kudos
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/tasks"
+	"github.com/ton-developer-program/util"
+)
+
+// Policy returns the policy of KudosModel.Give from the KUDOS_* settings
+func Policy(cfg util.KudosConfig) database.KudosPolicy {
+	return database.KudosPolicy{
+		Weight:           cfg.Weight,
+		DailyLimit:       cfg.DailyLimit,
+		ReciprocalWindow: time.Duration(cfg.ReciprocalDays) * 24 * time.Hour,
+	}
+}
+
+// CheckRatingRewards enqueues the check of rewards the receiver earned with the kudos.
+// Reciprocal kudos change neither rating nor kudos count, nothing is enqueued and the
+// returned info is nil.
+func CheckRatingRewards(ctx context.Context, client *asynq.Client, given *database.Kudos) (*asynq.TaskInfo, error) {
+	if given.Reciprocal {
+		return nil, nil
+	}
+
+	payload, err := json.Marshal(given.ReceiverID)
+	if err != nil {
+		return nil, err
+	}
+
+	task := tasks.NewTask(ctx, database.TYPE_CHECK_RATING_REWARDS, payload)
+
+	return client.Enqueue(task, asynq.ProcessIn(5*time.Second), asynq.MaxRetry(5), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_NORMAL))
+}
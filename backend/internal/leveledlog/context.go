@@ -0,0 +1,32 @@
+package leveledlog
+
+import "context"
+
+type contextKey string
+
+const (
+	fieldsContextKey    = contextKey("log_fields")
+	requestIDContextKey = contextKey("request_id")
+)
+
+// ContextWithFields returns a context carrying key-value pairs logged by Logger.Ctx
+func ContextWithFields(ctx context.Context, kv ...any) context.Context {
+	fields := append(append([]any{}, FieldsFromContext(ctx)...), kv...)
+	return context.WithValue(ctx, fieldsContextKey, fields)
+}
+
+func FieldsFromContext(ctx context.Context) []any {
+	fields, _ := ctx.Value(fieldsContextKey).([]any)
+	return fields
+}
+
+// ContextWithRequestID stores request id, it is also added to log fields
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	ctx = context.WithValue(ctx, requestIDContextKey, requestID)
+	return ContextWithFields(ctx, "request_id", requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
@@ -1,6 +1,7 @@
 package leveledlog
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -49,7 +50,10 @@ type Logger struct {
 	minLevel Level
 	useJSON  bool
 	colorize bool
-	mu       sync.Mutex
+	// key-value pairs added to every line
+	fields []any
+	// shared with loggers created by With
+	mu *sync.Mutex
 }
 
 func NewLogger(out io.Writer, minLevel Level, colorize bool) *Logger {
@@ -57,6 +61,7 @@ func NewLogger(out io.Writer, minLevel Level, colorize bool) *Logger {
 		out:      out,
 		minLevel: minLevel,
 		colorize: colorize,
+		mu:       &sync.Mutex{},
 	}
 }
 
@@ -65,49 +70,85 @@ func NewJSONLogger(out io.Writer, minLevel Level) *Logger {
 		out:      out,
 		minLevel: minLevel,
 		useJSON:  true,
+		mu:       &sync.Mutex{},
 	}
 }
 
+// With returns a logger that adds key-value pairs to every line
+func (l *Logger) With(kv ...any) *Logger {
+	if len(kv) == 0 {
+		return l
+	}
+
+	child := *l
+	child.fields = append(append([]any{}, l.fields...), kv...)
+
+	return &child
+}
+
+// Ctx returns a logger with fields stored in the context, e.g. request_id
+func (l *Logger) Ctx(ctx context.Context) *Logger {
+	return l.With(FieldsFromContext(ctx)...)
+}
+
 func (l *Logger) Info(format string, v ...any) {
 	message := fmt.Sprintf(format, v...)
-	l.print(LevelInfo, message, nil)
+	l.print(LevelInfo, message, nil, nil)
 }
 
 func (l *Logger) Warning(format string, v ...any) {
 	message := fmt.Sprintf(format, v...)
-	l.print(LevelWarning, message, nil)
+	l.print(LevelWarning, message, nil, nil)
 }
 
 func (l *Logger) Error(err error, trace []byte) {
-	l.print(LevelError, err.Error(), trace)
+	l.print(LevelError, err.Error(), trace, nil)
 }
 
 func (l *Logger) Fatal(err error, trace []byte) {
-	l.print(LevelFatal, err.Error(), trace)
+	l.print(LevelFatal, err.Error(), trace, nil)
 	os.Exit(1)
 }
 
+// Infow logs message with key-value pairs, e.g. Infow("block processed", "seqno", seqno)
+func (l *Logger) Infow(message string, kv ...any) {
+	l.print(LevelInfo, message, nil, kv)
+}
+
+func (l *Logger) Warningw(message string, kv ...any) {
+	l.print(LevelWarning, message, nil, kv)
+}
+
+func (l *Logger) Errorw(err error, kv ...any) {
+	l.print(LevelError, err.Error(), nil, kv)
+}
+
 func (l *Logger) Write(message []byte) (n int, err error) {
 	messageStr := string(message)
 
 	if strings.Contains(messageStr, "runtime/panic") {
-		return l.print(LevelError, messageStr, nil)
+		return l.print(LevelError, messageStr, nil, nil)
 	}
 
-	return l.print(LevelWarning, messageStr, nil)
+	return l.print(LevelWarning, messageStr, nil, nil)
 }
 
-func (l *Logger) print(level Level, message string, trace []byte) (n int, err error) {
+func (l *Logger) print(level Level, message string, trace []byte, kv []any) (n int, err error) {
 	if level < l.minLevel {
 		return 0, nil
 	}
 
+	fields := l.fields
+	if len(kv) > 0 {
+		fields = append(append([]any{}, l.fields...), kv...)
+	}
+
 	var line string
 
 	if l.useJSON {
-		line = jsonLine(level, message, trace)
+		line = jsonLine(level, message, trace, fields)
 	} else {
-		line = textLine(level, message, trace, l.colorize)
+		line = textLine(level, message, trace, fields, l.colorize)
 	}
 
 	l.mu.Lock()
@@ -116,9 +157,22 @@ func (l *Logger) print(level Level, message string, trace []byte) (n int, err er
 	return fmt.Fprintln(l.out, line)
 }
 
-func textLine(level Level, message string, trace []byte, colorize bool) string {
+func textLine(level Level, message string, trace []byte, fields []any, colorize bool) string {
 	line := fmt.Sprintf("level=%q time=%q message=%q", level, time.Now().UTC().Format(time.RFC3339), strings.TrimSpace(message))
 
+	forEachField(fields, func(key string, value any) {
+		switch v := value.(type) {
+		case string:
+			line += fmt.Sprintf(" %s=%q", key, v)
+		case error:
+			line += fmt.Sprintf(" %s=%q", key, v.Error())
+		case fmt.Stringer:
+			line += fmt.Sprintf(" %s=%q", key, v.String())
+		default:
+			line += fmt.Sprintf(" %s=%v", key, v)
+		}
+	})
+
 	if colorize {
 		switch level {
 		case LevelError, LevelFatal:
@@ -137,20 +191,27 @@ func textLine(level Level, message string, trace []byte, colorize bool) string {
 	return line
 }
 
-func jsonLine(level Level, message string, trace []byte) string {
-	aux := struct {
-		Level   string `json:"level"`
-		Time    string `json:"time"`
-		Message string `json:"message"`
-		Trace   string `json:"trace,omitempty"`
-	}{
-		Level:   level.String(),
-		Time:    time.Now().UTC().Format(time.RFC3339),
-		Message: message,
-	}
+func jsonLine(level Level, message string, trace []byte, fields []any) string {
+	aux := make(map[string]any, len(fields)/2+4)
+
+	forEachField(fields, func(key string, value any) {
+		switch v := value.(type) {
+		case error:
+			aux[key] = v.Error()
+		case fmt.Stringer:
+			aux[key] = v.String()
+		default:
+			aux[key] = v
+		}
+	})
+
+	// fields can't override the standard keys
+	aux["level"] = level.String()
+	aux["time"] = time.Now().UTC().Format(time.RFC3339)
+	aux["message"] = message
 
 	if trace != nil {
-		aux.Trace = string(trace)
+		aux["trace"] = string(trace)
 	}
 
 	var line []byte
@@ -162,3 +223,20 @@ func jsonLine(level Level, message string, trace []byte) string {
 
 	return string(line)
 }
+
+// forEachField walks key-value pairs, a value without a key is logged under "extra"
+func forEachField(fields []any, fn func(key string, value any)) {
+	for i := 0; i < len(fields); i += 2 {
+		if i+1 == len(fields) {
+			fn("extra", fields[i])
+			return
+		}
+
+		key, ok := fields[i].(string)
+		if !ok {
+			key = fmt.Sprint(fields[i])
+		}
+
+		fn(key, fields[i+1])
+	}
+}
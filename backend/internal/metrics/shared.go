@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics shared by api, worker and bot
+var (
+	BuildInfo = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tdp_build_info",
+		Help: "Service name and version, always 1.",
+	}, []string{"service", "version"})
+
+	// api
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "tdp_http_requests_total",
+		Help: "HTTP requests by route pattern, method and status code.",
+	}, []string{"route", "method", "status"})
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tdp_http_request_duration_seconds",
+		Help:    "HTTP request latency by route pattern and method.",
+		Buckets: DefaultBuckets,
+	}, []string{"route", "method"})
+
+	// worker
+	Tasks = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "tdp_tasks_total",
+		Help: "Processed asynq tasks by type and outcome (success, failure).",
+	}, []string{"type", "outcome"})
+	TaskDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tdp_task_duration_seconds",
+		Help:    "Asynq task processing time by type.",
+		Buckets: DefaultBuckets,
+	}, []string{"type"})
+
+	ListenerMasterchainSeqno = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "tdp_listener_masterchain_seqno",
+		Help: "Latest masterchain block seqno seen by the listener.",
+	})
+	ListenerProcessedSeqno = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "tdp_listener_processed_seqno",
+		Help: "Last masterchain block seqno processed by the listener.",
+	})
+	ListenerLag = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "tdp_listener_lag_blocks",
+		Help: "Masterchain blocks seen but not processed by the listener.",
+	})
+	ListenerLastProcessed = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "tdp_listener_last_processed_timestamp_seconds",
+		Help: "Unix time when the listener processed the last block.",
+	})
+
+	LiteserverErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "tdp_liteserver_errors_total",
+		Help: "Failed liteserver calls by call name.",
+	}, []string{"call"})
+
+	AdminWalletBalance = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "tdp_admin_wallet_balance_ton",
+		Help: "Balance of the admin wallet in TON.",
+	})
+	StoredRewardsBacklog = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "tdp_stored_rewards_backlog",
+		Help: "Approved stored rewards waiting to be minted.",
+	})
+
+	// bot
+	BotUpdates = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "tdp_bot_updates_total",
+		Help: "Telegram updates and Discord events processed by the bot by command and outcome (success, failure, skipped).",
+	}, []string{"command", "outcome"})
+	BotScoring = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "tdp_bot_scoring_total",
+		Help: "Group messages of linked users by scoring decision (scored, reaction or the reason the message is not scored).",
+	}, []string{"decision"})
+)
+
+// SetListenerSeqno updates listener gauges, lag is calculated from both seqno
+func SetListenerSeqno(latest, processed uint32) {
+	ListenerMasterchainSeqno.Set(float64(latest))
+	ListenerProcessedSeqno.Set(float64(processed))
+
+	lag := float64(0)
+	if latest > processed {
+		lag = float64(latest - processed)
+	}
+
+	ListenerLag.Set(lag)
+}
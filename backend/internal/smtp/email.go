@@ -0,0 +1,18 @@
+package smtp
+
+// templates in assets/emails sent by the worker
+const (
+	TemplateEmailVerification   = "email-verification.tmpl"
+	TemplateNewSBT              = "new-sbt.tmpl"
+	TemplateIncomingAchievement = "incoming-achievement.tmpl"
+	TemplateRankChange          = "rank-change.tmpl"
+	TemplateWeeklyDigest        = "weekly-digest.tmpl"
+)
+
+// Email is the payload of the send email task, texts are in Language, English when it is empty
+type Email struct {
+	Recipient string         `json:"recipient"`
+	Template  string         `json:"template"`
+	Language  string         `json:"language"`
+	Data      map[string]any `json:"data"`
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/ton-developer-program/assets"
 	"github.com/ton-developer-program/internal/funcs"
+	"github.com/ton-developer-program/internal/i18n"
 
 	"github.com/go-mail/mail/v2"
 
@@ -29,6 +30,13 @@ func NewMailer(host string, port int, username, password, from string) *Mailer {
 }
 
 func (m *Mailer) Send(recipient string, data any, patterns ...string) error {
+	return m.SendIn(i18n.English, recipient, data, patterns...)
+}
+
+// SendIn renders templates in the language, {{t "key" args}} formats a message of the i18n catalog
+func (m *Mailer) SendIn(lang, recipient string, data any, patterns ...string) error {
+	printer := i18n.Printer(lang)
+
 	for i := range patterns {
 		patterns[i] = "emails/" + patterns[i]
 	}
@@ -37,7 +45,7 @@ func (m *Mailer) Send(recipient string, data any, patterns ...string) error {
 	msg.SetHeader("To", recipient)
 	msg.SetHeader("From", m.from)
 
-	ts, err := textTemplate.New("").Funcs(funcs.TemplateFuncs).ParseFS(assets.EmbeddedFiles, patterns...)
+	ts, err := textTemplate.New("").Funcs(funcs.TemplateFuncs).Funcs(textTemplate.FuncMap{"t": printer.Sprintf}).ParseFS(assets.EmbeddedFiles, patterns...)
 	if err != nil {
 		return err
 	}
@@ -59,7 +67,7 @@ func (m *Mailer) Send(recipient string, data any, patterns ...string) error {
 	msg.SetBody("text/plain", plainBody.String())
 
 	if ts.Lookup("htmlBody") != nil {
-		ts, err := htmlTemplate.New("").Funcs(funcs.TemplateFuncs).ParseFS(assets.EmbeddedFiles, patterns...)
+		ts, err := htmlTemplate.New("").Funcs(funcs.TemplateFuncs).Funcs(htmlTemplate.FuncMap{"t": printer.Sprintf}).ParseFS(assets.EmbeddedFiles, patterns...)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,112 @@
+// Package tasks wraps asynq task payloads in an envelope carrying the id of
+// the request that enqueued the task and its trace context, so worker logs
+// and spans can be tied to it.
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/leveledlog"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+type envelope struct {
+	RequestID   string          `json:"request_id"`
+	Traceparent string          `json:"traceparent,omitempty"`
+	Tracestate  string          `json:"tracestate,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// the envelope has fields of the W3C trace context only, so it's propagated in this format
+// in every service, whether tracing is set up there or not
+var propagator = propagation.TraceContext{}
+
+// Get, Set and Keys make the envelope the carrier of the W3C trace context
+func (e *envelope) Get(key string) string {
+	switch key {
+	case "traceparent":
+		return e.Traceparent
+	case "tracestate":
+		return e.Tracestate
+	}
+
+	return ""
+}
+
+func (e *envelope) Set(key, value string) {
+	switch key {
+	case "traceparent":
+		e.Traceparent = value
+	case "tracestate":
+		e.Tracestate = value
+	}
+}
+
+func (e *envelope) Keys() []string {
+	return []string{"traceparent", "tracestate"}
+}
+
+// NewTask wraps marshaled payload with the request id and the current span from ctx
+func NewTask(ctx context.Context, typename string, payload []byte, opts ...asynq.Option) *asynq.Task {
+	if payload == nil {
+		payload = []byte("null")
+	}
+
+	e := envelope{
+		RequestID: leveledlog.RequestIDFromContext(ctx),
+		Payload:   payload,
+	}
+
+	propagator.Inject(ctx, &e)
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		// invalid json payload, the handler gets it as is
+		return asynq.NewTask(typename, payload, opts...)
+	}
+
+	return asynq.NewTask(typename, b, opts...)
+}
+
+// unwrap returns envelope of the task, ok is false for tasks enqueued without NewTask
+func unwrap(t *asynq.Task) (envelope, bool) {
+	var e envelope
+
+	dec := json.NewDecoder(bytes.NewReader(t.Payload()))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&e); err != nil || e.Payload == nil {
+		return envelope{}, false
+	}
+
+	return e, true
+}
+
+// Payload returns the original payload
+func Payload(t *asynq.Task) []byte {
+	if e, ok := unwrap(t); ok {
+		return e.Payload
+	}
+
+	return t.Payload()
+}
+
+// Decode unmarshals the original payload into v
+func Decode(t *asynq.Task, v any) error {
+	return json.Unmarshal(Payload(t), v)
+}
+
+// RequestID returns id of the request that enqueued the task
+func RequestID(t *asynq.Task) string {
+	e, _ := unwrap(t)
+	return e.RequestID
+}
+
+// Extract returns ctx with the span that enqueued the task as the remote parent
+func Extract(ctx context.Context, t *asynq.Task) context.Context {
+	e, _ := unwrap(t)
+	return propagator.Extract(ctx, &e)
+}
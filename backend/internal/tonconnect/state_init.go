@@ -93,7 +93,7 @@ func NewTonConnection(pool *liteclient.ConnectionPool, config util.Config) (*ton
 			return nil, err
 		}
 
-		return ton.NewAPIClient(pool), nil
+		return ton.NewAPIClient(&tracedLiteClient{pool}), nil
 	}
 	
 	err := pool.AddConnection(context.Background(), config.Ton.NodeAddress, config.Ton.ApiKey)
@@ -101,5 +101,5 @@ func NewTonConnection(pool *liteclient.ConnectionPool, config util.Config) (*ton
 		return nil, err
 	}
 
-	return ton.NewAPIClient(pool), nil
+	return ton.NewAPIClient(&tracedLiteClient{pool}), nil
 }
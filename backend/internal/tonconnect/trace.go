@@ -0,0 +1,42 @@
+package tonconnect
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ton-developer-program/internal/tracing"
+	"github.com/xssnick/tonutils-go/tl"
+	"github.com/xssnick/tonutils-go/ton"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedLiteClient adds a span for every liteserver query made with a traced context
+type tracedLiteClient struct {
+	ton.LiteClient
+}
+
+func (c *tracedLiteClient) QueryLiteserver(ctx context.Context, payload tl.Serializable, result tl.Serializable) error {
+	method := fmt.Sprintf("%T", payload)
+	method = method[strings.LastIndex(method, ".")+1:]
+
+	// queries made after WaitForBlock are sent as raw bytes with waitMasterchainSeqno prefix
+	if method == "Raw" {
+		method = "WaitMasterchainSeqno"
+	}
+
+	ctx, span := tracing.StartChild(ctx, "liteserver "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "liteserver"),
+			attribute.String("rpc.method", method),
+		),
+	)
+	defer span.End()
+
+	err := c.LiteClient.QueryLiteserver(ctx, payload, result)
+	tracing.RecordError(span, err)
+
+	return err
+}
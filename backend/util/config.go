@@ -4,10 +4,13 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
+const EnvDevelopment = "development"
+
 type Config struct {
 	App      AppConfig 
 	Database DatabaseConfig 
@@ -16,6 +19,110 @@ type Config struct {
 	Redis    RedisConfig 
 	Auth     AuthConfig 
 	AWS 	AWSConfig
+	RateLimit RateLimitConfig
+	CORS     CORSConfig
+	Metrics  MetricsConfig
+	Health   HealthConfig
+	Log      LogConfig
+	Tracing  TracingConfig
+	Notifications NotificationsConfig
+	Scoring  ScoringConfig
+	Bot      BotConfig
+	Discord  DiscordConfig
+	Kudos    KudosConfig
+}
+
+type KudosConfig struct {
+	// rating the receiver gets for a kudos
+	Weight int64
+	// kudos a user can give per UTC day
+	DailyLimit int
+	// a kudos back within this many days is reciprocal, it adds no rating and isn't
+	// counted in activity thresholds
+	ReciprocalDays int
+}
+
+type BotConfig struct {
+	// polling or webhook
+	Mode string
+	// public https url Telegram sends updates to, its path is served on WebhookAddr
+	WebhookURL string
+	WebhookAddr string
+	// compared with X-Telegram-Bot-Api-Secret-Token header of every update
+	WebhookSecret string
+	// updates are processed concurrently, updates of one chat in order
+	Workers int
+	// cron spec of the leaderboard digest posted to chats, in UTC
+	DigestCron string
+	// username of the bot without @, used in t.me links
+	Username string
+}
+
+type DiscordConfig struct {
+	// bot token, the Discord adapter of the bot runs only when it's set
+	Token string
+	// ids of guilds where messages are scored and slash commands are registered
+	Guilds []string
+	// base url of the REST API used by the OAuth callback, the bot discovers the gateway itself
+	APIURL string
+}
+
+type ScoringConfig struct {
+	// overrides in format chat=min_length:cooldown_sec:daily_cap:reaction_bonus:max_bonus,
+	// chat is telegram chat id, discord channel id or default
+	Policies string
+}
+
+type NotificationsConfig struct {
+	// cron spec of the weekly digest email, in UTC
+	DigestCron string
+	// in-app notifications are deleted after this many days
+	RetentionDays int
+}
+
+type TracingConfig struct {
+	// none, stdout or otlp
+	Exporter string
+	// OTLP/HTTP collector base url
+	Endpoint string
+	// share of new traces recorded, from 0 to 1
+	SampleRatio float64
+}
+
+type LogConfig struct {
+	// text or json
+	Format string
+}
+
+type HealthConfig struct {
+	// worker is not ready when the listener processed no block for this long
+	ListenerMaxAgeSec   int
+	// readiness is degraded when admin wallet has less TON
+	MinWalletBalanceTON float64
+}
+
+type MetricsConfig struct {
+	// listen address of /metrics, every service serves it apart from its public port
+	Addr string
+}
+
+type CORSConfig struct {
+	// origins of the public site, "*" allows any origin
+	PublicOrigins    []string
+	// origins of the admin app, used for /v1/admin routes
+	AdminOrigins     []string
+	AllowCredentials bool
+	MaxAgeSec        int
+	// send Strict-Transport-Security, enable only when served over https
+	HSTS             bool
+}
+
+type RateLimitConfig struct {
+	Enabled    bool
+	// take client ip from X-Real-IP set by nginx
+	TrustProxy bool
+	// overrides in format name=rate:burst,name=rate:burst, rate is requests per second
+	Policies   string
 }
 
 type AWSConfig struct {
@@ -27,6 +134,8 @@ type AWSConfig struct {
 
 
 type AppConfig struct {
+	// APP_ENV, "development" when it's not set
+	Env                string
 	BaseUrl            string 
 	HttpPort           int   
 	DomainName	      string	
@@ -39,6 +148,10 @@ type AppConfig struct {
 	AlloweGroupChatID    int64
 	BasicUsername            string
 	BasicPassword            string
+	AttestationKey     string
+	AttestationTTLSec  int
+	// token address is appended to it in notifications
+	TokenUrl           string
 }
 
 type DatabaseConfig struct {
@@ -75,6 +188,9 @@ type AuthConfig struct {
 	GithubClientId    string 
 	GithubClientSecret string
 	TelegramBotToken  string
+	DiscordRedirectUrl string
+	DiscordClientId    string
+	DiscordClientSecret string
 }
 
 func LoadConfig() (config Config, err error) {
@@ -99,7 +215,17 @@ func LoadConfig() (config Config, err error) {
 
 	int64AlloweGroupChatID, _ := strconv.ParseInt(os.Getenv("APP_ALLOWED_GROUP_CHAT_ID"), 10, 64)
 
+	attestationTTLSec, _ := strconv.Atoi(os.Getenv("APP_ATTESTATION_TTL_SEC"))
+	if attestationTTLSec == 0 {
+		attestationTTLSec = 600
+	}
+
+	if env == "" || env == "dev" {
+		env = EnvDevelopment
+	}
+
 	appConfig := AppConfig{
+		Env:                env,
 		BaseUrl:            os.Getenv("APP_BASE_URL"),
 		DomainName:         os.Getenv("APP_DOMAIN_NAME"),
 		HttpPort:           httpPort,
@@ -112,6 +238,9 @@ func LoadConfig() (config Config, err error) {
 		AlloweGroupChatID: int64AlloweGroupChatID,
 		BasicUsername: os.Getenv("APP_BASIC_USERNAME"),
 		BasicPassword: os.Getenv("APP_BASIC_PASSWORD"),
+		AttestationKey: os.Getenv("APP_ATTESTATION_KEY"),
+		AttestationTTLSec: attestationTTLSec,
+		TokenUrl: envOrDefault("APP_TOKEN_URL", "https://getgems.io/nft/"),
 	}
 
 	autoMigrate, _ := strconv.ParseBool(os.Getenv("DATABASE_AUTOMIGRATE"))
@@ -158,6 +287,9 @@ func LoadConfig() (config Config, err error) {
 		GithubClientId:    os.Getenv("AUTH_GITHUB_CLIENT_ID"),
 		GithubClientSecret: os.Getenv("AUTH_GITHUB_CLIENT_SECRET"),
 		TelegramBotToken:  os.Getenv("AUTH_TELEGRAM_BOT_TOKEN"),
+		DiscordRedirectUrl: os.Getenv("AUTH_DISCORD_REDIRECT_URL"),
+		DiscordClientId:    os.Getenv("AUTH_DISCORD_CLIENT_ID"),
+		DiscordClientSecret: os.Getenv("AUTH_DISCORD_CLIENT_SECRET"),
 	}
 
 	awsConfig := AWSConfig{
@@ -167,6 +299,74 @@ func LoadConfig() (config Config, err error) {
 		AWSBucket: os.Getenv("AWS_BUCKET"),
 	}
 
+	rateLimitEnabled, err := strconv.ParseBool(os.Getenv("RATE_LIMIT_ENABLED"))
+	if err != nil {
+		rateLimitEnabled = true
+	}
+
+	rateLimitTrustProxy, _ := strconv.ParseBool(os.Getenv("RATE_LIMIT_TRUST_PROXY"))
+
+	rateLimitConfig := RateLimitConfig{
+		Enabled:    rateLimitEnabled,
+		TrustProxy: rateLimitTrustProxy,
+		Policies:   os.Getenv("RATE_LIMIT_POLICIES"),
+	}
+
+	corsAllowCredentials, _ := strconv.ParseBool(os.Getenv("CORS_ALLOW_CREDENTIALS"))
+	corsMaxAgeSec, err := strconv.Atoi(os.Getenv("CORS_MAX_AGE_SEC"))
+	if err != nil {
+		corsMaxAgeSec = 600
+	}
+	hsts, _ := strconv.ParseBool(os.Getenv("SECURITY_HSTS"))
+
+	corsConfig := CORSConfig{
+		PublicOrigins:    splitList(envOrDefault("CORS_PUBLIC_ORIGINS", "*")),
+		AdminOrigins:     splitList(envOrDefault("CORS_ADMIN_ORIGINS", "https://tdpadmin.tonbuilders.com")),
+		AllowCredentials: corsAllowCredentials,
+		MaxAgeSec:        corsMaxAgeSec,
+		HSTS:             hsts,
+	}
+
+	healthListenerMaxAgeSec, err := strconv.Atoi(os.Getenv("HEALTH_LISTENER_MAX_AGE_SEC"))
+	if err != nil {
+		healthListenerMaxAgeSec = 180
+	}
+
+	healthMinWalletBalance, err := strconv.ParseFloat(os.Getenv("HEALTH_MIN_WALLET_BALANCE_TON"), 64)
+	if err != nil {
+		healthMinWalletBalance = 1
+	}
+
+	notificationsRetentionDays, err := strconv.Atoi(os.Getenv("NOTIFICATIONS_RETENTION_DAYS"))
+	if err != nil {
+		notificationsRetentionDays = 90
+	}
+
+	botWorkers, err := strconv.Atoi(os.Getenv("BOT_WORKERS"))
+	if err != nil || botWorkers < 1 {
+		botWorkers = 8
+	}
+
+	kudosWeight, err := strconv.ParseInt(os.Getenv("KUDOS_WEIGHT"), 10, 64)
+	if err != nil || kudosWeight < 0 {
+		kudosWeight = 1
+	}
+
+	kudosDailyLimit, err := strconv.Atoi(os.Getenv("KUDOS_DAILY_LIMIT"))
+	if err != nil || kudosDailyLimit < 1 {
+		kudosDailyLimit = 5
+	}
+
+	kudosReciprocalDays, err := strconv.Atoi(os.Getenv("KUDOS_RECIPROCAL_DAYS"))
+	if err != nil || kudosReciprocalDays < 0 {
+		kudosReciprocalDays = 7
+	}
+
+	tracingSampleRatio, err := strconv.ParseFloat(os.Getenv("TRACING_SAMPLE_RATIO"), 64)
+	if err != nil {
+		tracingSampleRatio = 1
+	}
+
 	config = Config{
 		App:      appConfig,
 		Database: databaseConfig,
@@ -175,7 +375,71 @@ func LoadConfig() (config Config, err error) {
 		Redis:    redisConfig,
 		Auth:     authConfig,
 		AWS: awsConfig,
+		RateLimit: rateLimitConfig,
+		CORS: corsConfig,
+		Metrics: MetricsConfig{
+			Addr: envOrDefault("METRICS_ADDR", ":9090"),
+		},
+		Log: LogConfig{
+			Format: envOrDefault("LOG_FORMAT", "text"),
+		},
+		Health: HealthConfig{
+			ListenerMaxAgeSec:   healthListenerMaxAgeSec,
+			MinWalletBalanceTON: healthMinWalletBalance,
+		},
+		Notifications: NotificationsConfig{
+			DigestCron:    envOrDefault("NOTIFICATIONS_DIGEST_CRON", "0 9 * * 1"),
+			RetentionDays: notificationsRetentionDays,
+		},
+		Scoring: ScoringConfig{
+			Policies: os.Getenv("SCORING_POLICIES"),
+		},
+		Bot: BotConfig{
+			Mode:          envOrDefault("BOT_MODE", "polling"),
+			WebhookURL:    os.Getenv("BOT_WEBHOOK_URL"),
+			WebhookAddr:   envOrDefault("BOT_WEBHOOK_ADDR", ":8081"),
+			WebhookSecret: os.Getenv("BOT_WEBHOOK_SECRET"),
+			Workers:       botWorkers,
+			DigestCron:    envOrDefault("BOT_DIGEST_CRON", "0 10 * * 1"),
+			Username:      os.Getenv("BOT_USERNAME"),
+		},
+		Discord: DiscordConfig{
+			Token:  os.Getenv("DISCORD_BOT_TOKEN"),
+			Guilds: splitList(os.Getenv("DISCORD_GUILDS")),
+			APIURL: envOrDefault("DISCORD_API_URL", "https://discord.com/api/v10"),
+		},
+		Kudos: KudosConfig{
+			Weight:         kudosWeight,
+			DailyLimit:     kudosDailyLimit,
+			ReciprocalDays: kudosReciprocalDays,
+		},
+		Tracing: TracingConfig{
+			Exporter:    envOrDefault("TRACING_EXPORTER", "none"),
+			Endpoint:    envOrDefault("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+			SampleRatio: tracingSampleRatio,
+		},
+	}
+
+	return config, nil
+}
+
+// splitList splits comma separated env value, empty items are skipped
+func splitList(value string) []string {
+	items := []string{}
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
 	}
 
-	return
-}
\ No newline at end of file
+	return defaultValue
+}
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/tasks"
 	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/tlb"
 	"github.com/xssnick/tonutils-go/ton/nft"
@@ -17,63 +22,58 @@ import (
 	"github.com/xssnick/tonutils-go/tvm/cell"
 )
 
-
 func (app *application) AddCollection(ctx context.Context, t *asynq.Task) error {
 
 	var collection database.SBTCollection
 
-
-	if err := json.Unmarshal(t.Payload(), &collection); err != nil {
+	if err := tasks.Decode(t, &collection); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
 	}
 
-	insertedCollection, err := app.getCollection(collection)
+	insertedCollection, err := app.getCollection(ctx, collection)
 	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error running get_collections: %v", err))
+		app.logger.Ctx(ctx).Warningw("error getting collections", "error", err)
 		return err
 	}
 
 	// for loop to next item index from 0 to next item index
 	for i := int64(0); i < insertedCollection.NextItemIndex; i++ {
-			payloadData := struct {
-				CollectionAddress string `json:"collection_address"`
-				ItemIndex 	   int64  `json:"item_index"`
-			}{
-				CollectionAddress: insertedCollection.FriendlyAddress,
-				ItemIndex: i,
-			}
-
-			payload, err := json.Marshal(payloadData)
+		payloadData := struct {
+			CollectionAddress string `json:"collection_address"`
+			ItemIndex         int64  `json:"item_index"`
+		}{
+			CollectionAddress: insertedCollection.FriendlyAddress,
+			ItemIndex:         i,
+		}
 
-			if err != nil {
-				app.logger.Error(err, nil)
-				return err
-			}
+		payload, err := json.Marshal(payloadData)
 
-			runGetNfts := asynq.NewTask(database.TYPE_MIGRATE_NFT, payload)
+		if err != nil {
+			app.logger.Ctx(ctx).Error(err, nil)
+			return err
+		}
 
+		runGetNfts := tasks.NewTask(ctx, database.TYPE_MIGRATE_NFT, payload)
 
-			info, err := app.asynqClient.Enqueue(runGetNfts, asynq.MaxRetry(5),  asynq.ProcessIn(5*time.Second), asynq.Retention(10 * time.Minute), asynq.Queue(database.PRIORITY_URGENT))
-			if err != nil {
-				app.logger.Error(fmt.Errorf("error: %s", err), nil)
-				return err
-			}
+		info, err := app.asynqClient.Enqueue(runGetNfts, asynq.MaxRetry(5), asynq.ProcessIn(5*time.Second), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_URGENT))
+		if err != nil {
+			app.logger.Ctx(ctx).Error(err, nil)
+			return err
+		}
 
-			app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))
+		app.logger.Ctx(ctx).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
 	}
 
-	app.logger.Info(fmt.Sprintf("collection %s migrated", insertedCollection.FriendlyAddress))
-	
+	app.logger.Ctx(ctx).Infow("collection migrated", "collection", insertedCollection.FriendlyAddress)
+
 	return nil
 }
 
-
 func (app *application) MigrateCollection(ctx context.Context, t *asynq.Task) error {
 
 	var collectionAddr string
 
-
-	if err := json.Unmarshal(t.Payload(), &collectionAddr); err != nil {
+	if err := tasks.Decode(t, &collectionAddr); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
 	}
 
@@ -83,66 +83,63 @@ func (app *application) MigrateCollection(ctx context.Context, t *asynq.Task) er
 
 	collectionData, err := collectionClient.GetCollectionData(ctx)
 	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error running get_collection_data: %v", err))
+		app.logger.Ctx(ctx).Warningw("error getting collection data", "error", err)
 		return err
 	}
 
 	// update collection in db
-	err = app.sqlModels.Nfts.UpdateNextItemIndex(collectionAddr, collectionData.NextItemIndex.Int64())
+	err = app.sqlModels.Nfts.UpdateNextItemIndex(ctx, collectionAddr, collectionData.NextItemIndex.Int64())
 	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error running update_collection: %v", err))
+		app.logger.Ctx(ctx).Warningw("error updating collection", "error", err)
 		return err
 	}
 
 	// for loop to next item index from 0 to next item index
 	for i := int64(0); i < collectionData.NextItemIndex.Int64(); i++ {
-			payloadData := struct {
-				CollectionAddress string `json:"collection_address"`
-				ItemIndex 	   int64  `json:"item_index"`
-			}{
-				CollectionAddress: collectionAddr,
-				ItemIndex: i,
-			}
+		payloadData := struct {
+			CollectionAddress string `json:"collection_address"`
+			ItemIndex         int64  `json:"item_index"`
+		}{
+			CollectionAddress: collectionAddr,
+			ItemIndex:         i,
+		}
 
-			payload, err := json.Marshal(payloadData)
+		payload, err := json.Marshal(payloadData)
 
-			if err != nil {
-				app.logger.Error(err, nil)
-				return err
-			}
+		if err != nil {
+			app.logger.Ctx(ctx).Error(err, nil)
+			return err
+		}
 
-			runGetNfts := asynq.NewTask(database.TYPE_MIGRATE_NFT, payload)
+		runGetNfts := tasks.NewTask(ctx, database.TYPE_MIGRATE_NFT, payload)
 
-			info, err := app.asynqClient.Enqueue(runGetNfts, asynq.MaxRetry(5),  asynq.ProcessIn(5*time.Second), asynq.Retention(10 * time.Minute), asynq.Queue(database.PRIORITY_URGENT))
-			if err != nil {
-				app.logger.Error(fmt.Errorf("error: %s", err), nil)
-				return err
-			}
+		info, err := app.asynqClient.Enqueue(runGetNfts, asynq.MaxRetry(5), asynq.ProcessIn(5*time.Second), asynq.Retention(10*time.Minute), asynq.Queue(database.PRIORITY_URGENT))
+		if err != nil {
+			app.logger.Ctx(ctx).Error(err, nil)
+			return err
+		}
 
-			app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))
+		app.logger.Ctx(ctx).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
 	}
 
-	app.logger.Info(fmt.Sprintf("collection %s migrated", collectionAddr))
-	
+	app.logger.Ctx(ctx).Infow("collection migrated", "collection", collectionAddr)
+
 	return nil
 }
 
-
-
-
 func (app *application) MigrateNFT(ctx context.Context, t *asynq.Task) error {
 
 	var payloadData struct {
 		CollectionAddress string `json:"collection_address"`
-		ItemIndex	 int64    `json:"item_index"`
+		ItemIndex         int64  `json:"item_index"`
 	}
 
-	if err := json.Unmarshal(t.Payload(), &payloadData); err != nil {
-		app.logger.Warning(fmt.Sprintf("error unmarshalling payload: %v", err))
+	if err := tasks.Decode(t, &payloadData); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
 		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
 	}
 
-	nft, err := app.getNFTByCollection(payloadData.CollectionAddress, payloadData.ItemIndex)
+	nft, err := app.getNFTByCollection(ctx, payloadData.CollectionAddress, payloadData.ItemIndex)
 	if err != nil {
 		return app.SkipError(err, t)
 	}
@@ -153,59 +150,65 @@ func (app *application) MigrateNFT(ctx context.Context, t *asynq.Task) error {
 
 	payload := struct {
 		UserAddr string `json:"user_address"`
-		NftAddr string `json:"nft_address"`
+		NftAddr  string `json:"nft_address"`
 	}{
 		UserAddr: nft.FriendlyOwnerAddress,
-		NftAddr: nft.FriendlyAddress,
+		NftAddr:  nft.FriendlyAddress,
 	}
 
 	outcomePayload, err := json.Marshal(payload)
 
 	if err != nil {
-		app.logger.Error(err, nil)
+		app.logger.Ctx(ctx).Error(err, nil)
 		return err
 	}
 
+	runGetRewardToAccount := tasks.NewTask(ctx, database.TYPE_ADD_REWARD_TO_ACCOUNT, outcomePayload)
 
-	runGetRewardToAccount := asynq.NewTask(database.TYPE_ADD_REWARD_TO_ACCOUNT, outcomePayload)
-
-	info, err := app.asynqClient.Enqueue(runGetRewardToAccount, asynq.TaskID(fmt.Sprintf(payload.NftAddr, payload.UserAddr)), asynq.MaxRetry(10),  asynq.ProcessIn(20*time.Second), asynq.Retention(24 * time.Hour), asynq.Queue(database.PRIORITY_URGENT))
+	info, err := app.asynqClient.Enqueue(runGetRewardToAccount, asynq.TaskID(fmt.Sprintf(payload.NftAddr, payload.UserAddr)), asynq.MaxRetry(10), asynq.ProcessIn(20*time.Second), asynq.Retention(24*time.Hour), asynq.Queue(database.PRIORITY_URGENT))
 	if err != nil {
-		app.logger.Error(fmt.Errorf("error: %s", err), nil)
+		app.logger.Ctx(ctx).Error(err, nil)
 		return err
 	}
 
-	app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))	
+	app.logger.Ctx(ctx).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
 
 	return nil
 }
 
-
 func (app *application) AddTgMessage(ctx context.Context, t *asynq.Task) error {
 
 	var payloadData struct {
-		UserId int `json:"user_id"`
-		MessageId int `json:"message_id"`
-		ChatId int64 `json:"chat_id"`
+		UserId    int   `json:"user_id"`
+		MessageId int   `json:"message_id"`
+		ChatId    int64 `json:"chat_id"`
+		Weight    *int  `json:"weight"`
 	}
 
-	if err := json.Unmarshal(t.Payload(), &payloadData); err != nil {
-		app.logger.Warning(fmt.Sprintf("error unmarshalling payload: %v", err))
+	if err := tasks.Decode(t, &payloadData); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
 		return err
 	}
 
-	// Get user by telegram id	
+	// tasks enqueued before chats had weights
+	weight := 1
+	if payloadData.Weight != nil {
+		weight = *payloadData.Weight
+	}
+
+	// Get user by telegram id
 
 	tx, err := app.sqlModels.Rewards.DB.BeginTx(ctx, nil)
 	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error starting transaction: %v", err))
+		app.logger.Ctx(ctx).Warningw("error starting transaction", "error", err)
 		return err
 	}
+	// a no-op after storeRatingRewards commits
+	defer tx.Rollback()
 
-	id, err := app.sqlModels.Rewards.InsertTelegramMessage(tx, payloadData.UserId, payloadData.MessageId, payloadData.ChatId)
+	id, err := app.sqlModels.Rewards.InsertTelegramMessage(tx, payloadData.UserId, payloadData.MessageId, payloadData.ChatId, weight)
 	if err != nil {
-		tx.Rollback()
-		app.logger.Warning(fmt.Sprintf("error running add tg message handler: %v", err))
+		app.logger.Ctx(ctx).Warningw("error adding telegram message", "error", err)
 		return app.SkipError(err, t)
 	}
 
@@ -217,101 +220,195 @@ func (app *application) AddTgMessage(ctx context.Context, t *asynq.Task) error {
 	// 	return err
 	// }
 
-	user, err := app.sqlModels.Users.GetByTelegramUserId(payloadData.UserId)
+	user, err := app.sqlModels.Users.GetByTelegramUserId(ctx, payloadData.UserId)
 	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error running get user handler: %v", err))
+		app.logger.Ctx(ctx).Warningw("error getting user", "error", err)
 		return err
 	}
 
+	// the account was unlinked after the message was scored, the message is rolled back
+	if user == nil {
+		return nil
+	}
 
+	err = app.storeRatingRewards(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	app.logger.Ctx(ctx).Infow("added telegram message", "id", id)
+
+	return nil
+}
+
+// storeRatingRewards stores rewards of prototypes the rating or received kudos of the user
+// reached and commits the transaction of the scored message, tx is nil for kudos
+func (app *application) storeRatingRewards(ctx context.Context, tx *sql.Tx, user *database.User) error {
 	// get prototype based user rating
-	prototypesNFT, err := app.sqlModels.Nfts.GetPrototypesByRating(user.ID)
+	prototypesNFT, err := app.sqlModels.Nfts.GetPrototypesByRating(ctx, user.ID)
 	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error running get user rating handler: %v", err))
+		app.logger.Ctx(ctx).Warningw("error getting user rating", "error", err)
 		return err
 	}
 
+	storedRewardIDs := make([]int64, 0, len(prototypesNFT))
+
 	for _, prototype := range prototypesNFT {
 		// check if user has this nft
 		id, err := app.sqlModels.Rewards.InsertStoredReward(user.FriendlyAddress, app.config.App.AdminCollectionAddress, prototype.Base64)
 		if err != nil {
-			app.logger.Warning(fmt.Sprintf("error running insert stored reward handler: %v", err))
+			app.logger.Ctx(ctx).Warningw("error inserting stored reward", "error", err)
 			return err
 		}
-	     app.logger.Info(fmt.Sprintf("added stored reward with id %d", id))		
+		app.logger.Ctx(ctx).Infow("added stored reward", "id", id)
+
+		storedRewardIDs = append(storedRewardIDs, id)
 	}
 
-	if err = tx.Commit(); err != nil {
-		app.logger.Warning(fmt.Sprintf("error committing transaction: %v", err))
+	if tx != nil {
+		if err = tx.Commit(); err != nil {
+			app.logger.Ctx(ctx).Warningw("error committing transaction", "error", err)
+			return err
+		}
+	}
+
+	for i, prototype := range prototypesNFT {
+		app.notifyAchievement(ctx, user, storedRewardIDs[i], prototype)
+	}
+
+	return nil
+}
+
+// AddDiscordMessage stores a message scored in a Discord guild, like AddTgMessage for Telegram
+func (app *application) AddDiscordMessage(ctx context.Context, t *asynq.Task) error {
+	var message database.DiscordMessage
+
+	if err := tasks.Decode(t, &message); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	user, err := app.sqlModels.Users.GetByDiscordUserId(ctx, message.UserID)
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error getting user", "error", err)
+		return err
+	}
+
+	// the account was unlinked after the message was scored
+	if user == nil {
+		return nil
+	}
+
+	tx, err := app.sqlModels.Rewards.DB.BeginTx(ctx, nil)
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error starting transaction", "error", err)
+		return err
+	}
+	// a no-op after storeRatingRewards commits
+	defer tx.Rollback()
+
+	id, err := app.sqlModels.Rewards.InsertDiscordMessage(tx, &message)
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error adding discord message", "error", err)
+		return app.SkipError(err, t)
+	}
+
+	err = app.storeRatingRewards(ctx, tx, user)
+	if err != nil {
 		return err
 	}
-	
 
-	app.logger.Info(fmt.Sprintf("added tg message with id %d", id))
+	app.logger.Ctx(ctx).Infow("added discord message", "id", id)
 
 	return nil
 }
 
+// CheckRatingRewards stores rewards the user earned after the rating or received kudos
+// changed outside of the worker, the payload is the user id
+func (app *application) CheckRatingRewards(ctx context.Context, t *asynq.Task) error {
+	var userId int64
+
+	if err := tasks.Decode(t, &userId); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	user, err := app.sqlModels.Users.GetById(ctx, userId)
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error getting user", "error", err)
+		return err
+	}
+
+	// the user was deleted after the kudos, retries won't find them either
+	if user == nil {
+		return fmt.Errorf("user %d not found: %w", userId, asynq.SkipRetry)
+	}
+
+	// rewards are stored without a scored message, there is nothing to commit
+	return app.storeRatingRewards(ctx, nil, user)
+}
+
 func (app *application) RewardForLinkedAccounts(ctx context.Context, t *asynq.Task) error {
 
 	var userId int64
 
-	if err := json.Unmarshal(t.Payload(), &userId); err != nil {
-		app.logger.Warning(fmt.Sprintf("error unmarshalling payload: %v", err))
+	if err := tasks.Decode(t, &userId); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
 		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
 	}
 
-	nftMetadata, err := app.sqlModels.Nfts.GetNFTMetadataByID(app.config.App.AuthMetadataID)
+	nftMetadata, err := app.sqlModels.Nfts.GetNFTMetadataByID(ctx, app.config.App.AuthMetadataID)
 	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error running get nft metadata by id handler: %v", err))
+		app.logger.Ctx(ctx).Warningw("error getting nft metadata", "error", err)
 		return err
 	}
 
 	// get user by id
-	user, err := app.sqlModels.Users.GetById(userId)
+	user, err := app.sqlModels.Users.GetById(ctx, userId)
 	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error running get user by id handler: %v", err))
+		app.logger.Ctx(ctx).Warningw("error getting user", "error", err)
 		return err
 	}
 
 	// insert stored_reward
 	id, err := app.sqlModels.Rewards.InsertStoredReward(user.FriendlyAddress, app.config.App.AdminCollectionAddress, nftMetadata.Base64)
 	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error running insert stored reward handler: %v", err))
+		app.logger.Ctx(ctx).Warningw("error inserting stored reward", "error", err)
 		return err
 	}
 
+	app.notifyAchievement(ctx, user, id, nftMetadata)
+
 	// count stored rewards
 	count, err := app.sqlModels.Rewards.CountStoredRewards()
 	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error running count stored rewards handler: %v", err))
+		app.logger.Ctx(ctx).Warningw("error counting stored rewards", "error", err)
 		return err
 	}
 
-
 	lastTime, err := app.sqlModels.Rewards.GetLastTimeStoredReward()
 	if err != nil {
-		app.logger.Warning(fmt.Sprintf("error running get last time stored reward handler: %v", err))
+		app.logger.Ctx(ctx).Warningw("error getting last stored reward time", "error", err)
 		return err
 	}
 
 	// if count is equal to 20 or more or last time is more than 1 hour then send reward
-	if count >= 20 || time.Now().Unix() - lastTime >= 3600 {
+	if count >= 20 || time.Now().Unix()-lastTime >= 3600 {
 
-		runMintStoredRewards := asynq.NewTask(database.TYPE_MINT_STORED_REWARDS, nil)
+		runMintStoredRewards := tasks.NewTask(ctx, database.TYPE_MINT_STORED_REWARDS, nil)
 
-		info, err := app.asynqClient.Enqueue(runMintStoredRewards, asynq.TaskID("MINT_STORED_REWARDS"), asynq.MaxRetry(10),  asynq.ProcessIn(5*time.Second), asynq.Retention(30 * time.Second), asynq.Queue(database.PRIORITY_URGENT))
+		info, err := app.asynqClient.Enqueue(runMintStoredRewards, asynq.TaskID("MINT_STORED_REWARDS"), asynq.MaxRetry(10), asynq.ProcessIn(5*time.Second), asynq.Retention(30*time.Second), asynq.Queue(database.PRIORITY_URGENT))
 		if err != nil {
-			app.logger.Error(fmt.Errorf("error: %s", err), nil)
+			app.logger.Ctx(ctx).Error(err, nil)
 			return err
 		}
 
-		app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))
+		app.logger.Ctx(ctx).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
 
 		return nil
 	}
 
-	app.logger.Info(fmt.Sprintf("added stored reward with id %d", id))
+	app.logger.Ctx(ctx).Infow("added stored reward", "id", id)
 
 	return nil
 }
@@ -321,21 +418,21 @@ func (app *application) MintStoredRewards(ctx context.Context, t *asynq.Task) er
 	// // get all stored rewards
 	rewards, err := app.sqlModels.Rewards.GetAllStoredRewards()
 	if err != nil {
-		app.logger.Error(err, nil)
+		app.logger.Ctx(ctx).Error(err, nil)
 		return err
 	}
 
 	// create map rewards by collection address
 	rewardsByCollection := make(map[string][]*database.StoredReward)
 
-	// must be like this: collectionAddress => rewards 
+	// must be like this: collectionAddress => rewards
 	// but in one reward is one collection address and it's can be duplicated
 	for _, reward := range rewards {
 		rewardsByCollection[reward.CollectionAddress] = append(rewardsByCollection[reward.CollectionAddress], reward)
 	}
 
-	app.logger.Info(fmt.Sprintf("rewards by collection: %v", rewardsByCollection))
-	
+	app.logger.Ctx(ctx).Infow("rewards by collection", "rewards", rewardsByCollection)
+
 	for collectionAddress, rewards := range rewardsByCollection {
 		// get collection data
 		collectionAddr := address.MustParseAddr(collectionAddress)
@@ -343,15 +440,14 @@ func (app *application) MintStoredRewards(ctx context.Context, t *asynq.Task) er
 
 		collectionData, err := collectionClient.GetCollectionData(context.Background())
 		if err != nil {
-			app.logger.Error(err, nil)
+			app.logger.Ctx(ctx).Error(err, nil)
 			return err
 		}
 
-
 		dict := cell.NewDict(64)
 
 		var storedNftAddrAndUserId []struct {
-			NftAddr string 
+			NftAddr  string
 			UserAddr string
 		}
 
@@ -362,7 +458,7 @@ func (app *application) MintStoredRewards(ctx context.Context, t *asynq.Task) er
 
 			con := cell.BeginCell().MustStoreStringSnake(offchainCon.URI).EndCell()
 			// get collection data
-		
+
 			dict.Set(cell.BeginCell().MustStoreUInt(collectionData.NextItemIndex.Uint64()+uint64(i), 64).EndCell(), cell.BeginCell().
 				MustStoreCoins(tlb.MustFromTON("0.04").NanoTON().Uint64()).
 				MustStoreRef(
@@ -372,18 +468,18 @@ func (app *application) MintStoredRewards(ctx context.Context, t *asynq.Task) er
 						MustStoreAddr(address.MustParseAddr(reward.CollectionAddress)). // editor address: admin wallet
 						EndCell()).
 				EndCell())
-			
+
 			nftAddr, err := collectionClient.GetNFTAddressByIndex(ctx, big.NewInt(int64(collectionData.NextItemIndex.Uint64()+uint64(i))))
 			if err != nil {
-				app.logger.Error(err, nil)
+				app.logger.Ctx(ctx).Error(err, nil)
 				return err
 			}
 
 			storedNftAddrAndUserId = append(storedNftAddrAndUserId, struct {
-				NftAddr string
+				NftAddr  string
 				UserAddr string
 			}{
-				NftAddr: nftAddr.String(),
+				NftAddr:  nftAddr.String(),
 				UserAddr: reward.UserAddress,
 			})
 
@@ -393,103 +489,97 @@ func (app *application) MintStoredRewards(ctx context.Context, t *asynq.Task) er
 			MustStoreUInt(rand.Uint64(), 64). // query id
 			MustStoreRef(dict.MustToCell()).
 			EndCell()
-			
+
 		w := app.getWallet()
 
-		mint := wallet.SimpleMessage(collectionAddr, tlb.MustFromTON(fmt.Sprint(0.06 * float64(len(rewards)))), dataCell)
+		mint := wallet.SimpleMessage(collectionAddr, tlb.MustFromTON(fmt.Sprint(0.06*float64(len(rewards)))), dataCell)
 
 		err = w.Send(ctx, mint, true)
 
 		if err != nil {
-			app.logger.Error(err, nil)
+			app.logger.Ctx(ctx).Error(err, nil)
 			return err
 		}
 
 		for _, object := range storedNftAddrAndUserId {
 			payloadData := struct {
 				UserAddr string `json:"user_address"`
-				NftAddr string `json:"nft_address"`
+				NftAddr  string `json:"nft_address"`
 			}{
 				UserAddr: object.UserAddr,
-				NftAddr: object.NftAddr,
+				NftAddr:  object.NftAddr,
 			}
 
 			payload, err := json.Marshal(payloadData)
 
 			if err != nil {
-				app.logger.Error(err, nil)
+				app.logger.Ctx(ctx).Error(err, nil)
 				return err
 			}
 
-			runGetRewardToAccount := asynq.NewTask(database.TYPE_ADD_REWARD_TO_ACCOUNT, payload)
+			runGetRewardToAccount := tasks.NewTask(ctx, database.TYPE_ADD_REWARD_TO_ACCOUNT, payload)
 
-			info, err := app.asynqClient.Enqueue(runGetRewardToAccount, asynq.TaskID(fmt.Sprintf(object.NftAddr, object.UserAddr)), asynq.MaxRetry(10),  asynq.ProcessIn(20*time.Second), asynq.Retention(24 * time.Hour), asynq.Queue(database.PRIORITY_URGENT))
+			info, err := app.asynqClient.Enqueue(runGetRewardToAccount, asynq.TaskID(fmt.Sprintf(object.NftAddr, object.UserAddr)), asynq.MaxRetry(10), asynq.ProcessIn(20*time.Second), asynq.Retention(24*time.Hour), asynq.Queue(database.PRIORITY_URGENT))
 			if err != nil {
-				app.logger.Error(fmt.Errorf("error: %s", err), nil)
+				app.logger.Ctx(ctx).Error(err, nil)
 				return err
 			}
 
-			app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))	
+			app.logger.Ctx(ctx).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
 		}
-		
 
-		app.logger.Info(fmt.Sprintf("minted %d nfts for collection %s", len(rewards), collectionAddress), nil)		
+		app.logger.Ctx(ctx).Infow("minted nfts", "count", len(rewards), "collection", collectionAddress)
 
 	}
 
 	// mark reward as processed
 	err = app.sqlModels.Rewards.MarkStoredRewardsAsProcessed()
 	if err != nil {
-		app.logger.Error(err, nil)
+		app.logger.Ctx(ctx).Error(err, nil)
 		return err
 	}
-	
 
 	return nil
 }
-	
-	
 
-
-// 
 func (app *application) SetReward(ctx context.Context, t *asynq.Task) error {
 
 	var payloadData struct {
-		UserAddr string `json:"user_address"`
+		UserAddr   string `json:"user_address"`
 		NftAddress string `json:"nft_address"`
 	}
 
-	if err := json.Unmarshal(t.Payload(), &payloadData); err != nil {
-		app.logger.Warning(fmt.Sprintf("error unmarshalling payload: %v", err))
+	if err := tasks.Decode(t, &payloadData); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
 		return err
 	}
 
 	tx := app.sqlModels.Rewards.DB.MustBeginTx(ctx, nil)
 
 	// check if nft already exists
-	nft, err := app.sqlModels.Nfts.GetTokenByAddress(payloadData.NftAddress)
+	nft, err := app.sqlModels.Nfts.GetTokenByAddress(ctx, payloadData.NftAddress)
 	if err != nil {
 		tx.Rollback()
-		app.logger.Error(err, nil)
+		app.logger.Ctx(ctx).Error(err, nil)
 		return err
 	}
 
 	// if nft does not exist - add it
 	if nft == nil {
 		// get nft metadata
-		nft, err = app.insertNFTbyAddr(tx, payloadData.NftAddress)
+		nft, err = app.insertNFTbyAddr(ctx, tx, payloadData.NftAddress)
 		if err != nil {
 			tx.Rollback()
-			app.logger.Error(err, nil)
+			app.logger.Ctx(ctx).Error(err, nil)
 			return err
 		}
-	}	
+	}
 
 	// get user by address
-	user, err := app.sqlModels.Users.GetByFriendlyAddress(payloadData.UserAddr)
+	user, err := app.sqlModels.Users.GetByFriendlyAddress(ctx, payloadData.UserAddr)
 	if err != nil {
 		tx.Rollback()
-		app.logger.Error(err, nil)
+		app.logger.Ctx(ctx).Error(err, nil)
 		return err
 	}
 
@@ -497,30 +587,184 @@ func (app *application) SetReward(ctx context.Context, t *asynq.Task) error {
 	id, err := app.sqlModels.Rewards.Insert(tx, user.ID, nft.ID)
 	if err != nil {
 		tx.Rollback()
-		app.logger.Error(err, nil)
+		app.logger.Ctx(ctx).Error(err, nil)
 		return err
 	}
 
-	app.logger.Info(fmt.Sprintf("added reward with id %d", id))
+	app.logger.Ctx(ctx).Infow("added reward", "id", id)
 
 	// count awards that has user
 
+	// position before the reward, to notify the user when it changes
+	oldPosition, _, err := app.sqlModels.Users.GetUserPosition(ctx, user.ID)
+	if err != nil {
+		tx.Rollback()
+		app.logger.Ctx(ctx).Error(err, nil)
+		return err
+	}
 
 	// update user rating
-	err = app.sqlModels.Rewards.UpdateRatingByReward(tx, user.ID, nft.CreatedAt, nft.Weight)
+	err = app.sqlModels.Rewards.UpdateRatingByReward(ctx, tx, user.ID, nft.CreatedAt, nft.Weight)
 	if err != nil {
 		tx.Rollback()
-		app.logger.Warning(fmt.Sprintf("error running update user rating handler: %v", err))
+		app.logger.Ctx(ctx).Warningw("error updating user rating", "error", err)
 		return err
 	}
 
 	if err = tx.Commit(); err != nil {
-		app.logger.Warning(fmt.Sprintf("error committing transaction: %v", err))
+		app.logger.Ctx(ctx).Warningw("error committing transaction", "error", err)
 		return err
 	}
 
-	app.logger.Info(fmt.Sprintf("added nft with id %d", nft.ID))
-		
+	app.logger.Ctx(ctx).Infow("added nft", "id", nft.ID)
+
+	app.notifyReward(ctx, user, nft, oldPosition)
+
 	return nil
 
-}
\ No newline at end of file
+}
+
+// RecoverWallet reissues platform SBTs of the old wallet to the new one and revokes the old SBTs
+func (app *application) RecoverWallet(ctx context.Context, t *asynq.Task) error {
+	var recoveryID int64
+
+	if err := tasks.Decode(t, &recoveryID); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
+		return err
+	}
+
+	recovery, err := app.sqlModels.Recoveries.GetByID(ctx, recoveryID)
+	if err != nil {
+		app.logger.Ctx(ctx).Error(err, nil)
+		return err
+	}
+
+	if recovery.Status != database.RecoveryStatusConfirmed {
+		app.logger.Ctx(ctx).Infow("recovery is not confirmed, skipping", "recovery_id", recovery.ID, "status", recovery.Status)
+		return nil
+	}
+
+	tokens, err := app.sqlModels.Recoveries.GetPlatformTokens(ctx, recovery.OldFriendlyAddress, app.config.App.DomainName)
+	if err != nil {
+		app.logger.Ctx(ctx).Error(err, nil)
+		return err
+	}
+
+	revoked, err := app.sqlModels.Recoveries.GetRevokedTokenIDs(ctx, recovery.ID)
+	if err != nil {
+		app.logger.Ctx(ctx).Error(err, nil)
+		return err
+	}
+
+	w := app.getWallet()
+
+	// only sbts that are revoked are reissued, so the user never holds two of them
+	reissue := []*database.SBTToken{}
+	collections := make(map[int64]string)
+	base64s := make(map[int64]string)
+	pending := []*database.SBTToken{}
+	revokes := []*wallet.Message{}
+
+	for _, token := range tokens {
+		// content uri looks like https://domain/v1/deployed-nft/n/<base64>/meta.json
+		parts := strings.Split(token.ContentUri, "/")
+		if len(parts) < 7 {
+			app.logger.Ctx(ctx).Warningw("unexpected content uri of token", "content_uri", token.ContentUri, "sbt_address", token.FriendlyAddress)
+			continue
+		}
+
+		sbtAddr, err := address.ParseAddr(token.FriendlyAddress)
+		if err != nil {
+			app.logger.Ctx(ctx).Warningw("invalid sbt address", "sbt_address", token.FriendlyAddress, "error", err)
+			continue
+		}
+
+		collection, err := app.sqlModels.Nfts.GetCollectionById(ctx, token.SBTCollectionID)
+		if err != nil {
+			app.logger.Ctx(ctx).Error(err, nil)
+			return err
+		}
+
+		collections[token.ID] = collection.FriendlyAddress
+		base64s[token.ID] = parts[6]
+
+		// revoked by a previous run of the task
+		if revoked[token.ID] {
+			reissue = append(reissue, token)
+			continue
+		}
+
+		authority, err := app.getAuthorityAddress(ctx, sbtAddr)
+		if err != nil {
+			app.logger.Ctx(ctx).Errorw(err, "sbt_address", token.FriendlyAddress)
+			return err
+		}
+
+		if !bytes.Equal(authority.Data(), w.Address().Data()) {
+			app.logger.Ctx(ctx).Warningw("admin wallet is not authority of sbt, it is not revoked nor reissued", "sbt_address", token.FriendlyAddress)
+			continue
+		}
+
+		pending = append(pending, token)
+		revokes = append(revokes, revokeMessage(sbtAddr))
+	}
+
+	// wallet v4 accepts up to 4 messages in one external message
+	for i := 0; i < len(revokes); i += 4 {
+		end := i + 4
+		if end > len(revokes) {
+			end = len(revokes)
+		}
+
+		ids := make([]int64, 0, end-i)
+		for _, token := range pending[i:end] {
+			ids = append(ids, token.ID)
+		}
+
+		err = app.sqlModels.Recoveries.InsertRevokes(ctx, recovery.ID, ids)
+		if err != nil {
+			app.logger.Ctx(ctx).Error(err, nil)
+			return err
+		}
+
+		err = w.SendMany(ctx, revokes[i:end], true)
+		if err != nil {
+			// the retry sends the batch again, a revoke of a revoked sbt bounces back
+			if delErr := app.sqlModels.Recoveries.DeleteRevokes(ctx, recovery.ID, ids); delErr != nil {
+				app.logger.Ctx(ctx).Error(delErr, nil)
+			}
+			app.logger.Ctx(ctx).Error(err, nil)
+			return err
+		}
+
+		reissue = append(reissue, pending[i:end]...)
+	}
+
+	app.logger.Ctx(ctx).Infow("revoked sbts", "count", len(revokes), "address", recovery.OldFriendlyAddress)
+
+	err = app.sqlModels.Recoveries.Reissue(ctx, recovery, reissue, collections, base64s)
+	if err != nil {
+		app.logger.Ctx(ctx).Error(err, nil)
+		return err
+	}
+
+	if len(reissue) == 0 {
+		return nil
+	}
+
+	runMintStoredRewards := tasks.NewTask(ctx, database.TYPE_MINT_STORED_REWARDS, nil)
+
+	info, err := app.asynqClient.Enqueue(runMintStoredRewards, asynq.TaskID("MINT_STORED_REWARDS"), asynq.MaxRetry(10), asynq.ProcessIn(5*time.Second), asynq.Retention(30*time.Second), asynq.Queue(database.PRIORITY_URGENT))
+	if err != nil {
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			return nil
+		}
+
+		app.logger.Ctx(ctx).Error(err, nil)
+		return err
+	}
+
+	app.logger.Ctx(ctx).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+
+	return nil
+}
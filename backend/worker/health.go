@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/ton-developer-program/internal/health"
+)
+
+// listenerCheck fails when the listener stopped processing masterchain blocks
+func (app *application) listenerCheck() health.Check {
+	maxAge := time.Duration(app.config.Health.ListenerMaxAgeSec) * time.Second
+
+	check := health.Heartbeat("listener", func() time.Time {
+		return time.Unix(app.lastProcessedAt.Load(), 0)
+	}, maxAge)
+
+	heartbeat := check.Run
+
+	check.Run = func(ctx context.Context) (map[string]any, error) {
+		details, err := heartbeat(ctx)
+
+		details["processed_seqno"] = app.processedSeqno.Load()
+		details["latest_seqno"] = app.latestSeqno.Load()
+
+		return details, err
+	}
+
+	return check
+}
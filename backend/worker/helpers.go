@@ -104,9 +104,13 @@ func (app *application) getMetaData(url string) (*database.Metadata, database.JS
 
 
 
-func (app *application) getWallet() *wallet.Wallet {
+func (app *application) newWallet() (*wallet.Wallet, error) {
 	words := strings.Split(app.config.App.SeedPhrase, "_")
-	w, err := wallet.FromSeed(app.tonLiteClient, words, wallet.V4R2)
+	return wallet.FromSeed(app.tonLiteClient, words, wallet.V4R2)
+}
+
+func (app *application) getWallet() *wallet.Wallet {
+	w, err := app.newWallet()
 	if err != nil {
 		panic(err)
 	}
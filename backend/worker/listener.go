@@ -9,6 +9,7 @@ import (
 
 	"github.com/hibiken/asynq"
 	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/tasks"
 	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/tlb"
 	"github.com/xssnick/tonutils-go/ton"
@@ -50,6 +51,7 @@ func (app *application) RunListeningTransactions(ctx context.Context) error {
 
 	master, err := app.tonLiteClient.GetMasterchainInfo(ctx)
 	if err != nil {
+		liteserverError("get_masterchain_info", err)
 		app.logger.Error(errors.New("get masterchain info:"+err.Error()), nil)
 		return err
 	}
@@ -65,6 +67,7 @@ func (app *application) RunListeningTransactions(ctx context.Context) error {
 	// to init storage of last seen shard seq numbers
 	firstShards, err := app.tonLiteClient.GetBlockShardsInfo(context, master)
 	if err != nil {
+		liteserverError("get_block_shards_info", err)
 		app.logger.Error(errors.New("get shards info:"+err.Error()), nil)
 		return err
 	}
@@ -83,7 +86,7 @@ func (app *application) RunListeningTransactions(ctx context.Context) error {
 			End: 100,
 		}
 				// get collections
-		collections, err := app.sqlModels.Nfts.GetAllCollections(pagination)
+		collections, err := app.sqlModels.Nfts.GetAllCollections(ctx, pagination)
 		if err != nil {
 			app.logger.Error(errors.New("get collections:"+err.Error()), nil)
 			return err
@@ -94,6 +97,7 @@ func (app *application) RunListeningTransactions(ctx context.Context) error {
 		// getting information about other work-chains and shards of master block
 		currentShards, err := app.tonLiteClient.GetBlockShardsInfo(context, master)
 		if err != nil {
+			liteserverError("get_block_shards_info", err)
 			app.logger.Error(errors.New("get shards info:"+err.Error()), nil)
 			return err
 		}
@@ -104,6 +108,7 @@ func (app *application) RunListeningTransactions(ctx context.Context) error {
 		for _, shard := range currentShards {
 			notSeen, err := getNotSeenShards(context, app.tonLiteClient, shard, shardLastSeqno)
 			if err != nil {
+				liteserverError("get_block_data", err)
 				app.logger.Error(errors.New("get not seen shards:"+err.Error()), nil)
 				return err
 			}
@@ -115,7 +120,7 @@ func (app *application) RunListeningTransactions(ctx context.Context) error {
 
 		// for each shard block getting transactions
 		for _, shard := range newShards {
-			app.logger.Info("scanning shard block", "shard", shard.Shard, "seqno", shard.SeqNo)
+			app.logger.Infow("scanning shard block", "shard", shard.Shard, "seqno", shard.SeqNo)
 
 			var fetchedIDs []ton.TransactionShortInfo
 			var after *ton.TransactionID3
@@ -125,6 +130,7 @@ func (app *application) RunListeningTransactions(ctx context.Context) error {
 			for more {
 				fetchedIDs, more, err = app.tonLiteClient.WaitForBlock(master.SeqNo).GetBlockTransactionsV2(context, shard, 100, after)
 				if err != nil {
+					liteserverError("get_block_transactions", err)
 					app.logger.Error(errors.New("get tx ids:"+err.Error()), nil)
 					return err
 				}
@@ -138,6 +144,7 @@ func (app *application) RunListeningTransactions(ctx context.Context) error {
 					// get full transaction by id
 					tx, err := app.tonLiteClient.GetTransaction(context, shard, address.NewAddress(0, 0, id.Account), id.LT)
 					if err != nil {
+						liteserverError("get_transaction", err)
 						app.logger.Error(errors.New("get tx:"+err.Error()), nil)
 						return err
 					}
@@ -158,7 +165,7 @@ func (app *application) RunListeningTransactions(ctx context.Context) error {
 						app.logger.Error(fmt.Errorf("error: %s", err), nil)
 						return err
 					}
-					runGetCollection := asynq.NewTask(database.TYPE_MIGRATE_COLLECTION, payload)
+					runGetCollection := tasks.NewTask(ctx, database.TYPE_MIGRATE_COLLECTION, payload)
 
 					info, err := app.asynqClient.Enqueue(runGetCollection, asynq.TaskID(collection.FriendlyAddress), asynq.MaxRetry(5), asynq.ProcessIn(5*time.Second), asynq.Retention(60 * time.Second), asynq.Queue(database.PRIORITY_URGENT))
 					if err != nil {
@@ -166,19 +173,22 @@ func (app *application) RunListeningTransactions(ctx context.Context) error {
 						return err
 					}
 
-					app.logger.Info(fmt.Sprintf("enqueued task with id %s", info.ID))
+					app.logger.Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
 				}
 			}
 
-			app.logger.Info("processing transaction", "id", transaction.String(), "index", i)
+			app.logger.Infow("processing transaction", "id", transaction.String(), "index", i)
 		}
 
 		if len(txList) == 0 {
 			app.logger.Info("no transactions found")
 		}
 
+		app.setProcessedSeqno(master.SeqNo)
+
 		master, err = app.tonLiteClient.WaitForBlock(master.SeqNo + 1).GetMasterchainInfo(context)
 		if err != nil {
+			liteserverError("get_masterchain_info", err)
 			app.logger.Error(errors.New("get masterchain info:"+err.Error()), nil)
 			return err
 		}
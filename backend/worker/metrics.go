@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/health"
+	"github.com/ton-developer-program/internal/metrics"
+)
+
+// how often wallet balance, backlog and masterchain seqno are collected
+const metricsInterval = 30 * time.Second
+
+// instrumentTask records outcome and duration of every task
+func (app *application) instrumentTask(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		start := time.Now()
+
+		err := next.ProcessTask(ctx, t)
+
+		outcome := "success"
+		if err != nil {
+			outcome = "failure"
+		}
+
+		metrics.Tasks.WithLabelValues(t.Type(), outcome).Inc()
+		metrics.TaskDuration.WithLabelValues(t.Type()).Observe(time.Since(start).Seconds())
+
+		return err
+	})
+}
+
+// liteserverError counts failed liteserver call and returns the error unchanged
+func liteserverError(call string, err error) error {
+	if err != nil {
+		metrics.LiteserverErrors.WithLabelValues(call).Inc()
+	}
+
+	return err
+}
+
+// setProcessedSeqno is called by the listener after the master block is processed
+func (app *application) setProcessedSeqno(seqno uint32) {
+	app.processedSeqno.Store(seqno)
+
+	latest := app.latestSeqno.Load()
+	if seqno > latest {
+		app.latestSeqno.Store(seqno)
+		latest = seqno
+	}
+
+	now := time.Now().Unix()
+	app.lastProcessedAt.Store(now)
+
+	metrics.SetListenerSeqno(latest, seqno)
+	metrics.ListenerLastProcessed.Set(float64(now))
+}
+
+// collectMetrics updates metrics that are not tied to requests or tasks
+func (app *application) collectMetrics(ctx context.Context) {
+	ticker := time.NewTicker(metricsInterval)
+	defer ticker.Stop()
+
+	for {
+		app.collectMetricsOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) collectMetricsOnce(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, metricsInterval)
+	defer cancel()
+
+	backlog, err := app.sqlModels.Rewards.CountStoredRewards()
+	if err != nil {
+		app.logger.Errorw(err, "metric", "stored_rewards_backlog")
+	} else {
+		metrics.StoredRewardsBacklog.Set(float64(backlog))
+	}
+
+	master, err := app.tonLiteClient.GetMasterchainInfo(ctx)
+	if err != nil {
+		liteserverError("get_masterchain_info", err)
+		app.logger.Warningw("error getting masterchain info", "metric", "listener_seqno", "error", err)
+		return
+	}
+
+	app.latestSeqno.Store(master.SeqNo)
+
+	if processed := app.processedSeqno.Load(); processed > 0 {
+		metrics.SetListenerSeqno(master.SeqNo, processed)
+	} else {
+		metrics.ListenerMasterchainSeqno.Set(float64(master.SeqNo))
+	}
+
+	w, err := app.newWallet()
+	if err != nil {
+		app.logger.Errorw(err, "metric", "admin_wallet_balance")
+		return
+	}
+
+	balance, err := w.GetBalance(ctx, master)
+	if err != nil {
+		liteserverError("get_account", err)
+		app.logger.Warningw("error getting admin wallet balance", "metric", "admin_wallet_balance", "error", err)
+		return
+	}
+
+	metrics.AdminWalletBalance.Set(health.NanoToTON(balance.NanoTON()))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/leveledlog"
+	"github.com/ton-developer-program/internal/tasks"
+	"github.com/ton-developer-program/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// taskContext adds request id from the task payload and task id to the logger context,
+// tasks enqueued by the handler carry the same request id
+func (app *application) taskContext(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		if requestID := tasks.RequestID(t); requestID != "" {
+			ctx = leveledlog.ContextWithRequestID(ctx, requestID)
+		}
+
+		taskID, _ := asynq.GetTaskID(ctx)
+		ctx = leveledlog.ContextWithFields(ctx, "task_type", t.Type(), "task_id", taskID)
+
+		return next.ProcessTask(ctx, t)
+	})
+}
+
+// traceTask starts a span for the task, the parent is the span of the request or task that enqueued it
+func (app *application) traceTask(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		ctx = tasks.Extract(ctx, t)
+
+		taskID, _ := asynq.GetTaskID(ctx)
+		queue, _ := asynq.GetQueueName(ctx)
+		retry, _ := asynq.GetRetryCount(ctx)
+
+		ctx, span := tracing.Tracer().Start(ctx, "task "+t.Type(),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "asynq"),
+				attribute.String("messaging.destination", queue),
+				attribute.String("messaging.message_id", taskID),
+				attribute.Int("asynq.retry", retry),
+			),
+		)
+		defer span.End()
+
+		if traceID := tracing.TraceIDFromContext(ctx); traceID != "" {
+			ctx = leveledlog.ContextWithFields(ctx, "trace_id", traceID)
+		}
+
+		err := next.ProcessTask(ctx, t)
+		tracing.RecordError(span, err)
+
+		return err
+	})
+}
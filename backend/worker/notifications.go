@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/i18n"
+	"github.com/ton-developer-program/internal/smtp"
+	"github.com/ton-developer-program/internal/tasks"
+)
+
+// email template of every notification event
+var notificationTemplates = map[string]string{
+	database.NotifyNewSBT:              smtp.TemplateNewSBT,
+	database.NotifyIncomingAchievement: smtp.TemplateIncomingAchievement,
+	database.NotifyRankChange:          smtp.TemplateRankChange,
+	database.NotifyWeeklyDigest:        smtp.TemplateWeeklyDigest,
+}
+
+// users get in-app notification when they reach these positions in the leaderboard
+var rankMilestones = []int64{1, 3, 10, 50, 100}
+
+// notifyInApp adds notification to the notification center of the user,
+// failed notifications are logged and never fail the caller
+func (app *application) notifyInApp(ctx context.Context, userID int64, notificationType, title string, data database.JSONB) {
+	notification := &database.Notification{
+		UserID: userID,
+		Type:   notificationType,
+		Title:  title,
+		Data:   data,
+	}
+
+	err := app.sqlModels.Notifications.Insert(ctx, notification)
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error inserting notification", "type", notificationType, "user_id", userID, "error", err)
+		return
+	}
+
+	app.logger.Ctx(ctx).Infow("added notification", "notification_id", notification.ID, "type", notificationType)
+}
+
+// notifyByEmail sends the event to the user by email if the user has verified email and
+// didn't turn the event off. Failed notifications are logged and never fail the caller.
+func (app *application) notifyByEmail(ctx context.Context, user *database.User, event string, data map[string]any) {
+	recipient, err := app.sqlModels.Emails.GetRecipient(ctx, user.ID, event)
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error getting email recipient", "user_id", user.ID, "error", err)
+		return
+	}
+
+	if recipient == "" {
+		return
+	}
+
+	language, err := app.sqlModels.Users.GetLanguage(ctx, user.ID)
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error getting user language", "user_id", user.ID, "error", err)
+	}
+
+	data["Username"] = user.Username
+	data["ProfileURL"] = app.config.App.BaseUrl + "/user/" + user.Username
+	data["AchievementsURL"] = app.config.App.BaseUrl + "/achievements"
+	data["SettingsURL"] = app.config.App.BaseUrl + "/settings"
+
+	payload, err := json.Marshal(smtp.Email{
+		Recipient: recipient,
+		Template:  notificationTemplates[event],
+		Language:  i18n.Match(language),
+		Data:      data,
+	})
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error marshalling email payload", "error", err)
+		return
+	}
+
+	task := tasks.NewTask(ctx, database.TYPE_SEND_EMAIL, payload)
+
+	info, err := app.asynqClient.Enqueue(task, asynq.MaxRetry(5), asynq.Retention(24*time.Hour), asynq.Queue(database.PRIORITY_LOW))
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error enqueueing email", "event", event, "user_id", user.ID, "error", err)
+		return
+	}
+
+	app.logger.Ctx(ctx).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type, "event", event)
+}
+
+// notifyTelegram asks the bot to send a direct message about the event,
+// the bot skips users without linked Telegram account
+func (app *application) notifyTelegram(ctx context.Context, taskType string, data map[string]any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error marshalling telegram payload", "error", err)
+		return
+	}
+
+	task := tasks.NewTask(ctx, taskType, payload)
+
+	info, err := app.asynqClient.Enqueue(task, asynq.MaxRetry(5), asynq.Retention(24*time.Hour), asynq.Queue(database.QUEUE_BOT))
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error enqueueing telegram message", "user_id", data["user_id"], "error", err)
+		return
+	}
+
+	app.logger.Ctx(ctx).Infow("enqueued task", "task_id", info.ID, "task_type", info.Type)
+}
+
+// notifyReward notifies the user about minted SBT and the new position in the leaderboard
+func (app *application) notifyReward(ctx context.Context, user *database.User, nft *database.SBTToken, oldPosition int64) {
+	data := map[string]any{
+		"Rating":   user.Rating + float64(nft.Weight),
+		"TokenURL": app.config.App.TokenUrl + nft.FriendlyAddress,
+	}
+
+	for key, value := range map[string]*string{"Name": nft.Name, "Description": nft.Description, "Image": nft.Image} {
+		data[key] = ""
+		if value != nil {
+			data[key] = *value
+		}
+	}
+
+	app.notifyInApp(ctx, user.ID, database.NotificationRewardMinted, fmt.Sprintf("You received %s", data["Name"]), database.JSONB{
+		"nft_address": nft.FriendlyAddress,
+		"name":        data["Name"],
+		"image":       data["Image"],
+		"token_url":   data["TokenURL"],
+	})
+
+	app.notifyByEmail(ctx, user, database.NotifyNewSBT, data)
+
+	app.notifyTelegram(ctx, database.TYPE_TG_REWARD_MINTED, map[string]any{
+		"user_id":     user.ID,
+		"nft_address": nft.FriendlyAddress,
+	})
+
+	position, count, err := app.sqlModels.Users.GetUserPosition(ctx, user.ID)
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error getting user position", "user_id", user.ID, "error", err)
+		return
+	}
+
+	if oldPosition == 0 || position >= oldPosition {
+		return
+	}
+
+	// the best milestone reached with this reward
+	for _, milestone := range rankMilestones {
+		if position <= milestone && oldPosition > milestone {
+			app.notifyInApp(ctx, user.ID, database.NotificationRankMilestone, fmt.Sprintf("You are in the top %d", milestone), database.JSONB{
+				"milestone":    milestone,
+				"position":     position,
+				"old_position": oldPosition,
+			})
+			break
+		}
+	}
+
+	app.notifyByEmail(ctx, user, database.NotifyRankChange, map[string]any{
+		"OldPosition": oldPosition,
+		"Position":    position,
+		"UsersCount":  count,
+	})
+}
+
+// notifyAchievement notifies the user about achievement waiting for approval
+func (app *application) notifyAchievement(ctx context.Context, user *database.User, achievementID int64, metadata *database.NFTMetadata) {
+	app.notifyInApp(ctx, user.ID, database.NotificationAchievementPending, fmt.Sprintf("%s is waiting for your approval", metadata.Name), database.JSONB{
+		"achievement_id": achievementID,
+		"name":           metadata.Name,
+		"image":          metadata.Image,
+	})
+
+	app.notifyByEmail(ctx, user, database.NotifyIncomingAchievement, map[string]any{
+		"Name":        metadata.Name,
+		"Description": metadata.Description,
+		"Image":       metadata.Image,
+	})
+
+	app.notifyTelegram(ctx, database.TYPE_TG_ACHIEVEMENT_PENDING, map[string]any{
+		"user_id":        user.ID,
+		"achievement_id": achievementID,
+	})
+}
+
+// SendEmail renders the template of the payload and sends it
+func (app *application) SendEmail(ctx context.Context, t *asynq.Task) error {
+	var email smtp.Email
+
+	if err := tasks.Decode(t, &email); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	err := app.mailer.SendIn(email.Language, email.Recipient, email.Data, email.Template)
+	if err != nil {
+		app.logger.Ctx(ctx).Warningw("error sending email", "template", email.Template, "error", err)
+		return err
+	}
+
+	app.logger.Ctx(ctx).Infow("sent email", "template", email.Template)
+
+	return nil
+}
+
+// SendWeeklyDigest sends stats of the last week to every user subscribed to the digest
+func (app *application) SendWeeklyDigest(ctx context.Context, t *asynq.Task) error {
+	ids, err := app.sqlModels.Emails.GetRecipientIDs(ctx, database.NotifyWeeklyDigest)
+	if err != nil {
+		app.logger.Ctx(ctx).Error(err, nil)
+		return err
+	}
+
+	since := time.Now().AddDate(0, 0, -7).Unix()
+
+	for _, id := range ids {
+		user, err := app.sqlModels.Users.GetById(ctx, id)
+		if err != nil || user == nil {
+			app.logger.Ctx(ctx).Warningw("error getting user", "user_id", id, "error", err)
+			continue
+		}
+
+		stats, err := app.sqlModels.Emails.GetDigestStats(ctx, id, since)
+		if err != nil {
+			app.logger.Ctx(ctx).Warningw("error getting digest stats", "user_id", id, "error", err)
+			continue
+		}
+
+		app.notifyByEmail(ctx, user, database.NotifyWeeklyDigest, map[string]any{
+			"Rating":        stats.Rating,
+			"Position":      stats.Position,
+			"UsersCount":    stats.UsersCount,
+			"NewRewards":    stats.NewRewards,
+			"PendingCount":  stats.PendingCount,
+			"MessagesCount": stats.MessagesCount,
+		})
+	}
+
+	app.logger.Ctx(ctx).Infow("weekly digest enqueued", "users", len(ids))
+
+	return nil
+}
+
+// AddAdminNotification adds notification written by admin to the notification center of users
+func (app *application) AddAdminNotification(ctx context.Context, t *asynq.Task) error {
+	var payloadData struct {
+		UserIDs []int64 `json:"user_ids"`
+		Title   string  `json:"title"`
+		Content string  `json:"content"`
+	}
+
+	if err := tasks.Decode(t, &payloadData); err != nil {
+		app.logger.Ctx(ctx).Warningw("error unmarshalling payload", "error", err)
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	count, err := app.sqlModels.Notifications.InsertForUsers(ctx, payloadData.UserIDs, &database.Notification{
+		Type:    database.NotificationAdminMessage,
+		Title:   payloadData.Title,
+		Content: &payloadData.Content,
+	})
+	if err != nil {
+		app.logger.Ctx(ctx).Error(err, nil)
+		return err
+	}
+
+	app.logger.Ctx(ctx).Infow("added admin notification", "users", count)
+
+	return nil
+}
+
+// CleanupNotifications deletes notifications older than the retention period
+func (app *application) CleanupNotifications(ctx context.Context, t *asynq.Task) error {
+	before := time.Now().AddDate(0, 0, -app.config.Notifications.RetentionDays).Unix()
+
+	count, err := app.sqlModels.Notifications.DeleteOlderThan(ctx, before)
+	if err != nil {
+		app.logger.Ctx(ctx).Error(err, nil)
+		return err
+	}
+
+	app.logger.Ctx(ctx).Infow("deleted notifications", "count", count)
+
+	return nil
+}
@@ -1,17 +1,25 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"runtime/debug"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/hibiken/asynq"
 	"github.com/ton-developer-program/internal/database"
+	"github.com/ton-developer-program/internal/health"
 	"github.com/ton-developer-program/internal/leveledlog"
+	"github.com/ton-developer-program/internal/metrics"
+	"github.com/ton-developer-program/internal/smtp"
 	"github.com/ton-developer-program/internal/tonconnect"
+	"github.com/ton-developer-program/internal/tracing"
 	"github.com/ton-developer-program/internal/version"
 	"github.com/ton-developer-program/util"
 	"github.com/xssnick/tonutils-go/liteclient"
@@ -26,6 +34,12 @@ type application struct {
 	tonLiteClient *ton.APIClient
 	asynqClient *asynq.Client
 	sqlModels database.Models
+	mailer *smtp.Mailer
+	// masterchain seqno for listener lag metrics
+	latestSeqno    atomic.Uint32
+	processedSeqno atomic.Uint32
+	// unix time of the last processed block, checked by readiness probe
+	lastProcessedAt atomic.Int64
 }
 
 func main() {
@@ -45,6 +59,10 @@ func run(logger *leveledlog.Logger) error {
 		return err
 	}
 
+	if cfg.Log.Format == "json" {
+		logger = leveledlog.NewJSONLogger(os.Stdout, leveledlog.LevelAll)
+	}
+
 	showVersion := flag.Bool("version", false, "display version and exit")
 
 	flag.Parse()
@@ -55,6 +73,23 @@ func run(logger *leveledlog.Logger) error {
 		return nil
 	}
 
+	shutdownTracing, err := tracing.Init(tracing.Config{
+		Service:     "worker",
+		Version:     version.Get(),
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		shutdownTracing(ctx)
+	}()
+
     // Set up connection using the tonLiteClient config
 
 
@@ -106,8 +141,40 @@ func run(logger *leveledlog.Logger) error {
 		tonLiteClient: tonLiteClient,
 		asynqClient: asynqClient,
 		sqlModels: database.NewModels(db.DB),
+		mailer: smtp.NewMailer(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From),
 	}
 
+	// listener gets ListenerMaxAgeSec to process the first block after start
+	app.lastProcessedAt.Store(time.Now().Unix())
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+	})
+	defer redisClient.Close()
+
+	checker := health.NewChecker("worker", version.Get(),
+		health.Postgres(db.DB),
+		health.Redis(redisClient),
+		health.Liteserver(tonLiteClient),
+		app.listenerCheck(),
+		health.AdminWallet(tonLiteClient, cfg.Ton.AdminWallet, cfg.Health.MinWalletBalanceTON),
+	)
+
+	metrics.BuildInfo.WithLabelValues("worker", version.Get()).Set(1)
+
+	go func() {
+		mux := http.NewServeMux()
+		checker.Register(mux)
+
+		err := metrics.Serve(cfg.Metrics.Addr, mux)
+		if err != nil {
+			app.logger.Errorw(err, "server", "metrics")
+		}
+	}()
+
+	go app.collectMetrics(context.Background())
+
 	stop := make(chan struct{})
     
     go func() {
@@ -137,11 +204,17 @@ func run(logger *leveledlog.Logger) error {
 func (app *application) routes() *asynq.ServeMux {
 	mux := asynq.NewServeMux()
 
+	mux.Use(app.taskContext)
+	mux.Use(app.traceTask)
+	mux.Use(app.instrumentTask)
+
 	mux.HandleFunc(database.TYPE_ADD_COLLECTION, app.AddCollection)
 	mux.HandleFunc(database.TYPE_MIGRATE_COLLECTION, app.MigrateCollection)
 
 	mux.HandleFunc(database.TYPE_MIGRATE_NFT, app.MigrateNFT)
 	mux.HandleFunc(database.TYPE_ADD_TG_MESSAGE, app.AddTgMessage)
+	mux.HandleFunc(database.TYPE_ADD_DISCORD_MESSAGE, app.AddDiscordMessage)
+	mux.HandleFunc(database.TYPE_CHECK_RATING_REWARDS, app.CheckRatingRewards)
 
 	mux.HandleFunc(database.TYPE_REWARD_FOR_LINKED_ACCOUNT, app.RewardForLinkedAccounts)
 
@@ -149,5 +222,13 @@ func (app *application) routes() *asynq.ServeMux {
 	
 	mux.HandleFunc(database.TYPE_ADD_REWARD_TO_ACCOUNT, app.SetReward)
 
+	mux.HandleFunc(database.TYPE_RECOVER_WALLET, app.RecoverWallet)
+
+	mux.HandleFunc(database.TYPE_SEND_EMAIL, app.SendEmail)
+	mux.HandleFunc(database.TYPE_SEND_WEEKLY_DIGEST, app.SendWeeklyDigest)
+
+	mux.HandleFunc(database.TYPE_ADMIN_NOTIFICATION, app.AddAdminNotification)
+	mux.HandleFunc(database.TYPE_CLEANUP_NOTIFICATIONS, app.CleanupNotifications)
+
 	return mux
 }
@@ -13,7 +13,7 @@ import (
 	"github.com/xssnick/tonutils-go/ton/nft"
 )
 
-func (app *application) getCollection(collection database.SBTCollection) (*database.SBTCollection, error) {
+func (app *application) getCollection(ctx context.Context, collection database.SBTCollection) (*database.SBTCollection, error) {
 
 	collectionAddr, err := address.ParseAddr(collection.FriendlyAddress)
 	if err != nil {
@@ -22,7 +22,7 @@ func (app *application) getCollection(collection database.SBTCollection) (*datab
 
 	app.logger.Info(fmt.Sprintf("getting collections for address %v", collection.FriendlyAddress))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	collectionClient := nft.NewCollectionClient(app.tonLiteClient, collectionAddr)
@@ -91,7 +91,7 @@ func (app *application) getCollection(collection database.SBTCollection) (*datab
 	}
 
 	// insert collection into database
-	insertedCollection, err := app.sqlModels.Nfts.InsertCollection(insertCollection)
+	insertedCollection, err := app.sqlModels.Nfts.InsertCollection(ctx, insertCollection)
 	if err != nil {
 		return nil, fmt.Errorf("error inserting collection: %v", err)
 	}
@@ -103,7 +103,7 @@ func (app *application) getCollection(collection database.SBTCollection) (*datab
 }
 
 
-func (app *application) getNFTByCollection(collectionAddress string, index int64) (*database.SBTToken, error) {
+func (app *application) getNFTByCollection(ctx context.Context, collectionAddress string, index int64) (*database.SBTToken, error) {
 	parseCollectionAddr, err := address.ParseAddr(collectionAddress)
 	if err != nil {
 		app.logger.Warning(fmt.Sprintf("error parsing collection address: %v", err))
@@ -112,7 +112,7 @@ func (app *application) getNFTByCollection(collectionAddress string, index int64
 
 	app.logger.Info(fmt.Sprintf("migrating nfts for collection %v", collectionAddress))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	nftAddr, err := app.getNftAddressByIndex(ctx, parseCollectionAddr, index)
@@ -137,7 +137,7 @@ func (app *application) getNFTByCollection(collectionAddress string, index int64
 	}
 
 	// get collection by address
-	collectionDB, err := app.sqlModels.Nfts.GetCollectionByAddress(collectionAddress)
+	collectionDB, err := app.sqlModels.Nfts.GetCollectionByAddress(ctx, collectionAddress)
 	if err != nil {
 		return nil, err
 	}
@@ -194,7 +194,7 @@ func (app *application) getNFTByCollection(collectionAddress string, index int64
 		if strings.Contains(urlNft, app.config.App.DomainName) {
 			base64FromUrl := strings.Split(urlNft, "/")[6]
 
-			metadataWeight, err := app.sqlModels.Nfts.GetWeightByBase64(base64FromUrl)
+			metadataWeight, err := app.sqlModels.Nfts.GetWeightByBase64(ctx, base64FromUrl)
 			if err != nil {
 				return nil, err
 			}
@@ -249,7 +249,7 @@ func (app *application) getNFTByCollection(collectionAddress string, index int64
 }
 
 
-func (app *application) insertNFTbyAddr(tx *sqlx.Tx, nftAddr string) (*database.SBTToken, error) {
+func (app *application) insertNFTbyAddr(ctx context.Context, tx *sqlx.Tx, nftAddr string) (*database.SBTToken, error) {
 	app.logger.Info(fmt.Sprintf("nft address %v", nftAddr))
 
 	item := nft.NewItemClient(app.tonLiteClient, address.MustParseAddr(nftAddr))
@@ -266,7 +266,7 @@ func (app *application) insertNFTbyAddr(tx *sqlx.Tx, nftAddr string) (*database.
 	}
 
 	// get collection by address
-	collectionDB, err := app.sqlModels.Nfts.GetCollectionByAddress(nftData.CollectionAddress.String())
+	collectionDB, err := app.sqlModels.Nfts.GetCollectionByAddress(ctx, nftData.CollectionAddress.String())
 	if err != nil {
 		return nil, err
 	}
@@ -323,7 +323,7 @@ func (app *application) insertNFTbyAddr(tx *sqlx.Tx, nftAddr string) (*database.
 		if strings.Contains(urlNft, app.config.App.DomainName) {
 			base64FromUrl := strings.Split(urlNft, "/")[6]
 
-			metadataWeight, err := app.sqlModels.Nfts.GetWeightByBase64(base64FromUrl)
+			metadataWeight, err := app.sqlModels.Nfts.GetWeightByBase64(ctx, base64FromUrl)
 			if err != nil {
 				return nil, err
 			}
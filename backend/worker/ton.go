@@ -3,18 +3,24 @@ package main
 import (
 	"context"
 	"fmt"
+	"math/rand"
 
 	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/ton/wallet"
+	"github.com/xssnick/tonutils-go/tvm/cell"
 )
 
 func (app *application) getNftAddressByIndex(ctx context.Context, collectionAddress *address.Address, nextItemIndex int64) (*address.Address, error) {
 	b, err := app.tonLiteClient.CurrentMasterchainInfo(ctx)
 	if err != nil {
+		liteserverError("get_masterchain_info", err)
 		return nil, fmt.Errorf("failed to get masterchain info: %w", err)
 	}
 
 	res, err := app.tonLiteClient.RunGetMethod(ctx, b, collectionAddress, "get_nft_address_by_index", nextItemIndex)
 	if err != nil {
+		liteserverError("run_get_method", err)
 		return nil, fmt.Errorf("failed to run get_nft_address_by_index method: %w", err)
 	}
 
@@ -30,3 +36,39 @@ func (app *application) getNftAddressByIndex(ctx context.Context, collectionAddr
 
 	return nftAddress, nil
 }
+
+func (app *application) getAuthorityAddress(ctx context.Context, sbtAddress *address.Address) (*address.Address, error) {
+	b, err := app.tonLiteClient.CurrentMasterchainInfo(ctx)
+	if err != nil {
+		liteserverError("get_masterchain_info", err)
+		return nil, fmt.Errorf("failed to get masterchain info: %w", err)
+	}
+
+	res, err := app.tonLiteClient.RunGetMethod(ctx, b, sbtAddress, "get_authority_address")
+	if err != nil {
+		liteserverError("run_get_method", err)
+		return nil, fmt.Errorf("failed to run get_authority_address method: %w", err)
+	}
+
+	authorityRes, err := res.Slice(0)
+	if err != nil {
+		return nil, fmt.Errorf("err get authorityRes slice value: %w", err)
+	}
+
+	authority, err := authorityRes.LoadAddr()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load authority from result slice: %w", err)
+	}
+
+	return authority, nil
+}
+
+// revoke message for sbt item, must be sent by the sbt authority
+func revokeMessage(sbtAddress *address.Address) *wallet.Message {
+	body := cell.BeginCell().
+		MustStoreUInt(0x6f89f5e3, 32).    // op code for revoke
+		MustStoreUInt(rand.Uint64(), 64). // query id
+		EndCell()
+
+	return wallet.SimpleMessage(sbtAddress, tlb.MustFromTON("0.02"), body)
+}
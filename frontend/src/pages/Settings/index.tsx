@@ -12,6 +12,7 @@ import { PageHeaderText } from '../../components/Text'
 import type { LinkedAccount } from '../../services/types'
 import {
   useCheckAuthTelegramMutation,
+  useCreateGithubLinkMutation,
   useGetMyAccountQuery,
   useUnlinkAccountMutation,
   useUpdateUserMutation,
@@ -169,10 +170,12 @@ const SettingsPage = () => {
 
   const [checkAuthTelegram] = useCheckAuthTelegramMutation()
 
-  const handleConnectGithub = () => {
-    // This should be the URL of your Go server's OAuth handler
-    const oauthURL = BASE_URL + '/v1/github/login?username=' + data?.username
-    window.location.href = oauthURL
+  const [createGithubLink] = useCreateGithubLinkMutation()
+
+  const handleConnectGithub = async () => {
+    // the authorization url carries a link token of the signed in user
+    const link = await createGithubLink().unwrap()
+    window.location.href = link.url
   }
 
   // get #tgAuthResult from url
@@ -101,6 +101,13 @@ export const userApi = createApi({
       query: ({ start, end }) => `/v1/users?_start=${start}&_end=${end}`,
     }),
 
+    createGithubLink: builder.mutation<{ url: string; expiry: string }, void>({
+      query: () => ({
+        method: 'POST',
+        url: `/v1/github/link`,
+      }),
+    }),
+
     getUserByUsername: builder.query<{ user: User }, { username: string }>({
       providesTags: ['User'],
       query: ({ username }) => `/v1/users/${username}`,
@@ -177,4 +184,5 @@ export const {
   useUpdateAchievementMutation,
   useGetNftsByUsernameQuery,
   useUnlinkAccountMutation,
+  useCreateGithubLinkMutation,
 } = userApi